@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// applyMissingWarning is printed before up --apply-missing-only applies a
+// migration numbered below one that is already recorded.
+const applyMissingWarning = "WARNING: applying migrations out of order; a migration that depends on a later one, or that a later one depended on, may fail or leave the schema inconsistent"
+
+// applyMissingDelay is how long up --apply-missing-only waits after the
+// warning, for the user to interrupt it, unless --force is given.
+const applyMissingDelay = 10 * time.Second
+
+// runApplyMissingUp applies every migration file that has no record in the
+// tracking table, oldest first, whatever is recorded around it, as after
+// restoring a backup whose history is ahead of the files on disk. The
+// migrator refuses a history with gaps or records it has no file for, so,
+// as with --only, the records are read from the migrator's tracking table
+// and each file is run here in its own transaction and recorded there as
+// the migrator would, which is why it needs the cargo engine.
+func runApplyMissingUp(cfg Config) int {
+	var namer migrations.MigrationNamer
+
+	files, err := migrations.Scan(cfg.SQLDir())
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	ctx, cancel := cfg.commandContext()
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	if err := ensureTrackingTable(ctx, conn); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	applied, err := appliedNewestFirst(ctx, conn)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	recorded := make(map[int64]bool, len(applied))
+	var newest int64
+	for _, m := range applied {
+		recorded[m.Version] = true
+		newest = max(newest, m.Version)
+	}
+
+	var skipped []string
+	var missing []migrations.File
+	onDisk := make(map[int64]bool, len(files))
+	outOfOrder := false
+	for _, file := range files {
+		name := namer.Base(file.Sequence, file.Name)
+		onDisk[int64(file.Sequence)] = true
+		if recorded[int64(file.Sequence)] {
+			skipped = append(skipped, name)
+			continue
+		}
+		if file.UpPath == "" {
+			printer.Error("Error: migration %s has no up file", name)
+			return 1
+		}
+		missing = append(missing, file)
+		outOfOrder = outOfOrder || int64(file.Sequence) < newest
+	}
+
+	if len(skipped) > 0 {
+		printer.Info("Skipping %d migration(s) already recorded in %s:", len(skipped), db.RecordsTable())
+		for _, name := range skipped {
+			printer.Info("  - %s", name)
+		}
+	}
+	for i := len(applied) - 1; i >= 0; i-- {
+		if !onDisk[applied[i].Version] {
+			printer.Warn("%s is recorded but has no file in %s; leaving the record alone", applied[i], cfg.SQLDir())
+		}
+	}
+	if len(missing) == 0 {
+		printer.Success("No missing migrations")
+		return 0
+	}
+
+	printer.Info("Applying %d missing migration(s):", len(missing))
+	for _, file := range missing {
+		printer.Info("  - %s", namer.Base(file.Sequence, file.Name))
+	}
+	if outOfOrder {
+		printer.Warn(applyMissingWarning)
+		if !cfg.Force {
+			printer.Warn("Continuing in %s; press Ctrl-C to abort, or pass --force to skip the wait", applyMissingDelay)
+			select {
+			case <-time.After(applyMissingDelay):
+			case <-ctx.Done():
+				printer.Error("Aborted; nothing was applied")
+				return 1
+			}
+		}
+	}
+
+	for i, file := range missing {
+		name := namer.Base(file.Sequence, file.Name)
+		start := time.Now()
+		if err := applySQLMigration(ctx, conn, file); err != nil {
+			printer.Error("Migration failed: %s: %v", name, err)
+			return 1
+		}
+		printer.Success("✓ %s (%s) [%d/%d]", name, time.Since(start).Round(time.Millisecond), i+1, len(missing))
+	}
+	printer.Success("Migration completed successfully")
+	return 0
+}
@@ -0,0 +1,12 @@
+package main
+
+import "testing"
+
+func TestApplyMissingOnlyNeedsCargoEngine(t *testing.T) {
+	for _, name := range []string{"golang-migrate", "flyway"} {
+		t.Setenv("MIGRATE_ENGINE", name)
+		if _, err := migrationEngine(Config{Command: "up", ApplyMissingOnly: true}); err == nil {
+			t.Errorf("MIGRATE_ENGINE=%s: up --apply-missing-only was accepted", name)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Formats lists the formats Export renders.
+var Formats = []string{"table", "csv"}
+
+var exportHeader = []string{"Timestamp", "Command", "User", "Database", "Duration", "Status"}
+
+// ReadLog reads the audit log at path, keeping the entries logged at or after
+// since. A zero since keeps every entry.
+func ReadLog(path string, since time.Time) ([]AuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if entry.Timestamp.Before(since) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Export writes entries to w as an aligned table or as RFC 4180 CSV.
+func Export(w io.Writer, entries []AuditEntry, format string) error {
+	switch format {
+	case "table":
+		return exportTable(w, entries)
+	case "csv":
+		return exportCSV(w, entries)
+	}
+	return fmt.Errorf("unknown format %q (expected %s)", format, strings.Join(Formats, " or "))
+}
+
+func exportTable(w io.Writer, entries []AuditEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	rule := make([]string, len(exportHeader))
+	for i, name := range exportHeader {
+		rule[i] = strings.Repeat("-", len(name))
+	}
+	fmt.Fprintln(tw, strings.Join(exportHeader, "\t"))
+	fmt.Fprintln(tw, strings.Join(rule, "\t"))
+	for _, entry := range entries {
+		fmt.Fprintln(tw, strings.Join(row(entry), "\t"))
+	}
+	return tw.Flush()
+}
+
+func exportCSV(w io.Writer, entries []AuditEntry) error {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	cw.Write(exportHeader)
+	for _, entry := range entries {
+		cw.Write(row(entry))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func row(entry AuditEntry) []string {
+	status := "ok"
+	if entry.ExitCode != 0 {
+		status = "failed (exit " + strconv.Itoa(entry.ExitCode) + ")"
+	}
+	return []string{
+		entry.Timestamp.UTC().Format(time.RFC3339),
+		entry.Command,
+		orDash(entry.User),
+		orDash(entry.DatabaseHost),
+		(time.Duration(entry.DurationMS) * time.Millisecond).String(),
+		status,
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
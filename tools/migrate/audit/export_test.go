@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeLog(t *testing.T, entries ...AuditEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger := NewAuditLogger(path)
+	for _, entry := range entries {
+		if err := logger.Log(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestReadLogSince(t *testing.T) {
+	now := time.Now().UTC()
+	path := writeLog(t,
+		AuditEntry{Timestamp: now.Add(-48 * time.Hour), Command: "up"},
+		AuditEntry{Timestamp: now.Add(-time.Hour), Command: "down"},
+	)
+
+	entries, err := ReadLog(path, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Command != "down" {
+		t.Fatalf("ReadLog() = %+v, want only the down entry", entries)
+	}
+}
+
+func TestReadLogRejectsInvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := os.WriteFile(path, []byte("{\"command\":\"up\"}\nnot json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadLog(path, time.Time{}); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Fatalf("ReadLog() error = %v, want one naming line 2", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	entries := []AuditEntry{
+		{Timestamp: time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC), Command: "up", User: "deploy", DatabaseHost: "db:5432", DurationMS: 1200},
+		{Timestamp: time.Date(2024, 1, 15, 15, 0, 0, 0, time.UTC), Command: "down", User: "ops, on call", ExitCode: 1, DurationMS: 300},
+	}
+
+	var table bytes.Buffer
+	if err := Export(&table, entries, "table"); err != nil {
+		t.Fatal(err)
+	}
+	want := "Timestamp             Command  User          Database  Duration  Status\n" +
+		"---------             -------  ----          --------  --------  ------\n" +
+		"2024-01-15T14:30:00Z  up       deploy        db:5432   1.2s      ok\n" +
+		"2024-01-15T15:00:00Z  down     ops, on call  -         300ms     failed (exit 1)\n"
+	if table.String() != want {
+		t.Errorf("table:\n%s\nwant:\n%s", table.String(), want)
+	}
+
+	var csv bytes.Buffer
+	if err := Export(&csv, entries, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	want = "Timestamp,Command,User,Database,Duration,Status\r\n" +
+		"2024-01-15T14:30:00Z,up,deploy,db:5432,1.2s,ok\r\n" +
+		"2024-01-15T15:00:00Z,down,\"ops, on call\",-,300ms,failed (exit 1)\r\n"
+	if csv.String() != want {
+		t.Errorf("csv:\n%q\nwant:\n%q", csv.String(), want)
+	}
+
+	if err := Export(&csv, entries, "xml"); err == nil {
+		t.Error("Export() with an unknown format succeeded")
+	}
+}
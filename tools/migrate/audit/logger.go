@@ -0,0 +1,52 @@
+// Package audit keeps a durable, append-only record of migration runs.
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// AuditEntry is a single line of the audit log.
+type AuditEntry struct {
+	Timestamp    time.Time `json:"timestamp"`
+	Command      string    `json:"command"`
+	User         string    `json:"user"`
+	Hostname     string    `json:"hostname"`
+	DatabaseHost string    `json:"database_host"`
+	ExitCode     int       `json:"exit_code"`
+	DurationMS   int64     `json:"duration_ms"`
+}
+
+// AuditLogger appends entries to a JSON Lines file.
+type AuditLogger struct {
+	path string
+}
+
+// NewAuditLogger returns a logger that appends to the file at path, creating
+// it if necessary.
+func NewAuditLogger(path string) *AuditLogger {
+	return &AuditLogger{path: path}
+}
+
+// Log appends entry to the log. The entry is written with a single Write on
+// a file opened with O_APPEND so that concurrent writers, including ones on
+// a shared log directory, do not interleave lines.
+func (l *AuditLogger) Log(entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(line)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
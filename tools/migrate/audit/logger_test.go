@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditLoggerWritesJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger := NewAuditLogger(path)
+
+	entries := []AuditEntry{
+		{Timestamp: time.Now(), Command: "up", User: "deploy", Hostname: "ci-1", DatabaseHost: "db:5432", ExitCode: 0, DurationMS: 1200},
+		{Timestamp: time.Now(), Command: "down", User: "deploy", Hostname: "ci-1", DatabaseHost: "db:5432", ExitCode: 1, DurationMS: 300},
+	}
+	for _, entry := range entries {
+		if err := logger.Log(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var lines int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var fields map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v", lines+1, err)
+		}
+		for _, key := range []string{"timestamp", "command", "user", "hostname", "database_host", "exit_code", "duration_ms"} {
+			if _, ok := fields[key]; !ok {
+				t.Errorf("line %d is missing %q", lines+1, key)
+			}
+		}
+		lines++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if lines != len(entries) {
+		t.Errorf("got %d lines, want %d", lines, len(entries))
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/audit"
+)
+
+// runAuditLogExport prints the audit log as a table or CSV, optionally only
+// the entries from the last --since.
+func runAuditLogExport(cfg Config) int {
+	var since time.Time
+	if cfg.Since > 0 {
+		since = time.Now().Add(-cfg.Since)
+	}
+
+	entries, err := audit.ReadLog(auditLogPath(cfg), since)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if err := audit.Export(os.Stdout, entries, cfg.Format); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/backup"
+)
+
+// backupTimeout bounds a single pg_dump or pg_restore run.
+const backupTimeout = time.Hour
+
+// newDumper returns a Dumper for databaseURL that runs the PostgreSQL tools
+// in environment.
+func newDumper(databaseURL string) *backup.Dumper {
+	dumper := backup.NewDumper(databaseURL)
+	dumper.Env = environment.Environ()
+	return dumper
+}
+
+// backupDatabase dumps the database to path, or to a timestamped file in
+// the backups directory when path is empty, and returns where it went.
+func backupDatabase(ctx context.Context, cfg Config, path string) (string, error) {
+	if path == "" {
+		path = backup.Path(cfg.BackupDir(), databaseName(cfg.DatabaseURL), time.Now())
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, backupTimeout)
+	defer cancel()
+
+	printer.Info("Backing up %s to %s", databaseName(cfg.DatabaseURL), path)
+	start := time.Now()
+	if err := newDumper(cfg.DatabaseURL).Dump(ctx, path); err != nil {
+		return "", err
+	}
+	printer.Success("Backup written to %s in %s", path, time.Since(start).Round(time.Millisecond))
+	return path, nil
+}
+
+// runBackup writes a pg_dump custom-format dump of the database to --output,
+// or to the backups directory.
+func runBackup(cfg Config) int {
+	if _, err := backupDatabase(context.Background(), cfg, cfg.Output); err != nil {
+		printer.Error("Backup failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// runRestore loads the dump at --input back into the database after the
+// same confirmation as fresh, since it replaces what is there.
+func runRestore(cfg Config) int {
+	if !cfg.Yes {
+		if err := confirmRestoreInteractive(cfg.DatabaseURL, cfg.Input); err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	printer.Info("Restoring %s from %s", databaseName(cfg.DatabaseURL), cfg.Input)
+	start := time.Now()
+	if err := newDumper(cfg.DatabaseURL).Restore(ctx, cfg.Input); err != nil {
+		printer.Error("Restore failed: %v", err)
+		return 1
+	}
+	printer.Success("Restored %s in %s", cfg.Input, time.Since(start).Round(time.Millisecond))
+	return 0
+}
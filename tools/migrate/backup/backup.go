@@ -0,0 +1,170 @@
+// Package backup dumps and restores a whole database with pg_dump and
+// pg_restore, so that a migration can be undone even when its down
+// migration cannot.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Dumper runs pg_dump and pg_restore against one database.
+type Dumper struct {
+	// DatabaseURL is passed to the tools as PG* environment variables,
+	// never as arguments, where the password would be visible to other
+	// users.
+	DatabaseURL string
+	// Stderr receives the tools' diagnostics.
+	Stderr io.Writer
+	// Env is the environment the tools run in, before the PG* variables
+	// are added; nil means the process environment.
+	Env []string
+}
+
+// NewDumper returns a Dumper for the database at databaseURL.
+func NewDumper(databaseURL string) *Dumper {
+	return &Dumper{DatabaseURL: databaseURL, Stderr: os.Stderr}
+}
+
+// Dump writes a custom-format (pg_dump -Fc) dump of the whole database to
+// path. The dump is written to a temporary file beside path first, so a
+// failed dump never leaves a truncated backup behind.
+func (d *Dumper) Dump(ctx context.Context, path string) error {
+	cmd, err := d.command(ctx, "pg_dump", "--format=custom")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".backup-*.dump")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	cmd.Stdout = tmp
+	err = cmd.Run()
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("pg_dump: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Schema writes a plain SQL dump of the database's schema, without data or
+// object owners, to w. The tables in exclude are left out.
+func (d *Dumper) Schema(ctx context.Context, w io.Writer, exclude ...string) error {
+	args := []string{"--schema-only", "--no-owner"}
+	for _, table := range exclude {
+		args = append(args, "--exclude-table="+table)
+	}
+	cmd, err := d.command(ctx, "pg_dump", args...)
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = w
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pg_dump: %w", err)
+	}
+	return nil
+}
+
+// Restore loads the dump at path back into the database, dropping the
+// objects it contains first. It runs in a single transaction, so a failed
+// restore leaves the database as it was.
+func (d *Dumper) Restore(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	u, err := url.Parse(d.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("DATABASE_URL is not a valid URL: %v", err)
+	}
+	database := strings.Trim(u.Path, "/")
+	if database == "" {
+		return errors.New("DATABASE_URL names no database to restore into")
+	}
+
+	// Without --dbname pg_restore prints the dump as SQL instead of
+	// restoring it. The name alone is safe to pass as an argument.
+	cmd, err := d.command(ctx, "pg_restore", "--dbname", database, "--clean", "--if-exists",
+		"--single-transaction", "--exit-on-error", "--no-owner", path)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pg_restore: %w", err)
+	}
+	return nil
+}
+
+func (d *Dumper) command(ctx context.Context, tool string, args ...string) (*exec.Cmd, error) {
+	bin, err := exec.LookPath(tool)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found in PATH; install the PostgreSQL client tools", tool)
+	}
+	vars, err := Env(d.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Stderr = d.Stderr
+	env := d.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(append([]string(nil), env...), vars...)
+	return cmd, nil
+}
+
+// Path returns the name of a backup of database taken at t in dir, e.g.
+// backups/crypto_bot-20240115T143000Z.dump.
+func Path(dir, database string, t time.Time) string {
+	if database == "" {
+		database = "backup"
+	}
+	return filepath.Join(dir, database+"-"+t.UTC().Format("20060102T150405Z")+".dump")
+}
+
+// Env translates a database URL into the PG* environment variables
+// understood by libpq tools such as pg_dump, so credentials stay out of
+// their command line.
+func Env(databaseURL string) ([]string, error) {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("DATABASE_URL is not a valid URL: %v", err)
+	}
+
+	var vars []string
+	add := func(key, value string) {
+		if value != "" {
+			vars = append(vars, key+"="+value)
+		}
+	}
+
+	add("PGHOST", u.Hostname())
+	add("PGPORT", u.Port())
+	add("PGDATABASE", strings.Trim(u.Path, "/"))
+	if u.User != nil {
+		add("PGUSER", u.User.Username())
+		password, _ := u.User.Password()
+		add("PGPASSWORD", password)
+	}
+
+	sslmode := u.Query().Get("sslmode")
+	if sslmode == "" && u.Scheme == "postgresql+ssl" {
+		sslmode = "require"
+	}
+	add("PGSSLMODE", sslmode)
+
+	return vars, nil
+}
@@ -0,0 +1,165 @@
+// Package bench times how long migrations take to apply on a database,
+// without keeping what they did.
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Migration is a migration to time, with the SQL of its up and down files.
+type Migration struct {
+	Name string
+	Up   []byte
+	// Down is needed in transaction mode, to undo the migration before it
+	// is applied again.
+	Down []byte
+}
+
+// Result is how long one migration took to apply.
+type Result struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Benchmark applies migrations that are already applied to DB a second
+// time, in a sandbox that is thrown away afterwards, and times each one.
+//
+// By default the sandbox is a transaction: the migrations' down files are
+// run newest first, their up files are timed oldest first, and the
+// transaction is rolled back. With Schema set it is instead a new schema of
+// that name, into which the migrations before the timed ones are applied
+// untimed to recreate what they build on; the schema is dropped at the end.
+// Only unqualified names are redirected to the schema, so a migration that
+// names public.orders still changes public.orders.
+type Benchmark struct {
+	DB     *sql.DB
+	Schema string
+	// OnApply, if set, is called with the name of each timed migration
+	// before it is applied.
+	OnApply func(name string)
+}
+
+// Run times the up files of timed, applied in order. setup are the
+// migrations before them, only applied in schema mode.
+func (b *Benchmark) Run(ctx context.Context, setup, timed []Migration) ([]Result, error) {
+	if b.Schema != "" {
+		return b.runInSchema(ctx, setup, timed)
+	}
+	return b.runInTransaction(ctx, timed)
+}
+
+func (b *Benchmark) runInTransaction(ctx context.Context, timed []Migration) ([]Result, error) {
+	for _, m := range timed {
+		if m.Down == nil {
+			return nil, fmt.Errorf("%s has no down file to undo it with before it is applied again; use a schema instead", m.Name)
+		}
+	}
+
+	tx, err := b.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Whatever happens, nothing the benchmark did is kept.
+	defer tx.Rollback()
+
+	for i := len(timed) - 1; i >= 0; i-- {
+		m := timed[i]
+		if _, err := tx.ExecContext(ctx, string(m.Down)); err != nil {
+			return nil, fmt.Errorf("undo %s: %w", m.Name, err)
+		}
+	}
+	return b.time(ctx, tx, timed)
+}
+
+func (b *Benchmark) runInSchema(ctx context.Context, setup, timed []Migration) (results []Result, err error) {
+	// search_path is set per session, so every statement must go over the
+	// same connection.
+	conn, err := b.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	schema := pgx.Identifier{b.Schema}.Sanitize()
+	// Without IF NOT EXISTS, so that an existing schema is never dropped.
+	if _, err := conn.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		return nil, fmt.Errorf("create schema %s: %w", b.Schema, err)
+	}
+	defer func() {
+		// ctx may be the reason Run is returning, so the drop gets its own.
+		dropCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if _, dropErr := conn.ExecContext(dropCtx, "DROP SCHEMA "+schema+" CASCADE"); dropErr != nil {
+			err = errors.Join(err, fmt.Errorf("drop schema %s: %w", b.Schema, dropErr))
+		}
+	}()
+	if _, err := conn.ExecContext(ctx, "SET search_path TO "+schema); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(context.Background(), "RESET search_path")
+
+	for _, m := range setup {
+		if _, err := conn.ExecContext(ctx, string(m.Up)); err != nil {
+			return nil, fmt.Errorf("set up %s: %w", m.Name, err)
+		}
+	}
+	return b.time(ctx, conn, timed)
+}
+
+// execer is what *sql.Tx and *sql.Conn have in common.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (b *Benchmark) time(ctx context.Context, db execer, timed []Migration) ([]Result, error) {
+	results := make([]Result, 0, len(timed))
+	for _, m := range timed {
+		if b.OnApply != nil {
+			b.OnApply(m.Name)
+		}
+		start := time.Now()
+		if _, err := db.ExecContext(ctx, string(m.Up)); err != nil {
+			return nil, fmt.Errorf("apply %s: %w", m.Name, err)
+		}
+		results = append(results, Result{Name: m.Name, Duration: time.Since(start)})
+	}
+	return results, nil
+}
+
+// Summary is the spread of a set of durations.
+type Summary struct {
+	Min, Max      time.Duration
+	P50, P95, P99 time.Duration
+}
+
+// Summarize returns the summary of the durations in results, by the
+// nearest-rank method: each percentile is one of the measured durations.
+func Summarize(results []Result) Summary {
+	if len(results) == 0 {
+		return Summary{}
+	}
+	durations := make([]time.Duration, len(results))
+	for i, r := range results {
+		durations[i] = r.Duration
+	}
+	slices.Sort(durations)
+
+	percentile := func(p int) time.Duration {
+		rank := (p*len(durations) + 99) / 100
+		return durations[max(rank, 1)-1]
+	}
+	return Summary{
+		Min: durations[0],
+		Max: durations[len(durations)-1],
+		P50: percentile(50),
+		P95: percentile(95),
+		P99: percentile(99),
+	}
+}
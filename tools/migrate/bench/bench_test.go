@@ -0,0 +1,44 @@
+package bench
+
+import (
+	"testing"
+	"time"
+)
+
+func results(ms ...int) []Result {
+	r := make([]Result, len(ms))
+	for i, n := range ms {
+		r[i] = Result{Name: "m", Duration: time.Duration(n) * time.Millisecond}
+	}
+	return r
+}
+
+func TestSummarize(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		results []Result
+		want    Summary
+	}{
+		{"empty", nil, Summary{}},
+		{"one", results(7), Summary{7 * ms, 7 * ms, 7 * ms, 7 * ms, 7 * ms}},
+		{"unsorted", results(30, 10, 20, 40), Summary{10 * ms, 40 * ms, 20 * ms, 40 * ms, 40 * ms}},
+		{"ten", results(1, 2, 3, 4, 5, 6, 7, 8, 9, 100), Summary{1 * ms, 100 * ms, 5 * ms, 100 * ms, 100 * ms}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.results); got != tt.want {
+				t.Errorf("Summarize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	hundred := make([]int, 100)
+	for i := range hundred {
+		hundred[i] = 100 - i
+	}
+	got := Summarize(results(hundred...))
+	if got.P50 != 50*ms || got.P95 != 95*ms || got.P99 != 99*ms {
+		t.Errorf("percentiles of 1..100ms = %s, %s, %s; want 50ms, 95ms, 99ms", got.P50, got.P95, got.P99)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/bench"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// runBenchmark applies the last --count applied migrations again, without
+// Cargo and without keeping the result, and prints how long each took with
+// the spread of the times, as a guide to how long future migrations will
+// take on this hardware.
+func runBenchmark(cfg Config) int {
+	files, state, err := migrationState(cfg)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	var applied []migrations.File
+	for i, m := range state {
+		if m.Applied {
+			applied = append(applied, files[i])
+		}
+	}
+	if len(applied) == 0 {
+		printer.Error("Error: no migrations are applied, so there is nothing to benchmark")
+		return 1
+	}
+	count := cfg.BenchCount
+	if count > len(applied) {
+		printer.Warn("Only %d migration(s) are applied; benchmarking all of them", len(applied))
+		count = len(applied)
+	}
+
+	var setup []bench.Migration
+	if cfg.BenchSchema != "" {
+		if setup, err = loadBenchMigrations(applied[:len(applied)-count]); err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+	}
+	timed, err := loadBenchMigrations(applied[len(applied)-count:])
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	ctx, cancel := cfg.commandContext()
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	if cfg.BenchSchema != "" {
+		printer.Info("Benchmarking %d migration(s) in schema %s, which is dropped afterwards", count, cfg.BenchSchema)
+	} else {
+		printer.Info("Benchmarking %d migration(s) in a transaction that is rolled back", count)
+	}
+	b := &bench.Benchmark{
+		DB:     conn,
+		Schema: cfg.BenchSchema,
+		OnApply: func(name string) {
+			printer.Info("Applying %s", name)
+		},
+	}
+	results, err := b.Run(ctx, setup, timed)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			printer.Error("Error: benchmark exceeded --timeout %s", cfg.Timeout)
+			return exitTimeout
+		}
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MIGRATION\tDURATION")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\n", r.Name, r.Duration.Round(time.Microsecond))
+	}
+	w.Flush()
+
+	s := bench.Summarize(results)
+	fmt.Printf("\nmin %s  max %s  p50 %s  p95 %s  p99 %s\n",
+		s.Min.Round(time.Microsecond), s.Max.Round(time.Microsecond),
+		s.P50.Round(time.Microsecond), s.P95.Round(time.Microsecond), s.P99.Round(time.Microsecond))
+	return 0
+}
+
+// loadBenchMigrations reads the up and down files of files.
+func loadBenchMigrations(files []migrations.File) ([]bench.Migration, error) {
+	var namer migrations.MigrationNamer
+
+	loaded := make([]bench.Migration, len(files))
+	for i, file := range files {
+		name := namer.Base(file.Sequence, file.Name)
+		if file.UpPath == "" {
+			return nil, fmt.Errorf("migration %s has no up file", name)
+		}
+		up, err := os.ReadFile(file.UpPath)
+		if err != nil {
+			return nil, err
+		}
+		loaded[i] = bench.Migration{Name: name, Up: up}
+		if file.DownPath != "" {
+			if loaded[i].Down, err = os.ReadFile(file.DownPath); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return loaded, nil
+}
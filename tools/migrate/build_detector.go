@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// cargoBuildLine matches the status lines Cargo prints while it resolves and
+// compiles the migration crate. Cargo's "Running `target/...`" line does not
+// match, so the run phase starts exactly when the migration binary launches.
+var cargoBuildLine = regexp.MustCompile(`^\s*(Blocking|Updating|Locking|Adding|Downloading|Downloaded|Compiling|Checking|Fresh|Finished)\s`)
+
+// BuildPhaseDetector splits the runtime of `cargo run` into the time spent
+// building and the time spent running the migration. The build phase lasts
+// until the first line of output that does not match its pattern.
+type BuildPhaseDetector struct {
+	pattern *regexp.Regexp
+
+	mu         sync.Mutex
+	start      time.Time
+	transition time.Time
+	end        time.Time
+	buildLines []string
+}
+
+// NewBuildPhaseDetector returns a detector that treats lines matching
+// pattern as build output.
+func NewBuildPhaseDetector(pattern *regexp.Regexp) *BuildPhaseDetector {
+	return &BuildPhaseDetector{pattern: pattern}
+}
+
+// Attach routes cmd's stdout and stderr through the detector, line by line,
+// before passing them on unchanged. It must be called just before cmd is
+// started, which is when the build phase begins, and Finish must be called
+// once cmd has exited and its output has been flushed.
+func (d *BuildPhaseDetector) Attach(cmd *exec.Cmd) {
+	d.start = time.Now()
+	cmd.Stdout = &phaseWriter{detector: d, w: cmd.Stdout}
+	cmd.Stderr = &phaseWriter{detector: d, w: cmd.Stderr}
+}
+
+// Finish marks the end of the command.
+func (d *BuildPhaseDetector) Finish() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.end = time.Now()
+}
+
+// BuildTime returns how long Cargo spent before the migration started, or
+// the whole runtime if it never did.
+func (d *BuildPhaseDetector) BuildTime() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.transition.IsZero() {
+		return d.end.Sub(d.start)
+	}
+	return d.transition.Sub(d.start)
+}
+
+// MigrationTime returns how long the migration itself ran, and false if
+// the run phase was never reached.
+func (d *BuildPhaseDetector) MigrationTime() (time.Duration, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.transition.IsZero() {
+		return 0, false
+	}
+	return d.end.Sub(d.transition), true
+}
+
+// BuildLines returns the lines Cargo printed during the build phase.
+func (d *BuildPhaseDetector) BuildLines() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.buildLines...)
+}
+
+func (d *BuildPhaseDetector) observe(line []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.transition.IsZero() {
+		return
+	}
+	if d.pattern.Match(line) {
+		d.buildLines = append(d.buildLines, string(line))
+		return
+	}
+	d.transition = time.Now()
+}
+
+// phaseWriter passes complete lines to the detector and then on to w.
+// exec.Cmd copies the child's output into it from its own goroutine.
+type phaseWriter struct {
+	detector *BuildPhaseDetector
+	w        io.Writer
+	buf      []byte
+}
+
+func (p *phaseWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		line := p.buf[:i+1]
+		p.detector.observe(bytes.TrimRight(line, "\r\n"))
+		if _, err := p.w.Write(line); err != nil {
+			return len(b), err
+		}
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush passes on a final line that was not terminated by a newline.
+func (p *phaseWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.detector.observe(p.buf)
+		p.w.Write(p.buf)
+		p.buf = nil
+	}
+	if f, ok := p.w.(flusher); ok {
+		f.Flush()
+	}
+}
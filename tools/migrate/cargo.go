@@ -0,0 +1,25 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// findCargo returns the Cargo binary to run: $CARGO_BIN when set, otherwise
+// cargo from PATH.
+func findCargo() (string, error) {
+	if bin := getenv("CARGO_BIN"); bin != "" {
+		path, err := exec.LookPath(bin)
+		if err != nil {
+			return "", fmt.Errorf("CARGO_BIN=%s is not an executable: %w", bin, errors.Unwrap(err))
+		}
+		return path, nil
+	}
+
+	path, err := exec.LookPath("cargo")
+	if err != nil {
+		return "", errors.New("cargo not found in PATH; add it to PATH, or point CARGO_BIN or --cargo-bin at the binary")
+	}
+	return path, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFakeCargo(t *testing.T, dir string) string {
+	t.Helper()
+	path := filepath.Join(dir, "cargo")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFindCargoFromPath(t *testing.T) {
+	dir := t.TempDir()
+	want := writeFakeCargo(t, dir)
+	t.Setenv("PATH", dir)
+	t.Setenv("CARGO_BIN", "")
+
+	got, err := findCargo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("findCargo() = %q, want %q", got, want)
+	}
+}
+
+func TestFindCargoPrefersCargoBin(t *testing.T) {
+	pathDir, binDir := t.TempDir(), t.TempDir()
+	writeFakeCargo(t, pathDir)
+	want := writeFakeCargo(t, binDir)
+	t.Setenv("PATH", pathDir)
+	t.Setenv("CARGO_BIN", want)
+
+	got, err := findCargo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("findCargo() = %q, want %q", got, want)
+	}
+}
+
+func TestFindCargoInvalidCargoBin(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeCargo(t, dir)
+	t.Setenv("PATH", dir)
+	t.Setenv("CARGO_BIN", filepath.Join(dir, "missing"))
+
+	_, err := findCargo()
+	if err == nil || !strings.Contains(err.Error(), "CARGO_BIN") {
+		t.Errorf("findCargo() error = %v, want one naming CARGO_BIN", err)
+	}
+}
+
+func TestFindCargoNotFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("CARGO_BIN", "")
+
+	_, err := findCargo()
+	if err == nil || !strings.Contains(err.Error(), "--cargo-bin") {
+		t.Errorf("findCargo() error = %v, want one suggesting --cargo-bin", err)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/crypto-bot/tools/migrate/changelog"
+)
+
+// runGenerateChangelog writes the migrations added between two git
+// revisions, or numbered above --after with --no-git, to --output or stdout
+// as Markdown for release notes.
+func runGenerateChangelog(cfg Config) int {
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	var (
+		entries []changelog.Entry
+		heading string
+		err     error
+	)
+	if cfg.NoGit {
+		entries, err = changelog.After(cfg.SQLDir(), cfg.After)
+		heading = fmt.Sprintf("Migrations after %06d", cfg.After)
+	} else {
+		entries, err = changelog.Added(ctx, cfg.SQLDir(), cfg.ChangelogFrom, cfg.ChangelogTo)
+		heading = fmt.Sprintf("Migrations in %s..%s", cfg.ChangelogFrom, cfg.ChangelogTo)
+	}
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	write := func(w io.Writer) error {
+		return changelog.Write(w, heading, entries)
+	}
+	if cfg.Output == "" {
+		err = write(os.Stdout)
+	} else {
+		err = writeOutput(cfg.Output, write)
+	}
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if cfg.Output != "" {
+		printer.Success("Changelog of %d migration(s) written to %s", len(entries), cfg.Output)
+	}
+	return 0
+}
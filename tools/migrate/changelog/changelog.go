@@ -0,0 +1,112 @@
+// Package changelog lists the migrations added between two releases as
+// Markdown for release notes.
+package changelog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// Entry is a migration in the changelog.
+type Entry struct {
+	Sequence int
+	Name     string
+}
+
+// Title is the human-readable form of the migration's name, e.g. "Create
+// wallets table" for create_wallets_table.
+func (e Entry) Title() string {
+	title := strings.ReplaceAll(e.Name, "_", " ")
+	if title == "" {
+		return title
+	}
+	return strings.ToUpper(title[:1]) + title[1:]
+}
+
+// Added returns the migrations whose files were added to dir, a directory
+// in a git work tree, between the revisions from and to, using
+// `git log --diff-filter=A`.
+func Added(ctx context.Context, dir, from, to string) ([]Entry, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "log", "--diff-filter=A", "--name-only", "--format=",
+		from+".."+to, "--", ".")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git log: %s", msg)
+		}
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			names = append(names, filepath.Base(line))
+		}
+	}
+	return entries(names), scanner.Err()
+}
+
+// After returns the migrations in dir with a sequence number greater than
+// sequence, for when git history is not available.
+func After(dir string, sequence int) ([]Entry, error) {
+	files, err := migrations.Scan(dir)
+	if err != nil {
+		return nil, err
+	}
+	var result []Entry
+	for _, file := range files {
+		if file.Sequence > sequence {
+			result = append(result, Entry{Sequence: file.Sequence, Name: file.Name})
+		}
+	}
+	return result, nil
+}
+
+// entries turns migration file names into entries ordered by sequence
+// number, with the up and down files of a migration counted once. Names
+// that are not migration files are skipped.
+func entries(names []string) []Entry {
+	var namer migrations.MigrationNamer
+	seen := make(map[string]bool)
+	var result []Entry
+	for _, name := range names {
+		sequence, stem, ok := namer.Parse(name)
+		if !ok || seen[namer.Base(sequence, stem)] {
+			continue
+		}
+		seen[namer.Base(sequence, stem)] = true
+		result = append(result, Entry{Sequence: sequence, Name: stem})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Sequence < result[j].Sequence
+	})
+	return result
+}
+
+// Write renders entries as a Markdown section headed heading, one ordered
+// list item per migration.
+func Write(w io.Writer, heading string, entries []Entry) error {
+	var namer migrations.MigrationNamer
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", heading)
+	if len(entries) == 0 {
+		b.WriteString("No new migrations.\n")
+	}
+	for i, e := range entries {
+		fmt.Fprintf(&b, "%d. %s (`%s`)\n", i+1, e.Title(), namer.Base(e.Sequence, e.Name))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,115 @@
+package changelog
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEntries(t *testing.T) {
+	got := entries([]string{
+		"000003_add_index.up.sql",
+		"000002_create_wallets_table.down.sql",
+		"000002_create_wallets_table.up.sql",
+		"README.md",
+		"000001_init.sql",
+	})
+	want := []Entry{
+		{1, "init"},
+		{2, "create_wallets_table"},
+		{3, "add_index"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAfter(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"000001_a.up.sql", "000002_b.up.sql", "000002_b.down.sql", "000003_c.up.sql"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := After(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{{2, "b"}, {3, "c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("After() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var b strings.Builder
+	if err := Write(&b, "Migrations in v1.2.0..v1.3.0", []Entry{{4, "create_wallets_table"}, {5, "add_fee_column"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := "## Migrations in v1.2.0..v1.3.0\n\n" +
+		"1. Create wallets table (`000004_create_wallets_table`)\n" +
+		"2. Add fee column (`000005_add_fee_column`)\n"
+	if b.String() != want {
+		t.Errorf("Write() = %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	if err := Write(&b, "Migrations after 000005", nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := "## Migrations after 000005\n\nNo new migrations.\n"; b.String() != want {
+		t.Errorf("Write() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestAdded(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	add := func(names ...string) {
+		t.Helper()
+		for _, name := range names {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git("add", ".")
+		git("commit", "-q", "-m", "add "+names[0])
+	}
+
+	git("init", "-q")
+	add("000001_init.up.sql")
+	git("tag", "v1")
+	add("000002_create_wallets_table.up.sql", "000002_create_wallets_table.down.sql")
+	add("000003_add_index.up.sql")
+	git("tag", "v2")
+	add("000004_later.up.sql")
+
+	got, err := Added(context.Background(), dir, "v1", "v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{{2, "create_wallets_table"}, {3, "add_index"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Added() = %+v, want %+v", got, want)
+	}
+
+	if _, err := Added(context.Background(), dir, "nope", "v2"); err == nil {
+		t.Error("Added() with an unknown revision succeeded")
+	}
+}
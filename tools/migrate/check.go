@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+
+	"github.com/crypto-bot/tools/migrate/integrity"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+)
+
+// runCheck fails if any applied migration file has been edited or removed
+// since it was applied, so that it can gate CI pipelines.
+func runCheck(cfg Config) int {
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	mismatches, err := integrity.NewChecksumChecker(conn, cfg.SQLDir()).Check(ctx)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	if len(mismatches) > 0 {
+		printer.Error("%d applied migration(s) changed since they were applied:", len(mismatches))
+		for _, m := range mismatches {
+			printer.Error("  - %s", m)
+		}
+		return 1
+	}
+
+	printer.Success("All applied migrations match their files")
+	return 0
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/interactive"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"github.com/crypto-bot/tools/migrate/repair"
+)
+
+// runClean reports the migration files the tracking table does not record
+// as applied and the records whose files are gone, and with
+// --remove-unapplied or --remove-orphaned-records deletes them, after
+// offering a backup and asking for confirmation.
+func runClean(cfg Config) int {
+	var namer migrations.MigrationNamer
+
+	files, err := migrations.Scan(cfg.SQLDir())
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	var applied []interactive.Migration
+	exists, err := db.NewPostgresRepository(conn).HasTrackingTable(ctx)
+	if err == nil && exists {
+		applied, err = appliedNewestFirst(ctx, conn)
+	}
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	unapplied, orphaned := unmatchedMigrations(files, applied)
+	if len(unapplied) == 0 && len(orphaned) == 0 {
+		printer.Success("Every migration file is recorded as applied and every record has a file")
+		return 0
+	}
+	if len(unapplied) > 0 {
+		printer.Info("Files not recorded as applied (%d):", len(unapplied))
+		for _, file := range unapplied {
+			printer.Info("  - %s", namer.Base(file.Sequence, file.Name))
+		}
+	}
+	if len(orphaned) > 0 {
+		printer.Info("Records without a file in %s (%d):", cfg.SQLDir(), len(orphaned))
+		for _, m := range orphaned {
+			printer.Info("  - %s", m)
+		}
+	}
+
+	if cfg.RemoveUnapplied && len(unapplied) > 0 {
+		if code := removeUnappliedFiles(cfg, unapplied); code != 0 {
+			return code
+		}
+	}
+	if cfg.RemoveOrphanedRecords && len(orphaned) > 0 {
+		return removeOrphanedRecords(cfg, orphaned)
+	}
+	return 0
+}
+
+// unmatchedMigrations returns the files with no successful record in
+// applied and the records in applied with no file, both oldest first.
+func unmatchedMigrations(files []migrations.File, applied []interactive.Migration) ([]migrations.File, []interactive.Migration) {
+	recorded := make(map[int64]bool, len(applied))
+	for _, m := range applied {
+		recorded[m.Version] = true
+	}
+	onDisk := make(map[int64]bool, len(files))
+	var unapplied []migrations.File
+	for _, file := range files {
+		onDisk[int64(file.Sequence)] = true
+		if !recorded[int64(file.Sequence)] {
+			unapplied = append(unapplied, file)
+		}
+	}
+
+	var orphaned []interactive.Migration
+	for i := len(applied) - 1; i >= 0; i-- {
+		if !onDisk[applied[i].Version] {
+			orphaned = append(orphaned, applied[i])
+		}
+	}
+	return unapplied, orphaned
+}
+
+// removeUnappliedFiles deletes the up and down files of unapplied once the
+// user has confirmed, copying them to the backups directory first if the
+// user wants a backup.
+func removeUnappliedFiles(cfg Config, unapplied []migrations.File) int {
+	var paths []string
+	for _, file := range unapplied {
+		for _, path := range []string{file.UpPath, file.DownPath} {
+			if path != "" {
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	backUp, err := confirmClean(cfg, fmt.Sprintf("delete %d migration file(s) that were never applied", len(paths)))
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if backUp {
+		dir := filepath.Join(cfg.BackupDir(), "clean-"+time.Now().UTC().Format("20060102T150405Z"))
+		if err := copyFiles(dir, paths); err != nil {
+			printer.Error("Error: backup failed, not deleting anything: %v", err)
+			return 1
+		}
+		printer.Success("Copied the files to %s", dir)
+	}
+
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		printer.Success("✓ Deleted %s", path)
+	}
+	return 0
+}
+
+// removeOrphanedRecords deletes the tracking table rows of orphaned once the
+// user has confirmed, dumping the database first if the user wants a
+// backup. The change is written to the audit log like a repair.
+func removeOrphanedRecords(cfg Config, orphaned []interactive.Migration) int {
+	backUp, err := confirmClean(cfg, fmt.Sprintf("delete %d tracking table record(s) whose files are gone", len(orphaned)))
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if backUp {
+		if _, err := backupDatabase(context.Background(), cfg, ""); err != nil {
+			printer.Error("Error: backup failed, not deleting anything: %v", err)
+			return 1
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	exitCode := 0
+	repairer := repair.NewRepairer(conn)
+	for _, m := range orphaned {
+		if err := repairer.MarkRolledBack(ctx, m.Version); err != nil {
+			printer.Error("✗ %s: %v", m, err)
+			exitCode = 1
+			break
+		}
+		printer.Success("✓ Deleted the record of %s", m)
+	}
+	recordAudit(cfg, start, exitCode, time.Since(start))
+	return exitCode
+}
+
+// confirmClean warns that clean is about to do action, offers a backup and
+// asks for confirmation through confirmInteractive, returning whether to back
+// up first. With --yes it backs up without asking; with no answer to read
+// on stdin it refuses, as confirmInteractive does.
+func confirmClean(cfg Config, action string) (backUp bool, err error) {
+	if cfg.Yes {
+		return true, nil
+	}
+
+	backUp = true
+	err = confirmInteractive(os.Stdin, os.Stdout, "the clean", func(in io.Reader, out io.Writer) error {
+		fmt.Fprintf(out, "WARNING: clean will %s.\n", action)
+		switch err := confirmYes(in, out, "Back up first?"); {
+		case errors.Is(err, errNotConfirmed):
+			backUp = false
+		case err != nil:
+			return err
+		}
+		return confirmYes(in, out, "Proceed with the clean?")
+	})
+	return backUp, err
+}
+
+// copyFiles copies each of paths into dir, which is created.
+func copyFiles(dir string, paths []string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(path)), data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/crypto-bot/tools/migrate/interactive"
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+func TestUnmatchedMigrations(t *testing.T) {
+	files := []migrations.File{
+		{Sequence: 1, Name: "create_users"},
+		{Sequence: 2, Name: "create_orders"},
+		{Sequence: 4, Name: "add_fees"},
+	}
+	applied := []interactive.Migration{
+		{Version: 5, Name: "drop_legacy"},
+		{Version: 3, Name: "add_limits"},
+		{Version: 1, Name: "create_users"},
+	}
+
+	unapplied, orphaned := unmatchedMigrations(files, applied)
+	if want := []migrations.File{files[1], files[2]}; !reflect.DeepEqual(unapplied, want) {
+		t.Errorf("unapplied = %v, want %v", unapplied, want)
+	}
+	if want := []interactive.Migration{applied[1], applied[0]}; !reflect.DeepEqual(orphaned, want) {
+		t.Errorf("orphaned = %v, want %v", orphaned, want)
+	}
+}
+
+func TestConfirmCleanRefusesWithoutAnswer(t *testing.T) {
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	stdin := os.Stdin
+	os.Stdin = devNull
+	defer func() { os.Stdin = stdin }()
+
+	_, err = confirmClean(Config{}, "delete 1 migration file(s) that were never applied")
+	if err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Errorf("confirmClean with stdin on %s = %v, want an error naming --yes", os.DevNull, err)
+	}
+	if backUp, err := confirmClean(Config{Yes: true}, "delete 1 migration file(s) that were never applied"); err != nil || !backUp {
+		t.Errorf("confirmClean with --yes = %v, %v, want a backup and no error", backUp, err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/doctor"
+	"github.com/crypto-bot/tools/migrate/metrics"
+	"github.com/crypto-bot/tools/migrate/secrets"
+)
+
+const (
+	// cloudRunDeadline is how long migrate cloud-run may take in total.
+	cloudRunDeadline = 10 * time.Minute
+	// cloudRunMargin is how much of the deadline is kept back from the
+	// migration itself, so that a migration stopped at the deadline still
+	// leaves time to release the lock, run the post-migrate hook and push
+	// metrics.
+	cloudRunMargin = 30 * time.Second
+	// cloudMetricsTimeout bounds loading credentials and pushing metrics.
+	cloudMetricsTimeout = 10 * time.Second
+)
+
+// cloudWatchNamespace is the CloudWatch namespace cloud-run reports to.
+const cloudWatchNamespace = "CryptoBot/Migrate"
+
+// cloudMetricsClient is what metrics.CloudMonitoring and metrics.CloudWatch
+// have in common.
+type cloudMetricsClient interface {
+	Push(ctx context.Context, name string, labels map[string]string, value float64, at time.Time) error
+}
+
+// setupCloudRun prepares migrate cloud-run for a Cloud Run or Fargate job,
+// which runs one container per task and is billed until it exits: one
+// scheduler thread, logs in the JSON format of the CLOUD_PROVIDER, gcp or
+// aws, and a deadline of cloudRunDeadline, less cloudRunMargin, after which
+// the migration is stopped as on --timeout. It must run before
+// setupLogging.
+func setupCloudRun(cfg *Config) error {
+	provider := strings.ToLower(getenv("CLOUD_PROVIDER"))
+	if provider != "gcp" && provider != "aws" {
+		return fmt.Errorf("cloud-run needs CLOUD_PROVIDER set to gcp or aws, not %q", provider)
+	}
+
+	runtime.GOMAXPROCS(1)
+	environment.Set("GOMAXPROCS", "1")
+	environment.Set("LOG_FORMAT", provider)
+
+	cfg.Deadline = time.Now().Add(cloudRunDeadline - cloudRunMargin)
+	return nil
+}
+
+// runCloudRun runs the health checks, then the migration with its lock,
+// hooks and audit record, and reports how long it took to Cloud Monitoring
+// or CloudWatch. It exits with 0 or 1 only, as job runners expect. Every
+// step is bounded by the cloudRunDeadline: waiting for the lock, even with
+// --lock-timeout 0, the safe-mode checks, the backup and the hooks as much
+// as the migration, which has to finish cloudRunMargin sooner.
+func runCloudRun(cfg Config) int {
+	ctx, cancel := context.WithDeadline(context.Background(), cfg.Deadline.Add(cloudRunMargin))
+	defer cancel()
+
+	checkCtx, cancelChecks := context.WithTimeout(ctx, time.Minute)
+	checkCtx, cancelDeadline := context.WithDeadline(checkCtx, cfg.Deadline)
+	results := doctor.RunChecks(checkCtx, healthChecks(cfg))
+	cancelDeadline()
+	cancelChecks()
+	for _, r := range results {
+		printer.Info("Health check %s: %s: %s", r.Name, r.Status, r.Message)
+	}
+	if !doctor.Passed(results) {
+		printer.Error("Error: health checks failed, not running the migration")
+		return 1
+	}
+
+	start := time.Now()
+	exitCode := migrateDatabase(ctx, cfg)
+	pushCloudMetrics(cfg.Command, exitCode, time.Since(start))
+
+	if ctx.Err() != nil || (exitCode == exitTimeout && !time.Now().Before(cfg.Deadline)) {
+		printer.Error("Error: cloud-run exceeded its %s deadline", cloudRunDeadline)
+	}
+	if exitCode != 0 {
+		return 1
+	}
+	return 0
+}
+
+// pushCloudMetrics reports how long a migration run took to the monitoring
+// service of CLOUD_PROVIDER, as pushMetrics does to a Pushgateway. Failures
+// only produce a warning.
+func pushCloudMetrics(command string, exitCode int, duration time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), cloudMetricsTimeout)
+	defer cancel()
+
+	client, err := newCloudMetricsClient(ctx, strings.ToLower(getenv("CLOUD_PROVIDER")))
+	if err == nil {
+		status := "success"
+		if exitCode != 0 {
+			status = "failure"
+		}
+		labels := map[string]string{"command": command, "status": status}
+		err = client.Push(ctx, "migration_duration_seconds", labels, duration.Seconds(), time.Now())
+	}
+	if err != nil {
+		printer.Warn("Warning: failed to push metrics: %v", err)
+	}
+}
+
+func newCloudMetricsClient(ctx context.Context, provider string) (cloudMetricsClient, error) {
+	if provider == "aws" {
+		return newCloudWatch(ctx)
+	}
+
+	project := getenv("GCP_PROJECT_ID")
+	if project == "" {
+		project = getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if project == "" {
+		return nil, errors.New("no GCP project for Cloud Monitoring; set GCP_PROJECT_ID")
+	}
+	creds := &secrets.GCPCredentials{Client: &http.Client{Timeout: gcpTimeout}, Getenv: getenv}
+	return &metrics.CloudMonitoring{Project: project, Token: creds.Token}, nil
+}
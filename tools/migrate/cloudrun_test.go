@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCommandContextStopsAtDeadline(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	for _, tt := range []struct {
+		timeout time.Duration
+		want    time.Time
+	}{
+		{timeout: 0, want: deadline},
+		{timeout: time.Hour, want: deadline},
+		{timeout: time.Second, want: time.Now().Add(time.Second)},
+	} {
+		ctx, cancel := Config{Timeout: tt.timeout, Deadline: deadline}.commandContext()
+		got, ok := ctx.Deadline()
+		cancel()
+		if !ok || got.Sub(tt.want).Abs() > 100*time.Millisecond {
+			t.Errorf("--timeout %s: context deadline = %v, %v; want %v", tt.timeout, got, ok, tt.want)
+		}
+	}
+
+	ctx, cancel := Config{}.commandContext()
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("the context has a deadline without --timeout or Deadline")
+	}
+}
+
+func TestRunHookStopsAtDeadline(t *testing.T) {
+	hook := filepath.Join(t.TempDir(), "pre-migrate")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\nexec sleep 30\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if code := runHook(ctx, Config{Command: "up"}, hook); code == 0 {
+		t.Error("a hook stopped at the deadline succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("runHook returned after %s, long after the deadline", elapsed)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build aws
+
+package main
+
+import (
+	"context"
+
+	"github.com/crypto-bot/tools/migrate/metrics"
+	"github.com/crypto-bot/tools/migrate/secrets"
+)
+
+// newCloudWatch returns the CloudWatch client cloud-run reports to, in
+// AWS_REGION.
+func newCloudWatch(ctx context.Context) (cloudMetricsClient, error) {
+	awsCfg, err := secrets.LoadAWSConfig(ctx, getenv("AWS_REGION"), getenv)
+	if err != nil {
+		return nil, err
+	}
+	return metrics.NewCloudWatch(awsCfg, cloudWatchNamespace)
+}
@@ -0,0 +1,14 @@
+//go:build !aws
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// newCloudWatch fails in builds without the aws tag, which leave out the
+// AWS SDK that CloudWatch requests are signed with.
+func newCloudWatch(context.Context) (cloudMetricsClient, error) {
+	return nil, errors.New("CloudWatch metrics need a binary built with -tags aws")
+}
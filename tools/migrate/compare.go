@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/crypto-bot/tools/migrate/compare"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+)
+
+// runCompare prints how the schema of --target differs from that of
+// --source and fails if it does, so that drift can gate CI.
+func runCompare(cfg Config) int {
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	var schemas [2]compare.Schema
+	for i, databaseURL := range []string{cfg.CompareSource, cfg.CompareTarget} {
+		conn, err := pg.Open(ctx, databaseURL)
+		if err != nil {
+			printer.Error("Error: connect to %s: %v", databaseHost(databaseURL), err)
+			return 1
+		}
+		schemas[i], err = compare.NewPostgresSchemaFetcher(conn).Fetch(ctx)
+		conn.Close()
+		if err != nil {
+			printer.Error("Error: read schema of %s: %v", databaseHost(databaseURL), err)
+			return 1
+		}
+	}
+
+	diff := compare.Compare(schemas[0], schemas[1])
+
+	if cfg.Format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diff); err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+	} else {
+		printDiff(diff)
+	}
+
+	if !diff.Empty() {
+		return 1
+	}
+	return 0
+}
+
+func printDiff(diff compare.Diff) {
+	if diff.Empty() {
+		printer.Success("Schemas match")
+		return
+	}
+
+	sections := []struct {
+		title string
+		items []string
+	}{
+		{"Tables only in source", diff.OnlyInSource.Tables},
+		{"Tables only in target", diff.OnlyInTarget.Tables},
+		{"Columns only in source", diff.OnlyInSource.Columns},
+		{"Columns only in target", diff.OnlyInTarget.Columns},
+		{"Columns that differ", diff.Changed.Columns},
+		{"Indexes only in source", diff.OnlyInSource.Indexes},
+		{"Indexes only in target", diff.OnlyInTarget.Indexes},
+		{"Indexes that differ", diff.Changed.Indexes},
+	}
+	for _, section := range sections {
+		if len(section.items) == 0 {
+			continue
+		}
+		fmt.Printf("%s:\n", section.title)
+		for _, item := range section.items {
+			fmt.Printf("  %s\n", item)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package compare
+
+import (
+	"context"
+	"database/sql"
+)
+
+// PostgresSchemaFetcher reads a schema from information_schema and
+// pg_indexes.
+type PostgresSchemaFetcher struct {
+	db *sql.DB
+}
+
+// NewPostgresSchemaFetcher returns a SchemaFetcher for db.
+func NewPostgresSchemaFetcher(db *sql.DB) *PostgresSchemaFetcher {
+	return &PostgresSchemaFetcher{db: db}
+}
+
+func (f *PostgresSchemaFetcher) Fetch(ctx context.Context) (Schema, error) {
+	schema := Schema{
+		Tables:  make(map[string]bool),
+		Columns: make(map[string]Column),
+		Indexes: make(map[string]string),
+	}
+
+	err := f.query(ctx, `SELECT table_schema || '.' || table_name
+		FROM information_schema.tables
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema')`,
+		func(rows *sql.Rows) error {
+			var table string
+			if err := rows.Scan(&table); err != nil {
+				return err
+			}
+			schema.Tables[table] = true
+			return nil
+		})
+	if err != nil {
+		return Schema{}, err
+	}
+
+	err = f.query(ctx, `SELECT table_schema || '.' || table_name || '.' || column_name, data_type, is_nullable = 'YES'
+		FROM information_schema.columns
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema')`,
+		func(rows *sql.Rows) error {
+			var key string
+			var column Column
+			if err := rows.Scan(&key, &column.Type, &column.Nullable); err != nil {
+				return err
+			}
+			schema.Columns[key] = column
+			return nil
+		})
+	if err != nil {
+		return Schema{}, err
+	}
+
+	err = f.query(ctx, `SELECT schemaname || '.' || indexname, indexdef
+		FROM pg_indexes
+		WHERE schemaname NOT IN ('pg_catalog', 'information_schema')`,
+		func(rows *sql.Rows) error {
+			var key, definition string
+			if err := rows.Scan(&key, &definition); err != nil {
+				return err
+			}
+			schema.Indexes[key] = definition
+			return nil
+		})
+	if err != nil {
+		return Schema{}, err
+	}
+
+	return schema, nil
+}
+
+func (f *PostgresSchemaFetcher) query(ctx context.Context, query string, scan func(*sql.Rows) error) error {
+	rows, err := f.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
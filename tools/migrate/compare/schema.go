@@ -0,0 +1,121 @@
+// Package compare finds the differences between two database schemas.
+package compare
+
+import (
+	"context"
+	"sort"
+)
+
+// Schema describes the objects in a database that compare looks at. Tables
+// are keyed by "schema.table", columns by "schema.table.column" and indexes
+// by "schema.index".
+type Schema struct {
+	Tables  map[string]bool
+	Columns map[string]Column
+	Indexes map[string]string
+}
+
+// Column describes a table column.
+type Column struct {
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+// SchemaFetcher reads the schema of a database.
+type SchemaFetcher interface {
+	Fetch(ctx context.Context) (Schema, error)
+}
+
+// Objects lists schema objects by kind.
+type Objects struct {
+	Tables  []string `json:"tables"`
+	Columns []string `json:"columns"`
+	Indexes []string `json:"indexes"`
+}
+
+func (o Objects) empty() bool {
+	return len(o.Tables) == 0 && len(o.Columns) == 0 && len(o.Indexes) == 0
+}
+
+// Diff is the difference between a source and a target schema.
+type Diff struct {
+	OnlyInSource Objects `json:"only_in_source"`
+	OnlyInTarget Objects `json:"only_in_target"`
+	// Changed lists the columns and indexes present in both whose type,
+	// nullability or definition differ. Tables are never listed.
+	Changed Objects `json:"changed"`
+}
+
+// Empty reports whether the schemas are the same.
+func (d Diff) Empty() bool {
+	return d.OnlyInSource.empty() && d.OnlyInTarget.empty() && d.Changed.empty()
+}
+
+// Compare returns how target differs from source. Columns of a table that
+// is missing altogether are not listed separately.
+func Compare(source, target Schema) Diff {
+	var d Diff
+
+	d.OnlyInSource.Tables = missing(source.Tables, target.Tables)
+	d.OnlyInTarget.Tables = missing(target.Tables, source.Tables)
+
+	for key, column := range source.Columns {
+		other, ok := target.Columns[key]
+		switch {
+		case !ok && target.Tables[tableOf(key)]:
+			d.OnlyInSource.Columns = append(d.OnlyInSource.Columns, key)
+		case ok && other != column:
+			d.Changed.Columns = append(d.Changed.Columns, key)
+		}
+	}
+	for key := range target.Columns {
+		if _, ok := source.Columns[key]; !ok && source.Tables[tableOf(key)] {
+			d.OnlyInTarget.Columns = append(d.OnlyInTarget.Columns, key)
+		}
+	}
+
+	for key, definition := range source.Indexes {
+		other, ok := target.Indexes[key]
+		switch {
+		case !ok:
+			d.OnlyInSource.Indexes = append(d.OnlyInSource.Indexes, key)
+		case other != definition:
+			d.Changed.Indexes = append(d.Changed.Indexes, key)
+		}
+	}
+	for key := range target.Indexes {
+		if _, ok := source.Indexes[key]; !ok {
+			d.OnlyInTarget.Indexes = append(d.OnlyInTarget.Indexes, key)
+		}
+	}
+
+	for _, list := range [][]string{
+		d.OnlyInSource.Columns, d.OnlyInTarget.Columns, d.Changed.Columns,
+		d.OnlyInSource.Indexes, d.OnlyInTarget.Indexes, d.Changed.Indexes,
+	} {
+		sort.Strings(list)
+	}
+	return d
+}
+
+// missing returns the keys of a that are not in b, sorted.
+func missing(a, b map[string]bool) []string {
+	var keys []string
+	for key := range a {
+		if !b[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// tableOf returns the "schema.table" part of a column key.
+func tableOf(column string) string {
+	for i := len(column) - 1; i >= 0; i-- {
+		if column[i] == '.' {
+			return column[:i]
+		}
+	}
+	return column
+}
@@ -0,0 +1,1021 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/audit"
+	"github.com/crypto-bot/tools/migrate/config"
+	"github.com/crypto-bot/tools/migrate/phases"
+	"github.com/crypto-bot/tools/migrate/plan"
+	"github.com/crypto-bot/tools/migrate/retry"
+	"github.com/crypto-bot/tools/migrate/tags"
+	"github.com/crypto-bot/tools/migrate/vcs"
+)
+
+// Config holds the settings for a single invocation of the tool.
+type Config struct {
+	Command string
+	// Args holds the positional arguments that follow the command.
+	Args []string
+	// ExtraArgs holds everything after a `--` separator, which is passed
+	// to the migration binary after the wrapper's own arguments.
+	ExtraArgs []string
+
+	ProjectRoot    string
+	MigrationDir   string
+	EnvFiles       []string
+	SkipValidation bool
+	NoColor        bool
+	VaultTimeout   time.Duration
+	OtelEndpoint   string
+	// RedactLogs masks the database password and other credentials in
+	// everything the tool and the commands it runs print.
+	RedactLogs bool
+	// Engine is the migration tool that runs migrations, from
+	// MIGRATE_ENGINE or the configuration file's engine.
+	Engine string
+
+	// CargoBin overrides the Cargo binary found by findCargo.
+	CargoBin string
+
+	// ConfigFile is the --config path. File holds what was read from it, or
+	// from the first of config.DefaultPaths that exists; flags take
+	// precedence over it, and it over environment variables.
+	ConfigFile string
+	File       config.Config
+
+	// Env is the --env environment of the configuration file whose
+	// settings replace the top-level ones. ListEnvironments is set by a
+	// bare --env, which lists them instead of running a command.
+	Env              string
+	ListEnvironments bool
+
+	// Confirm is --confirm, which --env production requires and which
+	// repair and squash take as --yes.
+	Confirm bool
+
+	// DatabaseURL is resolved from the environment after .env files and
+	// secrets have been loaded, or taken from a shard definition.
+	DatabaseURL string
+
+	ShardsFile      string
+	Parallel        bool
+	Parallelism     int
+	ContinueOnError bool
+
+	DryRun      bool
+	Yes         bool
+	Steps       int
+	Target      string
+	Phase       phases.Phase
+	ToDate      time.Time
+	LockTimeout time.Duration
+	Format      string
+
+	// Interactive makes rollback ask about each applied migration in turn
+	// instead of rolling back to ToDate. InteractiveDiff does the same but
+	// shows each migration's down SQL and asks whether to apply it.
+	Interactive     bool
+	InteractiveDiff bool
+
+	// Max limits up to that many pending migrations, after which the
+	// number still pending is reported.
+	Max int
+
+	// Only names the single migration up applies, out of order.
+	Only string
+
+	// CloudRun runs the command as a one-shot Cloud Run or Fargate job,
+	// given as migrate cloud-run up.
+	CloudRun bool
+
+	// ApplyMissingOnly makes up apply every migration file the tracking
+	// table has no record of, wherever it falls among the recorded ones.
+	// Force skips the pause after the out-of-order warning.
+	ApplyMissingOnly bool
+	Force            bool
+
+	Timeout time.Duration
+	Retries int
+	// Deadline, when set, is when the migration is stopped however much of
+	// --timeout is left, across retries; cloud-run sets it.
+	Deadline time.Time
+
+	// Output is the file snapshot writes the schema to, export writes the
+	// migrations to, backup writes the dump to, or squash names the
+	// baseline; Input is the dump restore loads.
+	Output string
+	Input  string
+
+	// BackupBeforeMigrate makes up dump the database to BackupDir first.
+	BackupBeforeMigrate bool
+
+	// SafeMode makes up refuse to run while more than MaxConnections other
+	// sessions are busy on the database.
+	SafeMode       bool
+	MaxConnections int
+
+	// ChangelogFrom and ChangelogTo are the git revisions whose added
+	// migrations generate-changelog lists; with NoGit it lists the
+	// migrations numbered above After instead.
+	ChangelogFrom string
+	ChangelogTo   string
+	NoGit         bool
+	After         int
+
+	// PR is the pull request URL whose added migrations diff prints,
+	// compared with BaseBranch.
+	PR         string
+	BaseBranch string
+
+	// PendingCount makes status print only the number of pending
+	// migrations, read without Cargo.
+	PendingCount bool
+	// ExitCode makes status report through its exit code whether every
+	// migration is applied, some are pending or the tracking table is
+	// missing, read without Cargo.
+	ExitCode bool
+
+	// WatchDelay is how long watch waits for further changes before
+	// running up.
+	WatchDelay time.Duration
+
+	// Template is the scaffold init writes: minimal or full.
+	Template string
+
+	// MarkApplied and MarkRolledBack name the migration repair records as
+	// applied or removes the record of.
+	MarkApplied    string
+	MarkRolledBack string
+
+	// Migration is the migration version-history reports on.
+	Migration string
+
+	// At is the migration tag labels. ToTag makes rollback roll back every
+	// migration after the one tagged, and SinceTag makes status list them.
+	At       string
+	ToTag    string
+	SinceTag string
+
+	// AppliedSince makes status list only the migrations applied after it,
+	// from --since.
+	AppliedSince time.Time
+
+	// WatchStatus makes status redraw its table every Interval.
+	WatchStatus bool
+	Interval    time.Duration
+
+	// Fix makes doctor repair the problems it can.
+	Fix bool
+
+	// FromDB makes import capture the schema of the database.
+	FromDB bool
+
+	// All makes env print the whole environment Cargo runs in.
+	All bool
+
+	// PlanFrom and PlanTo are the first and last migrations plan prints.
+	PlanFrom string
+	PlanTo   string
+
+	// Threshold is how long a migration must take for diagnose-slow to
+	// report it; LogFile is a saved run log to read the times from instead
+	// of the tracking table.
+	Threshold time.Duration
+	LogFile   string
+
+	// VerifyDatabaseURL is the database verify runs its cycle on, in place
+	// of DATABASE_URL.
+	VerifyDatabaseURL string
+
+	// ListOrphaned makes clean report the migration files and tracking table
+	// records that have no counterpart; RemoveUnapplied and
+	// RemoveOrphanedRecords make it delete the files and records.
+	ListOrphaned          bool
+	RemoveUnapplied       bool
+	RemoveOrphanedRecords bool
+
+	// Reference is the .env file config diff compares the configuration
+	// with.
+	Reference string
+
+	// EncryptKey is the base64 AES-256 key config encrypt and config decrypt
+	// use; MIGRATE_ENCRYPT_KEY is read when it is empty.
+	EncryptKey string
+
+	// Since limits audit-log export to entries logged within it.
+	Since time.Duration
+
+	// SeedDir is the directory seed loads fixtures from.
+	SeedDir string
+
+	// BenchCount is how many of the last applied migrations benchmark
+	// times; BenchSchema, if set, is the schema it isolates them in instead
+	// of a rolled-back transaction.
+	BenchCount  int
+	BenchSchema string
+
+	// Port and Token are where serve listens and the bearer token its
+	// requests must carry.
+	Port  int
+	Token string
+
+	// CompareSource and CompareTarget are the database URLs compare diffs.
+	CompareSource string
+	CompareTarget string
+
+	// Retry is the number of times a failed migration is re-run when it
+	// exits with one of RetryOn.
+	Retry        int
+	RetryDelay   time.Duration
+	RetryBackoff string
+	RetryOn      []int
+}
+
+// SQLDir is the directory holding the .sql migration files.
+func (c Config) SQLDir() string {
+	return filepath.Join(c.MigrationDir, "migrations")
+}
+
+// BackupDir is the directory backup and --backup-before-migrate write
+// timestamped dumps to.
+func (c Config) BackupDir() string {
+	return filepath.Join(c.ProjectRoot, "backups")
+}
+
+// commandContext returns the context bounding the migration run, which
+// expires after --timeout when one is set, and at Deadline at the latest.
+func (c Config) commandContext() (context.Context, context.CancelFunc) {
+	deadline := c.Deadline
+	if c.Timeout > 0 && (deadline.IsZero() || time.Now().Add(c.Timeout).Before(deadline)) {
+		deadline = time.Now().Add(c.Timeout)
+	}
+	if deadline.IsZero() {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithDeadline(context.Background(), deadline)
+}
+
+var commands = map[string]bool{
+	"up":                 true,
+	"down":               true,
+	"status":             true,
+	"fresh":              true,
+	"rollback":           true,
+	"init":               true,
+	"create":             true,
+	"export":             true,
+	"test":               true,
+	"serve":              true,
+	"seed":               true,
+	"benchmark":          true,
+	"graph":              true,
+	"diff":               true,
+	"config":             true,
+	"env":                true,
+	"audit-log":          true,
+	"doctor":             true,
+	"health":             true,
+	"lint":               true,
+	"generate-changelog": true,
+	"backup":             true,
+	"restore":            true,
+	"repair":             true,
+	"squash":             true,
+	"check":              true,
+	"compare":            true,
+	"list":               true,
+	"plan":               true,
+	"diagnose-slow":      true,
+	"clean":              true,
+	"tidy":               true,
+	"verify":             true,
+	"watch":              true,
+	"ping":               true,
+	"import":             true,
+	"tag":                true,
+	"version-history":    true,
+	"snapshot":           true,
+	"version":            true,
+}
+
+// registerCommonFlags registers the flags accepted both before and after
+// any command.
+func registerCommonFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&cfg.MigrationDir, "migration-dir", cfg.MigrationDir, "")
+	fs.BoolVar(&cfg.SkipValidation, "skip-validation", cfg.SkipValidation, "")
+	fs.BoolVar(&cfg.NoColor, "no-color", cfg.NoColor, "")
+	fs.BoolVar(&cfg.RedactLogs, "redact-logs", cfg.RedactLogs, "")
+	fs.Func("env-file", "", func(value string) error {
+		cfg.EnvFiles = append(cfg.EnvFiles, value)
+		return nil
+	})
+	fs.StringVar(&cfg.OtelEndpoint, "otel-endpoint", cfg.OtelEndpoint, "")
+	fs.StringVar(&cfg.CargoBin, "cargo-bin", cfg.CargoBin, "")
+	fs.StringVar(&cfg.ConfigFile, "config", cfg.ConfigFile, "")
+	fs.StringVar(&cfg.Env, "env", cfg.Env, "")
+	fs.BoolVar(&cfg.Confirm, "confirm", cfg.Confirm, "")
+	fs.DurationVar(&cfg.VaultTimeout, "vault-timeout", cfg.VaultTimeout, "")
+	fs.StringVar(&cfg.ShardsFile, "shards", cfg.ShardsFile, "")
+	fs.BoolVar(&cfg.Parallel, "parallel", cfg.Parallel, "")
+	fs.BoolVar(&cfg.ContinueOnError, "continue-on-error", cfg.ContinueOnError, "")
+	fs.Func("parallelism", "", func(value string) error {
+		n, err := positiveInt(value)
+		cfg.Parallelism = n
+		return err
+	})
+}
+
+// splitExtraArgs splits args at the `--` separator into the arguments the
+// wrapper parses and those forwarded to the migration binary. extra is nil
+// when there is no separator.
+func splitExtraArgs(args []string) (own, extra []string, err error) {
+	i := slices.Index(args, "--")
+	if i < 0 {
+		return args, nil, nil
+	}
+	own, extra = args[:i], append([]string{}, args[i+1:]...)
+	if slices.Contains(extra, "--") {
+		return nil, nil, errors.New("-- can only be given once")
+	}
+	return own, extra, nil
+}
+
+// loadFileConfig reads the --config file, or the first of
+// config.DefaultPaths there is, into cfg.File, with the settings of the
+// --env environment in place of the top-level ones.
+func loadFileConfig(cfg *Config) error {
+	paths := config.DefaultPaths
+	if cfg.ConfigFile != "" {
+		if _, err := os.Stat(cfg.ConfigFile); err != nil {
+			return fmt.Errorf("--config: %w", err)
+		}
+		paths = []string{cfg.ConfigFile}
+	}
+	file, err := config.LoadConfig(paths)
+	if err != nil {
+		return err
+	}
+	cfg.File = file
+	if cfg.Env == "" {
+		return nil
+	}
+
+	env, ok := file.Environments[cfg.Env]
+	switch {
+	case ok && env.DatabaseURL == "" && env.DatabaseURLSecret == "":
+		return fmt.Errorf("--env %s: the environment has no database_url", cfg.Env)
+	case ok:
+		cfg.File.DatabaseURL = env.DatabaseURL
+		cfg.File.DatabaseURLSecret = env.DatabaseURLSecret
+		return nil
+	case len(file.Environments) == 0:
+		return fmt.Errorf("--env %s: no configuration file defines environments", cfg.Env)
+	default:
+		return fmt.Errorf("--env %s: unknown environment (expected %s)", cfg.Env, strings.Join(file.ListEnvironments(), ", "))
+	}
+}
+
+// parseConfig parses the command line (without the program name) and rejects
+// flag combinations that make no sense for the chosen command.
+func parseConfig(args []string) (Config, error) {
+	cfg := Config{
+		ProjectRoot:  filepath.Join("..", ".."),
+		Format:       "text",
+		Parallelism:  1,
+		VaultTimeout: 5 * time.Second,
+		RetryDelay:   5 * time.Second,
+		RetryBackoff: "fixed",
+		RetryOn:      []int{exitCargoPanic},
+	}
+
+	args, extra, err := splitExtraArgs(args)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.ExtraArgs = extra
+
+	// Flags shared by every command may also precede it, as in
+	// `migrate --shards shards.yaml up`.
+	global := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	global.SetOutput(io.Discard)
+	registerCommonFlags(global, &cfg)
+	if err := global.Parse(args); err != nil {
+		// A bare --env, as in `migrate --env`, lists the environments.
+		if n := len(args); n == 0 || (args[n-1] != "--env" && args[n-1] != "-env") {
+			return cfg, err
+		}
+		if global.Parse(args[:len(args)-1]) != nil || global.NArg() > 0 || cfg.ExtraArgs != nil {
+			return cfg, err
+		}
+		cfg.ListEnvironments = true
+		return cfg, loadFileConfig(&cfg)
+	}
+
+	if global.NArg() > 0 && global.Arg(0) == "cloud-run" {
+		cfg.CloudRun = true
+		if err := global.Parse(global.Args()[1:]); err != nil {
+			return cfg, err
+		}
+		if global.NArg() == 0 {
+			return cfg, errors.New("cloud-run requires the command to run, e.g. migrate cloud-run up")
+		}
+	}
+	if global.NArg() == 0 && cfg.ExtraArgs != nil {
+		return cfg, errors.New("-- must follow the command")
+	}
+	if global.NArg() == 0 {
+		return cfg, errors.New("no command given")
+	}
+	cfg.Command = global.Arg(0)
+	if !commands[cfg.Command] {
+		return cfg, fmt.Errorf("unknown command: %s", cfg.Command)
+	}
+
+	fs := flag.NewFlagSet(cfg.Command, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	registerCommonFlags(fs, &cfg)
+
+	switch cfg.Command {
+	case "up", "down", "status", "fresh":
+		fs.BoolVar(&cfg.DryRun, "dry-run", false, "")
+		fs.BoolVar(&cfg.Yes, "yes", false, "")
+		fs.BoolVar(&cfg.Yes, "y", false, "")
+		fs.DurationVar(&cfg.LockTimeout, "lock-timeout", 60*time.Second, "")
+		fs.StringVar(&cfg.Format, "format", "text", "")
+		fs.Func("steps", "", func(value string) error {
+			n, err := positiveInt(value)
+			cfg.Steps = n
+			return err
+		})
+		fs.Func("max", "", func(value string) error {
+			n, err := positiveInt(value)
+			cfg.Max = n
+			return err
+		})
+		fs.StringVar(&cfg.Target, "target", "", "")
+		fs.StringVar(&cfg.Only, "only", "", "")
+		fs.BoolVar(&cfg.ApplyMissingOnly, "apply-missing-only", false, "")
+		fs.BoolVar(&cfg.Force, "force", false, "")
+		fs.BoolVar(&cfg.BackupBeforeMigrate, "backup-before-migrate", false, "")
+		fs.BoolVar(&cfg.SafeMode, "safe-mode", false, "")
+		fs.IntVar(&cfg.MaxConnections, "max-connections", 0, "")
+		fs.BoolVar(&cfg.PendingCount, "pending-count", false, "")
+		fs.StringVar(&cfg.SinceTag, "since-tag", "", "")
+		fs.BoolVar(&cfg.WatchStatus, "watch", false, "")
+		fs.Func("interval", "", func(value string) error {
+			d, err := time.ParseDuration(value)
+			if err == nil && d <= 0 {
+				err = errors.New("must be positive")
+			}
+			cfg.Interval = d
+			return err
+		})
+		fs.Func("since", "", func(value string) error {
+			t, err := parseSince(value, time.Now())
+			cfg.AppliedSince = t
+			return err
+		})
+		fs.BoolVar(&cfg.ExitCode, "exit-code", false, "")
+		fs.Func("phase", "", func(value string) error {
+			phase, err := phases.ParsePhase(value)
+			cfg.Phase = phase
+			return err
+		})
+		fs.Var((*durationValue)(&cfg.Timeout), "timeout", "")
+		fs.Func("retry", "", func(value string) error {
+			n, err := positiveInt(value)
+			cfg.Retry = n
+			return err
+		})
+		fs.Var((*durationValue)(&cfg.RetryDelay), "retry-delay", "")
+		fs.Func("retry-backoff", "", func(value string) error {
+			_, err := retry.ParseBackoff(value, 0, 0)
+			cfg.RetryBackoff = value
+			return err
+		})
+		fs.Func("retry-on", "", func(value string) error {
+			codes, err := exitCodeList(value)
+			cfg.RetryOn = codes
+			return err
+		})
+	case "rollback":
+		fs.Func("to-date", "", func(value string) error {
+			t, err := parseRollbackDate(value)
+			cfg.ToDate = t
+			return err
+		})
+		fs.BoolVar(&cfg.Interactive, "interactive", false, "")
+		fs.BoolVar(&cfg.InteractiveDiff, "interactive-diff", false, "")
+		fs.StringVar(&cfg.ToTag, "to-tag", "", "")
+		fs.BoolVar(&cfg.Yes, "yes", false, "")
+		fs.BoolVar(&cfg.Yes, "y", false, "")
+		fs.DurationVar(&cfg.LockTimeout, "lock-timeout", 60*time.Second, "")
+		fs.Var((*durationValue)(&cfg.Timeout), "timeout", "")
+	case "compare":
+		fs.StringVar(&cfg.CompareSource, "source", "", "")
+		fs.StringVar(&cfg.CompareTarget, "target", "", "")
+		fs.StringVar(&cfg.Format, "format", "text", "")
+	case "repair":
+		fs.StringVar(&cfg.MarkApplied, "mark-applied", "", "")
+		fs.StringVar(&cfg.MarkRolledBack, "mark-rolled-back", "", "")
+		fs.BoolVar(&cfg.Yes, "yes", false, "")
+		fs.BoolVar(&cfg.Yes, "y", false, "")
+	case "squash":
+		fs.StringVar(&cfg.Output, "output", "000001_baseline.up.sql", "")
+		fs.BoolVar(&cfg.Yes, "yes", false, "")
+		fs.BoolVar(&cfg.Yes, "y", false, "")
+		fs.DurationVar(&cfg.LockTimeout, "lock-timeout", 60*time.Second, "")
+	case "import":
+		fs.BoolVar(&cfg.FromDB, "from-db", false, "")
+		fs.BoolVar(&cfg.DryRun, "dry-run", false, "")
+		fs.DurationVar(&cfg.LockTimeout, "lock-timeout", 60*time.Second, "")
+	case "doctor":
+		fs.BoolVar(&cfg.Fix, "fix", false, "")
+	case "health":
+		fs.StringVar(&cfg.Format, "format", "text", "")
+	case "config":
+		fs.StringVar(&cfg.Reference, "reference", "", "")
+		fs.StringVar(&cfg.EncryptKey, "key", "", "")
+	case "env":
+		fs.BoolVar(&cfg.All, "all", false, "")
+	case "verify":
+		fs.StringVar(&cfg.VerifyDatabaseURL, "database-url", "", "")
+		fs.DurationVar(&cfg.LockTimeout, "lock-timeout", 60*time.Second, "")
+	case "tidy":
+		fs.BoolVar(&cfg.DryRun, "dry-run", false, "")
+		fs.DurationVar(&cfg.LockTimeout, "lock-timeout", 60*time.Second, "")
+	case "clean":
+		fs.BoolVar(&cfg.ListOrphaned, "list-orphaned", false, "")
+		fs.BoolVar(&cfg.RemoveUnapplied, "remove-unapplied", false, "")
+		fs.BoolVar(&cfg.RemoveOrphanedRecords, "remove-orphaned-records", false, "")
+		fs.BoolVar(&cfg.Yes, "yes", false, "")
+		fs.BoolVar(&cfg.Yes, "y", false, "")
+	case "diagnose-slow":
+		fs.DurationVar(&cfg.Threshold, "threshold", 5*time.Second, "")
+		fs.StringVar(&cfg.LogFile, "log-file", "", "")
+	case "plan":
+		fs.StringVar(&cfg.PlanFrom, "from", "", "")
+		fs.StringVar(&cfg.PlanTo, "to", "", "")
+		fs.StringVar(&cfg.Format, "format", "sql", "")
+	case "audit-log":
+		fs.StringVar(&cfg.Format, "format", "table", "")
+		fs.Var((*durationValue)(&cfg.Since), "since", "")
+	case "seed":
+		fs.StringVar(&cfg.SeedDir, "seed-dir", "", "")
+	case "benchmark":
+		cfg.BenchCount = 10
+		fs.Func("count", "", func(value string) error {
+			n, err := positiveInt(value)
+			cfg.BenchCount = n
+			return err
+		})
+		fs.StringVar(&cfg.BenchSchema, "pg-schema", "", "")
+		fs.Var((*durationValue)(&cfg.Timeout), "timeout", "")
+	case "serve":
+		cfg.Port = 8080
+		fs.Func("port", "", func(value string) error {
+			n, err := positiveInt(value)
+			if err == nil && n > 65535 {
+				err = errors.New("must be a port number")
+			}
+			cfg.Port = n
+			return err
+		})
+		fs.StringVar(&cfg.Token, "token", "", "")
+		fs.DurationVar(&cfg.LockTimeout, "lock-timeout", 60*time.Second, "")
+		fs.Var((*durationValue)(&cfg.Timeout), "timeout", "")
+	case "watch":
+		cfg.WatchDelay = 500 * time.Millisecond
+		fs.Var((*durationValue)(&cfg.WatchDelay), "watch-delay", "")
+		fs.DurationVar(&cfg.LockTimeout, "lock-timeout", 60*time.Second, "")
+	case "init":
+		fs.StringVar(&cfg.Template, "template", "minimal", "")
+	case "export", "snapshot", "backup":
+		fs.StringVar(&cfg.Output, "output", "", "")
+	case "generate-changelog":
+		fs.StringVar(&cfg.ChangelogFrom, "from", "", "")
+		fs.StringVar(&cfg.ChangelogTo, "to", "HEAD", "")
+		fs.BoolVar(&cfg.NoGit, "no-git", false, "")
+		fs.Func("after", "", func(value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return errors.New("must be a non-negative sequence number")
+			}
+			cfg.After = n
+			return nil
+		})
+		fs.StringVar(&cfg.Output, "output", "", "")
+	case "diff":
+		fs.StringVar(&cfg.PR, "pr", "", "")
+		fs.StringVar(&cfg.BaseBranch, "base-branch", "main", "")
+		fs.StringVar(&cfg.Output, "output", "", "")
+	case "restore":
+		fs.StringVar(&cfg.Input, "input", "", "")
+		fs.BoolVar(&cfg.Yes, "yes", false, "")
+		fs.BoolVar(&cfg.Yes, "y", false, "")
+	case "tag":
+		fs.StringVar(&cfg.At, "at", "", "")
+	case "version-history":
+		fs.StringVar(&cfg.Migration, "migration", "", "")
+	case "ping":
+		cfg.Timeout = 5 * time.Second
+		cfg.RetryDelay = pingRetryDelay
+		cfg.RetryBackoff = "jitter"
+		fs.Var((*durationValue)(&cfg.Timeout), "timeout", "")
+		fs.Var((*durationValue)(&cfg.RetryDelay), "retry-delay", "")
+		fs.Func("retry-strategy", "", func(value string) error {
+			_, err := retry.ParseBackoff(value, 0, 0)
+			cfg.RetryBackoff = value
+			return err
+		})
+		fs.Func("retries", "", func(value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return errors.New("must be a non-negative integer")
+			}
+			cfg.Retries = n
+			return nil
+		})
+	}
+
+	// Positional arguments may be interleaved with flags, so keep parsing
+	// after each one.
+	rest := global.Args()[1:]
+	for {
+		if err := fs.Parse(rest); err != nil {
+			return cfg, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		cfg.Args = append(cfg.Args, fs.Arg(0))
+		rest = fs.Args()[1:]
+	}
+
+	if err := loadFileConfig(&cfg); err != nil {
+		return cfg, err
+	}
+	if cfg.MigrationDir == "" {
+		cfg.MigrationDir = cfg.File.MigrationDir
+	}
+	if cfg.CargoBin == "" {
+		cfg.CargoBin = cfg.File.CargoBin
+	}
+
+	if cfg.MigrationDir == "" {
+		cfg.MigrationDir = filepath.Join(cfg.ProjectRoot, "migration")
+	}
+	if cfg.Command == "seed" && cfg.SeedDir == "" {
+		cfg.SeedDir = filepath.Join(cfg.ProjectRoot, "seeds")
+	}
+	if cfg.Command == "snapshot" && cfg.Output == "" {
+		cfg.Output = filepath.Join(cfg.ProjectRoot, "schema.sql")
+	}
+
+	if cfg.ExtraArgs != nil {
+		switch cfg.Command {
+		case "up", "down", "status", "fresh", "rollback", "watch", "test":
+		default:
+			return cfg, fmt.Errorf("arguments after -- can only be used with commands that run the migration binary, not %s", cfg.Command)
+		}
+	}
+	if cfg.Command == "config" && (len(cfg.Args) != 1 || !slices.Contains([]string{"validate", "diff", "encrypt", "decrypt"}, cfg.Args[0])) {
+		return cfg, errors.New("usage: migrate config validate, migrate config diff [--reference P], or migrate config encrypt|decrypt [--key K]")
+	}
+	if cfg.Command == "config" && cfg.EncryptKey != "" && cfg.Args[0] != "encrypt" && cfg.Args[0] != "decrypt" {
+		return cfg, errors.New("--key can only be used with config encrypt and config decrypt")
+	}
+	if cfg.Command == "config" && cfg.Args[0] == "diff" && cfg.Reference == "" {
+		cfg.Reference = filepath.Join(cfg.ProjectRoot, ".env.example")
+	}
+	if cfg.Command == "audit-log" {
+		if len(cfg.Args) != 1 || cfg.Args[0] != "export" {
+			return cfg, errors.New("usage: migrate audit-log export [--format table|csv] [--since D]")
+		}
+		if !slices.Contains(audit.Formats, cfg.Format) {
+			return cfg, fmt.Errorf("unknown format %q (expected %s)", cfg.Format, strings.Join(audit.Formats, " or "))
+		}
+	}
+	if cfg.Command == "tag" {
+		if len(cfg.Args) != 1 || cfg.At == "" {
+			return cfg, errors.New("usage: migrate tag <tag> --at <migration>, e.g. migrate tag v2.5.0 --at 000120_create_wallets")
+		}
+		if err := tags.Validate(cfg.Args[0]); err != nil {
+			return cfg, err
+		}
+	}
+	if len(cfg.Args) > 0 {
+		switch cfg.Command {
+		case "create", "config", "audit-log", "tag":
+		default:
+			return cfg, fmt.Errorf("unexpected argument: %s", cfg.Args[0])
+		}
+	}
+	if cfg.DryRun && cfg.Command != "up" && cfg.Command != "down" && cfg.Command != "import" && cfg.Command != "tidy" {
+		return cfg, fmt.Errorf("--dry-run can only be used with up, down, import or tidy, not %s", cfg.Command)
+	}
+	if cfg.Command == "clean" && !cfg.ListOrphaned && !cfg.RemoveUnapplied && !cfg.RemoveOrphanedRecords {
+		return cfg, errors.New("clean requires --list-orphaned, --remove-unapplied or --remove-orphaned-records")
+	}
+	if cfg.Command == "diagnose-slow" && cfg.Threshold < 0 {
+		return cfg, errors.New("--threshold cannot be negative")
+	}
+	if cfg.Command == "import" && !cfg.FromDB {
+		return cfg, errors.New("import requires --from-db, the only source it can import from")
+	}
+	if cfg.ShardsFile != "" {
+		switch cfg.Command {
+		case "up", "down", "status", "fresh":
+		default:
+			return cfg, fmt.Errorf("--shards can only be used with up, down, status or fresh, not %s", cfg.Command)
+		}
+		if cfg.Format != "text" {
+			return cfg, errors.New("--format cannot be combined with --shards")
+		}
+		if cfg.Command == "fresh" && !cfg.Yes {
+			return cfg, errors.New("fresh with --shards requires --yes")
+		}
+	}
+	if cfg.Parallelism > 1 && cfg.ShardsFile != "" && !cfg.Parallel {
+		return cfg, errors.New("--parallelism requires --parallel with --shards")
+	}
+	if cfg.Parallelism > 1 && cfg.ShardsFile == "" {
+		switch {
+		case cfg.Parallel:
+			return cfg, errors.New("--parallel can only be used with --shards")
+		case cfg.Command != "up":
+			return cfg, fmt.Errorf("--parallelism can only be used with up or --shards, not %s", cfg.Command)
+		case cfg.DryRun || cfg.Target != "" || cfg.Format != "text":
+			return cfg, errors.New("--parallelism cannot be combined with --dry-run, --target or --format")
+		}
+	}
+	if cfg.Yes {
+		switch cfg.Command {
+		case "fresh", "rollback", "repair", "restore", "squash", "clean":
+		case "up":
+			if cfg.Only == "" {
+				return cfg, errors.New("--yes can only be used with up together with --only")
+			}
+		default:
+			return cfg, fmt.Errorf("--yes can only be used with fresh, rollback, repair, restore, squash, clean or up --only, not %s", cfg.Command)
+		}
+	}
+	if cfg.Command == "squash" && (filepath.Base(cfg.Output) != cfg.Output || !strings.HasSuffix(cfg.Output, ".up.sql")) {
+		return cfg, errors.New("squash --output must be a file name ending in .up.sql; the baseline is written to the migration directory")
+	}
+	if cfg.Command == "restore" && cfg.Input == "" {
+		return cfg, errors.New("restore requires --input")
+	}
+	if cfg.Command == "generate-changelog" {
+		switch {
+		case cfg.NoGit && cfg.ChangelogFrom != "":
+			return cfg, errors.New("--from cannot be combined with --no-git; use --after")
+		case !cfg.NoGit && cfg.ChangelogFrom == "":
+			return cfg, errors.New("generate-changelog requires --from, or --no-git")
+		case !cfg.NoGit && cfg.After > 0:
+			return cfg, errors.New("--after can only be used with --no-git")
+		}
+	}
+	if cfg.Command == "diff" {
+		if cfg.PR == "" {
+			return cfg, errors.New("usage: migrate diff --pr <url>, e.g. migrate diff --pr https://github.com/org/repo/pull/123")
+		}
+		if _, err := vcs.ParsePullRequestURL(cfg.PR); err != nil {
+			return cfg, fmt.Errorf("--pr: %w", err)
+		}
+		if cfg.BaseBranch == "" {
+			return cfg, errors.New("--base-branch must not be empty")
+		}
+	}
+	if cfg.PendingCount {
+		switch {
+		case cfg.Command != "status":
+			return cfg, fmt.Errorf("--pending-count can only be used with status, not %s", cfg.Command)
+		case cfg.Format != "text" || cfg.DryRun || cfg.ShardsFile != "" || cfg.ExtraArgs != nil:
+			return cfg, errors.New("--pending-count cannot be combined with --format, --dry-run, --shards or arguments after --")
+		}
+	}
+	if cfg.ExitCode {
+		switch {
+		case cfg.Command != "status":
+			return cfg, fmt.Errorf("--exit-code can only be used with status, not %s", cfg.Command)
+		case cfg.Format != "text" || cfg.PendingCount || cfg.SinceTag != "" || cfg.ShardsFile != "" || cfg.ExtraArgs != nil:
+			return cfg, errors.New("--exit-code cannot be combined with --format, --pending-count, --since-tag, --shards or arguments after --")
+		}
+	}
+	if cfg.SinceTag != "" {
+		switch {
+		case cfg.Command != "status":
+			return cfg, fmt.Errorf("--since-tag can only be used with status, not %s", cfg.Command)
+		case cfg.Format != "text" || cfg.PendingCount || cfg.ShardsFile != "" || cfg.ExtraArgs != nil:
+			return cfg, errors.New("--since-tag cannot be combined with --format, --pending-count, --shards or arguments after --")
+		}
+	}
+	if !cfg.AppliedSince.IsZero() {
+		switch {
+		case cfg.Command != "status":
+			return cfg, fmt.Errorf("--since can only be used with status, not %s", cfg.Command)
+		case cfg.Format != "text" || cfg.PendingCount || cfg.ExitCode || cfg.SinceTag != "" || cfg.ShardsFile != "" || cfg.ExtraArgs != nil:
+			return cfg, errors.New("--since cannot be combined with --format, --pending-count, --exit-code, --since-tag, --shards or arguments after --")
+		}
+	}
+	if cfg.WatchStatus {
+		switch {
+		case cfg.Command != "status":
+			return cfg, fmt.Errorf("--watch can only be used with status, not %s", cfg.Command)
+		case cfg.Format != "text" || cfg.PendingCount || cfg.ExitCode || cfg.SinceTag != "" || !cfg.AppliedSince.IsZero() ||
+			cfg.ShardsFile != "" || cfg.ExtraArgs != nil:
+			return cfg, errors.New("--watch cannot be combined with --format, --pending-count, --exit-code, --since, --since-tag, --shards or arguments after --")
+		}
+	}
+	if cfg.Interval != 0 && !cfg.WatchStatus {
+		return cfg, errors.New("--interval can only be used with status --watch")
+	}
+	if cfg.WatchStatus && cfg.Interval == 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.BackupBeforeMigrate && cfg.Command != "up" {
+		return cfg, fmt.Errorf("--backup-before-migrate can only be used with up, not %s", cfg.Command)
+	}
+	if cfg.SafeMode && cfg.Command != "up" {
+		return cfg, fmt.Errorf("--safe-mode can only be used with up, not %s", cfg.Command)
+	}
+	if cfg.MaxConnections != 0 && !cfg.SafeMode {
+		return cfg, errors.New("--max-connections can only be used with --safe-mode")
+	}
+	if cfg.MaxConnections < 0 {
+		return cfg, errors.New("--max-connections must not be negative")
+	}
+	if cfg.Command == "repair" && (cfg.MarkApplied == "") == (cfg.MarkRolledBack == "") {
+		return cfg, errors.New("repair requires exactly one of --mark-applied or --mark-rolled-back")
+	}
+	if cfg.Command == "version-history" && cfg.Migration == "" {
+		return cfg, errors.New("version-history requires --migration, e.g. --migration 000042_add_orders")
+	}
+	if cfg.Command == "rollback" {
+		given := 0
+		for _, set := range []bool{!cfg.ToDate.IsZero(), cfg.ToTag != "", cfg.Interactive, cfg.InteractiveDiff} {
+			if set {
+				given++
+			}
+		}
+		if given != 1 {
+			return cfg, errors.New("rollback requires exactly one of --to-date, --to-tag, --interactive or --interactive-diff")
+		}
+	}
+	if cfg.Interactive && cfg.Yes {
+		return cfg, errors.New("--interactive asks about every migration and cannot be combined with --yes")
+	}
+	if cfg.InteractiveDiff && cfg.Yes {
+		return cfg, errors.New("--interactive-diff asks about every migration and cannot be combined with --yes")
+	}
+	if cfg.Steps > 0 && cfg.Command != "down" {
+		return cfg, fmt.Errorf("--steps can only be used with down, not %s", cfg.Command)
+	}
+	if cfg.Only != "" {
+		switch {
+		case cfg.Command != "up":
+			return cfg, fmt.Errorf("--only can only be used with up, not %s", cfg.Command)
+		case cfg.Target != "" || cfg.Steps > 0 || cfg.Max > 0 || cfg.Phase != "" || cfg.DryRun ||
+			cfg.ShardsFile != "" || cfg.Parallelism > 1:
+			return cfg, errors.New("--only cannot be combined with --target, --steps, --max, --phase, --dry-run, --shards or --parallelism")
+		}
+	}
+	if cfg.CloudRun && cfg.Command != "up" && cfg.Command != "down" {
+		return cfg, fmt.Errorf("cloud-run can only run up or down, not %s", cfg.Command)
+	}
+	if cfg.ApplyMissingOnly {
+		switch {
+		case cfg.Command != "up":
+			return cfg, fmt.Errorf("--apply-missing-only can only be used with up, not %s", cfg.Command)
+		case cfg.Only != "" || cfg.Target != "" || cfg.Max > 0 || cfg.Phase != "" || cfg.DryRun ||
+			cfg.ShardsFile != "" || cfg.Parallelism > 1:
+			return cfg, errors.New("--apply-missing-only cannot be combined with --only, --target, --max, --phase, --dry-run, --shards or --parallelism")
+		}
+	}
+	if cfg.LockTimeout < 0 {
+		return cfg, errors.New("--lock-timeout must not be negative; 0 waits for the lock indefinitely")
+	}
+	if cfg.Force && !cfg.ApplyMissingOnly {
+		return cfg, errors.New("--force can only be used with up --apply-missing-only")
+	}
+	if cfg.Command == "repair" || cfg.Command == "squash" {
+		cfg.Yes = cfg.Yes || cfg.Confirm
+	} else if cfg.Confirm && cfg.Env == "" {
+		return cfg, errors.New("--confirm can only be used with --env, repair or squash")
+	}
+	if cfg.Env == "production" && !cfg.Confirm {
+		return cfg, errors.New("--env production changes the production database and requires --confirm")
+	}
+	if cfg.Max > 0 {
+		switch {
+		case cfg.Command != "up":
+			return cfg, fmt.Errorf("--max can only be used with up, not %s", cfg.Command)
+		case cfg.Target != "" || cfg.Phase != "" || cfg.ShardsFile != "" || cfg.Parallelism > 1:
+			return cfg, errors.New("--max cannot be combined with --target, --phase, --shards or --parallelism")
+		}
+	}
+	if cfg.Target != "" {
+		if cfg.Command != "up" && cfg.Command != "down" {
+			return cfg, fmt.Errorf("--target can only be used with up or down, not %s", cfg.Command)
+		}
+		if cfg.Steps > 0 {
+			return cfg, errors.New("--target cannot be combined with --steps")
+		}
+	}
+	if cfg.Phase != "" {
+		switch {
+		case cfg.Command != "up":
+			return cfg, fmt.Errorf("--phase can only be used with up, not %s", cfg.Command)
+		case cfg.Target != "" || cfg.ShardsFile != "" || cfg.Parallelism > 1:
+			return cfg, errors.New("--phase cannot be combined with --target, --shards or --parallelism")
+		}
+	}
+	if cfg.Command == "init" && !slices.Contains(initTemplates, cfg.Template) {
+		return cfg, fmt.Errorf("unknown template %q (expected %s)", cfg.Template, strings.Join(initTemplates, " or "))
+	}
+	if cfg.Command == "serve" && cfg.Token == "" {
+		return cfg, errors.New("serve requires --token")
+	}
+	if cfg.Command == "compare" {
+		if cfg.CompareSource == "" || cfg.CompareTarget == "" {
+			return cfg, errors.New("compare requires --source and --target")
+		}
+		if cfg.Format != "text" && cfg.Format != "json" {
+			return cfg, fmt.Errorf("unknown format %q (expected text or json)", cfg.Format)
+		}
+	} else if cfg.Command == "plan" {
+		if !slices.Contains(plan.Formats, cfg.Format) {
+			return cfg, fmt.Errorf("unknown format %q (expected %s)", cfg.Format, strings.Join(plan.Formats, " or "))
+		}
+	} else if cfg.Command == "health" {
+		if cfg.Format != "text" && cfg.Format != "json" {
+			return cfg, fmt.Errorf("unknown format %q (expected text or json)", cfg.Format)
+		}
+	} else if cfg.Format != "text" && cfg.Command != "audit-log" {
+		if cfg.Command != "status" {
+			return cfg, fmt.Errorf("--format can only be used with status or compare, not %s", cfg.Command)
+		}
+		if _, ok := statusFormatters[cfg.Format]; !ok {
+			return cfg, fmt.Errorf("unknown format %q (expected text, json or table)", cfg.Format)
+		}
+	}
+
+	return cfg, nil
+}
+
+func positiveInt(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, errors.New("must be a positive integer")
+	}
+	return n, nil
+}
+
+// exitCodeList parses a comma-separated list of exit codes such as "101,2".
+func exitCodeList(value string) ([]int, error) {
+	var codes []int
+	for _, field := range strings.Split(value, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || code <= 0 {
+			return nil, errors.New("must be a comma-separated list of non-zero exit codes")
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// durationValue is a flag.Value accepting either a Go duration ("10m") or a
+// plain number of seconds ("600").
+type durationValue time.Duration
+
+func (d *durationValue) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *durationValue) Set(value string) error {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return errors.New("must not be negative")
+		}
+		*d = durationValue(time.Duration(seconds) * time.Second)
+		return nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return errors.New("must be a number of seconds or a duration like 10m")
+	}
+	*d = durationValue(duration)
+	return nil
+}
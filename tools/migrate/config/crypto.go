@@ -0,0 +1,101 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the length of an encryption key: AES-256.
+const KeySize = 32
+
+// ParseKey decodes a base64-encoded encryption key, as given to config
+// encrypt --key or in MIGRATE_ENCRYPT_KEY.
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.New("invalid encryption key: not base64")
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid encryption key: %d bytes, want %d (e.g. openssl rand -base64 32)", len(key), KeySize)
+	}
+	return key, nil
+}
+
+// EncryptEnv encrypts the value of every variable in data, the contents of a
+// .env file, with AES-256-GCM under key, leaving names, comments and blank
+// lines as they are. Each value is sealed with a fresh random nonce and
+// bound to its variable's name, and replaced by the base64 of the nonce and
+// ciphertext. A value is encrypted exactly as written, quotes and escapes
+// included, so that DecryptEnv restores the file byte for byte.
+func EncryptEnv(data, key []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return mapValues(data, func(name, value string) (string, error) {
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		sealed := aead.Seal(nonce, nonce, []byte(value), []byte(name))
+		return base64.StdEncoding.EncodeToString(sealed), nil
+	})
+}
+
+// DecryptEnv reverses EncryptEnv. It fails if any value was not encrypted
+// under key for its variable.
+func DecryptEnv(data, key []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return mapValues(data, func(name, value string) (string, error) {
+		sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return "", fmt.Errorf("%s: not an encrypted value", name)
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		plain, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+		if err != nil {
+			return "", fmt.Errorf("%s: cannot decrypt; wrong key?", name)
+		}
+		return string(plain), nil
+	})
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// mapValues replaces the value of each NAME=value line in data, everything
+// after the first =, with what fn returns for it. Blank lines, comments and
+// lines without = are kept as they are.
+func mapValues(data []byte, fn func(name, value string) (string, error)) ([]byte, error) {
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		prefix, value, found := strings.Cut(line, "=")
+		name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(prefix), "export "))
+		if !found || name == "" {
+			continue
+		}
+		mapped, err := fn(name, value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		lines[i] = prefix + "=" + mapped
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
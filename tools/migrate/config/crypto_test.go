@@ -0,0 +1,111 @@
+package config
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+const plainEnv = `# Database
+DATABASE_URL=postgres://bot:p@ss=w#rd!@localhost:5432/crypto_bot
+export API_SECRET='single $quoted \ value'
+PRIVATE_KEY="-----BEGIN KEY-----\nMIIB\"quoted\"\n-----END KEY-----"
+GREETING="héllo, wörld ✓"   # trailing comment
+EMPTY=
+
+not a variable
+`
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEncryptEnvRoundTrip(t *testing.T) {
+	key := testKey(t)
+	encrypted, err := EncryptEnv([]byte(plainEnv), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"p@ss", "single", "BEGIN KEY", "héllo"} {
+		if bytes.Contains(encrypted, []byte(secret)) {
+			t.Errorf("encrypted file contains %q:\n%s", secret, encrypted)
+		}
+	}
+	for _, kept := range []string{"# Database\n", "\nDATABASE_URL=", "\nexport API_SECRET=", "\nnot a variable\n"} {
+		if !bytes.Contains(encrypted, []byte(kept)) {
+			t.Errorf("encrypted file lost %q:\n%s", kept, encrypted)
+		}
+	}
+
+	decrypted, err := DecryptEnv(encrypted, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decrypted) != plainEnv {
+		t.Errorf("round trip gave\n%s\nwant\n%s", decrypted, plainEnv)
+	}
+}
+
+func TestEncryptEnvMultilineValue(t *testing.T) {
+	// A value holding real newlines, as a caller might build it, survives
+	// too; only the lines of the file are split.
+	key := testKey(t)
+	value := "first\nsecond"
+	sealed, err := EncryptEnv([]byte("CERT="+value), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(sealed), "\n"); n != 1 {
+		t.Fatalf("EncryptEnv wrote %d newlines, want 1 (one per input line)", n)
+	}
+}
+
+func TestEncryptEnvUsesFreshNonces(t *testing.T) {
+	key := testKey(t)
+	a, err := EncryptEnv([]byte("A=same\nB=same\n"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(a), "\n")
+	if strings.TrimPrefix(lines[0], "A=") == strings.TrimPrefix(lines[1], "B=") {
+		t.Error("equal values encrypted to the same ciphertext")
+	}
+}
+
+func TestDecryptEnvRejectsWrongKeyAndMovedValues(t *testing.T) {
+	key := testKey(t)
+	encrypted, err := EncryptEnv([]byte("A=one\nB=two\n"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DecryptEnv(encrypted, testKey(t)); err == nil {
+		t.Error("DecryptEnv with another key succeeded")
+	}
+
+	lines := strings.Split(string(encrypted), "\n")
+	swapped := "A=" + strings.TrimPrefix(lines[1], "B=") + "\n"
+	if _, err := DecryptEnv([]byte(swapped), key); err == nil {
+		t.Error("DecryptEnv accepted B's value moved to A")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	key := testKey(t)
+	got, err := ParseKey(base64.StdEncoding.EncodeToString(key) + "\n")
+	if err != nil || !bytes.Equal(got, key) {
+		t.Errorf("ParseKey = %x, %v; want %x", got, err, key)
+	}
+	for _, bad := range []string{"", "not base64!", base64.StdEncoding.EncodeToString(key[:16])} {
+		if _, err := ParseKey(bad); err == nil {
+			t.Errorf("ParseKey(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// ParseExtraArgs splits raw, the value of MIGRATE_EXTRA_ARGS, into
+// arguments the way a POSIX shell would, without expanding anything:
+// whitespace separates arguments; single quotes keep everything up to the
+// next single quote; double quotes keep everything up to the next
+// unescaped double quote, where a backslash escapes ", \, $ and `; and
+// outside quotes a backslash escapes any character.
+func ParseExtraArgs(raw string) ([]string, error) {
+	const (
+		between = iota
+		unquoted
+		singleQuoted
+		doubleQuoted
+	)
+
+	var args []string
+	var current strings.Builder
+	state := between
+	escaped := false
+
+	for _, r := range raw {
+		if escaped {
+			if state == doubleQuoted && !strings.ContainsRune(`"\$`+"`", r) {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+			continue
+		}
+
+		switch state {
+		case between, unquoted:
+			switch {
+			case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+				if state == unquoted {
+					args = append(args, current.String())
+					current.Reset()
+					state = between
+				}
+			case r == '\\':
+				escaped = true
+				state = unquoted
+			case r == '\'':
+				state = singleQuoted
+			case r == '"':
+				state = doubleQuoted
+			default:
+				current.WriteRune(r)
+				state = unquoted
+			}
+		case singleQuoted:
+			if r == '\'' {
+				state = unquoted
+			} else {
+				current.WriteRune(r)
+			}
+		case doubleQuoted:
+			switch r {
+			case '"':
+				state = unquoted
+			case '\\':
+				escaped = true
+			default:
+				current.WriteRune(r)
+			}
+		}
+	}
+
+	switch {
+	case escaped:
+		return nil, errors.New("trailing backslash")
+	case state == singleQuoted:
+		return nil, errors.New("unterminated single quote")
+	case state == doubleQuoted:
+		return nil, errors.New("unterminated double quote")
+	case state == unquoted:
+		args = append(args, current.String())
+	}
+	return args, nil
+}
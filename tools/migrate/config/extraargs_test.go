@@ -0,0 +1,79 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseExtraArgs(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"--log-level trace --config /etc/migrate.toml", []string{"--log-level", "trace", "--config", "/etc/migrate.toml"}},
+		{"  -v\t--num 2 ", []string{"-v", "--num", "2"}},
+		{`--name 'two words'`, []string{"--name", "two words"}},
+		{`--name "two words"`, []string{"--name", "two words"}},
+		{`--opt='a "b" c'`, []string{`--opt=a "b" c`}},
+		{`"say \"hi\" \$HOME \n"`, []string{`say "hi" $HOME \n`}},
+		{`'it'\''s'`, []string{"it's"}},
+		{`two\ words plain\\slash`, []string{"two words", `plain\slash`}},
+		{`'' ""`, []string{"", ""}},
+		{`a"b"'c'd`, []string{"abcd"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseExtraArgs(tt.raw)
+		if err != nil {
+			t.Errorf("ParseExtraArgs(%q): %v", tt.raw, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParseExtraArgs(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseExtraArgsErrors(t *testing.T) {
+	for _, raw := range []string{`'open`, `"open`, `--flag \`, `"escaped end\"`} {
+		if args, err := ParseExtraArgs(raw); err == nil {
+			t.Errorf("ParseExtraArgs(%q) = %q, want an error", raw, args)
+		}
+	}
+}
+
+// shellQuote single-quotes arg so that ParseExtraArgs reads it back as is.
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// FuzzParseExtraArgs checks that no input panics and that whatever parses
+// survives being quoted and parsed again unchanged.
+func FuzzParseExtraArgs(f *testing.F) {
+	for _, seed := range []string{
+		"--log-level trace --config /etc/migrate.toml",
+		`--name 'two words' "quoted \"value\"" back\ slash`,
+		`'it'\''s'`, `"`, `'`, `\`, "\x00\xff'\"\\", "a\tb\nc",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		args, err := ParseExtraArgs(raw)
+		if err != nil {
+			return
+		}
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = shellQuote(arg)
+		}
+		again, err := ParseExtraArgs(strings.Join(quoted, " "))
+		if err != nil {
+			t.Fatalf("re-parsing %q: %v", args, err)
+		}
+		if !slices.Equal(again, args) {
+			t.Fatalf("ParseExtraArgs(%q) = %q, but its quoted form parses as %q", raw, args, again)
+		}
+	})
+}
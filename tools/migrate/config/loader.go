@@ -0,0 +1,179 @@
+// Package config reads the optional migrate.yaml / migrate.json file that
+// holds settings otherwise given as environment variables.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPaths are the files looked for in the current directory when no
+// --config is given.
+var DefaultPaths = []string{"migrate.yaml", "migrate.yml", "migrate.json"}
+
+// Config is the contents of a configuration file. Empty fields were not
+// set.
+type Config struct {
+	DatabaseURL  string `mapstructure:"database_url"`
+	MigrationDir string `mapstructure:"migration_dir"`
+	CargoBin     string `mapstructure:"cargo_bin"`
+	AuditLog     string `mapstructure:"audit_log"`
+	WebhookURL   string `mapstructure:"webhook_url"`
+	Engine       string `mapstructure:"engine"`
+	S3Dir        string `mapstructure:"s3_dir"`
+	Lint         Lint   `mapstructure:"lint"`
+
+	// Environments maps the names given to --env, such as staging or
+	// production, to their settings.
+	Environments map[string]EnvironmentConfig `mapstructure:"environments"`
+
+	// DatabaseURLSecret is the reference database_url was given as with the
+	// !secret tag, in which case DatabaseURL is empty.
+	DatabaseURLSecret string `mapstructure:"-"`
+
+	// Path is the file the configuration was read from, or empty if none
+	// of the candidates existed.
+	Path string `mapstructure:"-"`
+}
+
+// EnvironmentConfig holds the settings of one environment, which replace
+// the top-level ones when it is selected with --env.
+type EnvironmentConfig struct {
+	DatabaseURL string `mapstructure:"database_url"`
+
+	// DatabaseURLSecret is the reference database_url was given as with the
+	// !secret tag, in which case DatabaseURL is empty.
+	DatabaseURLSecret string `mapstructure:"-"`
+}
+
+// Lint configures migrate lint.
+type Lint struct {
+	// Rules maps rule names to the severity they are reported with:
+	// error, warning or off.
+	Rules map[string]string `mapstructure:"rules"`
+}
+
+// LoadConfig reads the first of paths that exists, as JSON if its name ends
+// in .json and as YAML otherwise. Relative migration_dir and audit_log
+// values are taken relative to the file's directory. It returns an empty
+// Config if none of the files exist, and an error for unknown keys.
+func LoadConfig(paths []string) (Config, error) {
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return Config{}, err
+		}
+
+		cfg, err := parse(path, data)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s: %w", path, err)
+		}
+		cfg.Path = path
+		cfg.MigrationDir = relativeTo(path, cfg.MigrationDir)
+		cfg.AuditLog = relativeTo(path, cfg.AuditLog)
+		return cfg, nil
+	}
+	return Config{}, nil
+}
+
+// ListEnvironments returns the names of the environments the file defines,
+// sorted.
+func (c Config) ListEnvironments() []string {
+	names := make([]string, 0, len(c.Environments))
+	for name := range c.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parse(path string, data []byte) (Config, error) {
+	var raw map[string]any
+	secrets := map[string]string{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return Config{}, err
+		}
+	} else {
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return Config{}, err
+		}
+		if len(doc.Content) > 0 {
+			if err := takeSecrets(doc.Content[0], "", secrets); err != nil {
+				return Config{}, err
+			}
+			if err := doc.Content[0].Decode(&raw); err != nil {
+				return Config{}, err
+			}
+		}
+	}
+
+	var cfg Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused: true,
+		Result:      &cfg,
+	})
+	if err != nil {
+		return Config{}, err
+	}
+	if err := decoder.Decode(raw); err != nil {
+		return Config{}, err
+	}
+
+	cfg.DatabaseURLSecret = secrets["database_url"]
+	for name, env := range cfg.Environments {
+		env.DatabaseURLSecret = secrets["environments."+name+".database_url"]
+		cfg.Environments[name] = env
+	}
+	return cfg, nil
+}
+
+// takeSecrets records the value of every scalar under node tagged !secret,
+// such as `database_url: !secret vault://secret/data/crypto-bot`, in
+// secrets by its dotted key, and empties it so that it decodes as unset.
+// Only database_url values may be secrets, and only Vault references are
+// understood.
+func takeSecrets(node *yaml.Node, key string, secrets map[string]string) error {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!secret" {
+		if key != "database_url" && !(strings.HasPrefix(key, "environments.") && strings.HasSuffix(key, ".database_url")) {
+			return fmt.Errorf("line %d: !secret can only be used for database_url, not %s", node.Line, key)
+		}
+		if !strings.HasPrefix(node.Value, "vault://") {
+			return fmt.Errorf("line %d: unsupported secret reference %q, expected vault://<path>", node.Line, node.Value)
+		}
+		secrets[key] = node.Value
+		node.Tag, node.Value = "!!str", ""
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name := node.Content[i].Value
+		if key != "" {
+			name = key + "." + name
+		}
+		if err := takeSecrets(node.Content[i+1], name, secrets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func relativeTo(configPath, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(configPath), path)
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigEnvironments(t *testing.T) {
+	path := writeConfig(t, "migrate.yaml", `
+database_url: postgres://localhost/app
+environments:
+  staging:
+    database_url: postgres://staging-db/app
+  production:
+    database_url: !secret vault://secret/data/crypto-bot#url
+`)
+
+	cfg, err := LoadConfig([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DatabaseURL != "postgres://localhost/app" || cfg.DatabaseURLSecret != "" {
+		t.Errorf("top-level database_url = %q, secret %q", cfg.DatabaseURL, cfg.DatabaseURLSecret)
+	}
+	if got := cfg.ListEnvironments(); !reflect.DeepEqual(got, []string{"production", "staging"}) {
+		t.Errorf("ListEnvironments() = %v", got)
+	}
+	if got := cfg.Environments["staging"]; got.DatabaseURL != "postgres://staging-db/app" || got.DatabaseURLSecret != "" {
+		t.Errorf("staging = %+v", got)
+	}
+	if got := cfg.Environments["production"]; got.DatabaseURL != "" || got.DatabaseURLSecret != "vault://secret/data/crypto-bot#url" {
+		t.Errorf("production = %+v", got)
+	}
+}
+
+func TestLoadConfigSecretErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"migration_dir: !secret vault://secret/data/x\n", "only be used for database_url"},
+		{"database_url: !secret ssm://db-url\n", "unsupported secret reference"},
+		{"environments:\n  staging:\n    databse_url: postgres://db/app\n", "databse_url"},
+	}
+
+	for _, tt := range tests {
+		path := writeConfig(t, "migrate.yaml", tt.content)
+		if _, err := LoadConfig([]string{path}); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("LoadConfig(%q) error = %v, want it to mention %q", tt.content, err, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigEmptyFile(t *testing.T) {
+	path := writeConfig(t, "migrate.yaml", "")
+	cfg, err := LoadConfig([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Path != path || len(cfg.ListEnvironments()) != 0 {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
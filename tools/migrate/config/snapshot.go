@@ -0,0 +1,81 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// sensitiveWords mark a variable whose value is a credential when they
+// appear anywhere in its name.
+var sensitiveWords = []string{"PASSWORD", "SECRET", "TOKEN", "KEY"}
+
+// Setting is one variable of a ConfigSnapshot.
+type Setting struct {
+	Value string
+	// Sensitive is set for credentials, whose values must not be printed.
+	Sensitive bool
+}
+
+// ConfigSnapshot is a set of configuration variables and their values,
+// such as the tool's effective environment or a reference .env file.
+type ConfigSnapshot map[string]Setting
+
+// NewConfigSnapshot returns a snapshot of values, marking the variables
+// whose names contain PASSWORD, SECRET, TOKEN or KEY as sensitive.
+func NewConfigSnapshot(values map[string]string) ConfigSnapshot {
+	snapshot := make(ConfigSnapshot, len(values))
+	for key, value := range values {
+		snapshot[key] = Setting{Value: value, Sensitive: IsSensitive(key)}
+	}
+	return snapshot
+}
+
+// IsSensitive reports whether the variable called key holds a credential.
+func IsSensitive(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, word := range sensitiveWords {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Difference is a variable that is missing from one snapshot or has a
+// different value in each. A missing side has a nil Setting.
+type Difference struct {
+	Key       string
+	Current   *Setting
+	Reference *Setting
+}
+
+// Diff returns the variables that differ between s and reference, ordered
+// by name.
+func (s ConfigSnapshot) Diff(reference ConfigSnapshot) []Difference {
+	keys := make(map[string]bool, len(s)+len(reference))
+	for key := range s {
+		keys[key] = true
+	}
+	for key := range reference {
+		keys[key] = true
+	}
+
+	var diffs []Difference
+	for key := range keys {
+		current, inCurrent := s[key]
+		ref, inReference := reference[key]
+		if inCurrent && inReference && current.Value == ref.Value {
+			continue
+		}
+		diff := Difference{Key: key}
+		if inCurrent {
+			diff.Current = &current
+		}
+		if inReference {
+			diff.Reference = &ref
+		}
+		diffs = append(diffs, diff)
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Key < diffs[j].Key })
+	return diffs
+}
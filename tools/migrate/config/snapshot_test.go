@@ -0,0 +1,48 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsSensitive(t *testing.T) {
+	for key, want := range map[string]bool{
+		"DB_PASSWORD":            true,
+		"GCP_SECRET_NAME":        true,
+		"vault_token":            true,
+		"AWS_ACCESS_KEY_ID":      true,
+		"DATABASE_URL":           false,
+		"MIGRATE_AUDIT_LOG":      false,
+		"PROMETHEUS_PUSHGATEWAY": false,
+	} {
+		if got := IsSensitive(key); got != want {
+			t.Errorf("IsSensitive(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	current := NewConfigSnapshot(map[string]string{
+		"APP_ENV":      "staging",
+		"DATABASE_URL": "postgres://db/app",
+		"DB_PASSWORD":  "s3cret",
+		"LOG_LEVEL":    "debug",
+	})
+	reference := NewConfigSnapshot(map[string]string{
+		"APP_ENV":      "development",
+		"DATABASE_URL": "postgres://db/app",
+		"DB_PASSWORD":  "changeme",
+		"RUST_LOG":     "info",
+	})
+
+	got := current.Diff(reference)
+	want := []Difference{
+		{Key: "APP_ENV", Current: &Setting{Value: "staging"}, Reference: &Setting{Value: "development"}},
+		{Key: "DB_PASSWORD", Current: &Setting{Value: "s3cret", Sensitive: true}, Reference: &Setting{Value: "changeme", Sensitive: true}},
+		{Key: "LOG_LEVEL", Current: &Setting{Value: "debug"}},
+		{Key: "RUST_LOG", Reference: &Setting{Value: "info"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/crypto-bot/tools/migrate/config"
+	"github.com/crypto-bot/tools/migrate/internal/env"
+	"github.com/crypto-bot/tools/migrate/lint"
+)
+
+// applyFileConfig exports the configuration file's settings that the rest of
+// the tool reads from the environment, overriding the environment.
+func applyFileConfig(file config.Config) {
+	for key, value := range map[string]string{
+		"DATABASE_URL":        file.DatabaseURL,
+		"MIGRATE_AUDIT_LOG":   file.AuditLog,
+		"MIGRATE_WEBHOOK_URL": file.WebhookURL,
+		"MIGRATE_ENGINE":      file.Engine,
+		"MIGRATE_S3_DIR":      file.S3Dir,
+	} {
+		if value != "" {
+			environment.Set(key, value)
+		}
+	}
+}
+
+// runConfigValidate prints the settings the configuration file, flags and
+// environment resolve to, with credentials masked, and fails if the
+// migration directory or DATABASE_URL is unusable.
+func runConfigValidate(cfg Config) int {
+	if cfg.File.Path != "" {
+		printer.Info("Configuration file: %s", cfg.File.Path)
+	} else {
+		printer.Info("Configuration file: none")
+	}
+	if cfg.Env != "" {
+		printer.Info("Environment: %s", cfg.Env)
+	}
+
+	source := func(fromFile bool, value string) string {
+		switch {
+		case value == "":
+			return "not set"
+		case fromFile:
+			return "file"
+		default:
+			return "flag or environment"
+		}
+	}
+	orDash := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+		return value
+	}
+
+	databaseURL := getenv("DATABASE_URL")
+	auditLog := getenv("MIGRATE_AUDIT_LOG")
+	webhookURL := getenv("MIGRATE_WEBHOOK_URL")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	if cfg.File.DatabaseURLSecret != "" {
+		fmt.Fprintf(w, "database_url\t%s\tfile\n", cfg.File.DatabaseURLSecret)
+	} else {
+		fmt.Fprintf(w, "database_url\t%s\t%s\n", orDash(maskDatabaseURL(databaseURL)),
+			source(cfg.File.DatabaseURL != "", databaseURL))
+	}
+	fmt.Fprintf(w, "migration_dir\t%s\t%s\n", cfg.MigrationDir,
+		source(cfg.File.MigrationDir != "" && cfg.File.MigrationDir == cfg.MigrationDir, cfg.MigrationDir))
+	engineSource := source(cfg.File.Engine != "", getenv("MIGRATE_ENGINE"))
+	if engineSource == "not set" {
+		engineSource = "default"
+	}
+	fmt.Fprintf(w, "engine\t%s\t%s\n", cfg.Engine, engineSource)
+	fmt.Fprintf(w, "cargo_bin\t%s\t%s\n", orDash(cfg.CargoBin),
+		source(cfg.File.CargoBin != "" && cfg.File.CargoBin == cfg.CargoBin, cfg.CargoBin))
+	fmt.Fprintf(w, "audit_log\t%s\t%s\n", orDash(auditLog), source(cfg.File.AuditLog != "", auditLog))
+	fmt.Fprintf(w, "webhook_url\t%s\t%s\n", orDash(maskWebhookURL(webhookURL)),
+		source(cfg.File.WebhookURL != "", webhookURL))
+	w.Flush()
+
+	ok := checkMigrationDir(cfg)
+	if _, err := lint.NewLinter(lint.Rules(), cfg.File.Lint.Rules); err != nil {
+		printer.Error("Error: %v", err)
+		ok = false
+	}
+	if databaseURL != "" && cfg.File.DatabaseURLSecret == "" {
+		cfg.DatabaseURL = databaseURL
+		ok = checkDatabaseURL(cfg) && ok
+	}
+	if !ok {
+		return 1
+	}
+	printer.Success("Configuration is valid")
+	return 0
+}
+
+// toolVariables are the environment variables the tool reads, compared by
+// config diff even when no .env file sets them.
+var toolVariables = []string{
+	"DATABASE_URL", "DATABASE_URL_FILE",
+	"VAULT_ADDR", "VAULT_TOKEN", "VAULT_SECRET_PATH",
+	"GCP_PROJECT_ID", "GCP_SECRET_NAME",
+	"AWS_PARAMETER_NAME", "AWS_REGION",
+	"APP_ENV", "MIGRATE_WEBHOOK_URL", "PROMETHEUS_PUSHGATEWAY_URL", "MIGRATE_AUDIT_LOG",
+	"PRE_MIGRATE_HOOK", "POST_MIGRATE_HOOK", "LOG_FORMAT", "LOG_LEVEL",
+	"CARGO_BIN", "MIGRATE_ENGINE", "MIGRATE_ENGINE_BIN", "MIGRATE_TABLE_NAME",
+	"MIGRATE_EXTRA_ARGS", "MIGRATE_HISTORY_DB_URLS", "MIGRATE_ENCRYPT_KEY",
+	"MIGRATE_S3_DIR", "CLOUD_PROVIDER", "GITHUB_TOKEN",
+}
+
+// runConfigDiff compares the effective configuration, the tool's variables
+// and those from the loaded .env files, with the reference .env file at
+// --reference. Credentials are never printed, only whether they differ.
+// Like diff, it fails when there are differences.
+func runConfigDiff(cfg Config) int {
+	values, err := env.ReadFile(cfg.Reference)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	reference := config.NewConfigSnapshot(values)
+
+	keys := append(environment.Keys(), toolVariables...)
+	for key := range values {
+		keys = append(keys, key)
+	}
+	effective := make(map[string]string)
+	for _, key := range keys {
+		if value := getenv(key); value != "" {
+			effective[key] = value
+		}
+	}
+	diffs := config.NewConfigSnapshot(effective).Diff(reference)
+	if len(diffs) == 0 {
+		printer.Success("The configuration matches %s", cfg.Reference)
+		return 0
+	}
+
+	show := func(s config.Setting) string {
+		if s.Sensitive {
+			return "<set>"
+		}
+		return maskDatabaseURLs(s.Value)
+	}
+	var onlyCurrent, onlyReference, changed []string
+	for _, d := range diffs {
+		switch {
+		case d.Reference == nil:
+			onlyCurrent = append(onlyCurrent, d.Key+"="+show(*d.Current))
+		case d.Current == nil:
+			onlyReference = append(onlyReference, d.Key+"="+show(*d.Reference))
+		case d.Current.Sensitive:
+			changed = append(changed, d.Key+": <changed>")
+		default:
+			changed = append(changed, fmt.Sprintf("%s: %s (reference: %s)", d.Key, show(*d.Current), show(*d.Reference)))
+		}
+	}
+	for _, section := range []struct {
+		heading string
+		lines   []string
+	}{
+		{"Only in the current configuration:", onlyCurrent},
+		{"Only in " + cfg.Reference + ":", onlyReference},
+		{"Different values:", changed},
+	} {
+		if len(section.lines) == 0 {
+			continue
+		}
+		fmt.Println(section.heading)
+		for _, line := range section.lines {
+			fmt.Println("  " + line)
+		}
+	}
+	printer.Warn("%d difference(s) from %s", len(diffs), cfg.Reference)
+	return 1
+}
+
+// maskDatabaseURL hides the password in a connection string.
+func maskDatabaseURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	// Redacted writes the password as xxxxx; use the redactor's marker.
+	return strings.Replace(u.Redacted(), ":xxxxx@", ":***@", 1)
+}
+
+// maskDatabaseURLs hides the passwords in a comma-separated list of
+// connection strings, such as MIGRATE_HISTORY_DB_URLS.
+func maskDatabaseURLs(raw string) string {
+	if !strings.Contains(raw, "://") {
+		return raw
+	}
+	urls := strings.Split(raw, ",")
+	for i, u := range urls {
+		urls[i] = maskDatabaseURL(strings.TrimSpace(u))
+	}
+	return strings.Join(urls, ",")
+}
+
+// maskWebhookURL keeps only the scheme and host of a webhook URL, since
+// services such as Slack embed the credential in the path.
+func maskWebhookURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		if raw == "" {
+			return ""
+		}
+		return "****"
+	}
+	return u.Scheme + "://" + u.Host + "/****"
+}
+
+// runConfigCrypt encrypts the project's .env into .env.encrypted, which can
+// be committed, or decrypts .env.encrypted back into .env, with the key from
+// --key or MIGRATE_ENCRYPT_KEY. Decrypting never overwrites an existing .env.
+func runConfigCrypt(cfg Config) int {
+	encoded := cfg.EncryptKey
+	if encoded == "" {
+		encoded = getenv("MIGRATE_ENCRYPT_KEY")
+	}
+	if encoded == "" {
+		printer.Error("Error: config %s needs a key: pass --key or set MIGRATE_ENCRYPT_KEY", cfg.Args[0])
+		return 1
+	}
+	key, err := config.ParseKey(encoded)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	plainPath := filepath.Join(cfg.ProjectRoot, ".env")
+	encryptedPath := plainPath + ".encrypted"
+	from, to, transform := plainPath, encryptedPath, config.EncryptEnv
+	if cfg.Args[0] == "decrypt" {
+		from, to, transform = encryptedPath, plainPath, config.DecryptEnv
+		if _, err := os.Stat(to); err == nil {
+			printer.Error("Error: %s already exists; remove it first to decrypt %s", to, from)
+			return 1
+		}
+	}
+
+	data, err := os.ReadFile(from)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	out, err := transform(data, key)
+	if err != nil {
+		printer.Error("Error: %s: %v", from, err)
+		return 1
+	}
+	if err := os.WriteFile(to, out, 0o600); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	printer.Success("✓ Wrote %s", to)
+	return 0
+}
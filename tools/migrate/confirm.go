@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// errNotConfirmed is returned when the user declines a destructive command.
+var errNotConfirmed = errors.New("confirmation failed, aborting")
+
+// confirmFresh asks the user to type the name of the database that fresh is
+// about to wipe and fails unless the answer matches.
+func confirmFresh(in io.Reader, out io.Writer, databaseURL string) error {
+	warning := fmt.Sprintf("fresh drops every table on %s and re-runs all migrations.", databaseHost(databaseURL))
+	return confirmDatabaseName(in, out, databaseURL, warning)
+}
+
+// confirmRestore asks the user to type the name of the database that restore
+// is about to overwrite with input and fails unless the answer matches.
+func confirmRestore(in io.Reader, out io.Writer, databaseURL, input string) error {
+	warning := fmt.Sprintf("restore replaces the contents of %s with %s.", databaseHost(databaseURL), input)
+	return confirmDatabaseName(in, out, databaseURL, warning)
+}
+
+// confirmDatabaseName prints warning, then asks for the name of the database
+// and fails unless it is typed.
+func confirmDatabaseName(in io.Reader, out io.Writer, databaseURL, warning string) error {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return err
+	}
+	database := strings.Trim(u.Path, "/")
+
+	fmt.Fprintln(out, "WARNING: "+warning)
+	fmt.Fprintf(out, "Type the database name (%s) to continue: ", database)
+
+	scanner := bufio.NewScanner(in)
+	if !scanner.Scan() {
+		fmt.Fprintln(out)
+		return errNotConfirmed
+	}
+	if strings.TrimSpace(scanner.Text()) != database {
+		return errNotConfirmed
+	}
+	return nil
+}
+
+// confirmRollback asks the user to approve the rollback plan that has just
+// been printed and fails unless the answer is yes.
+func confirmRollback(in io.Reader, out io.Writer) error {
+	return confirmYes(in, out, "Proceed with the rollback?")
+}
+
+// confirmRepair asks the user to approve editing the migration tracking
+// table and fails unless the answer is yes.
+func confirmRepair(in io.Reader, out io.Writer, action string) error {
+	fmt.Fprintf(out, "WARNING: repair will %s without running it.\n", action)
+	return confirmYes(in, out, "Proceed with the repair?")
+}
+
+// confirmOnly asks the user to approve applying the migration called name
+// out of order and fails unless the answer is yes.
+func confirmOnly(in io.Reader, out io.Writer, name string) error {
+	return confirmYes(in, out, fmt.Sprintf("Apply %s out of order?", name))
+}
+
+// confirmYes asks question and fails unless the answer is y or yes.
+func confirmYes(in io.Reader, out io.Writer, question string) error {
+	fmt.Fprint(out, question+" [y/N] ")
+
+	scanner := bufio.NewScanner(in)
+	if !scanner.Scan() {
+		fmt.Fprintln(out)
+		return errNotConfirmed
+	}
+	switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+	case "y", "yes":
+		return nil
+	}
+	return errNotConfirmed
+}
+
+// confirmInteractive runs ask, one of the confirmations above, against the
+// standard input in. A terminal is asked, and piped input, as in
+// `echo crypto_bot | migrate fresh`, is read as the answer. Input that is
+// neither, such as the /dev/null most CI jobs run with or a closed stdin,
+// leaves no answer to read, so the command is refused rather than run
+// unconfirmed; --yes is the way to run it unattended.
+func confirmInteractive(in *os.File, out io.Writer, command string, ask func(io.Reader, io.Writer) error) error {
+	if !term.IsTerminal(int(in.Fd())) {
+		info, err := in.Stat()
+		if err != nil || (info.Mode()&os.ModeNamedPipe == 0 && !info.Mode().IsRegular()) {
+			return fmt.Errorf("no terminal or piped answer to confirm %s with; pass --yes to run it without confirmation", command)
+		}
+	}
+	return ask(byteReader{in}, out)
+}
+
+// byteReader reads one byte at a time, so that the scanner reading one
+// answer from piped input does not take the next answer with it.
+type byteReader struct{ r io.Reader }
+
+func (b byteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return b.r.Read(p)
+}
+
+// confirmFreshInteractive runs confirmFresh through confirmInteractive.
+func confirmFreshInteractive(databaseURL string) error {
+	return confirmInteractive(os.Stdin, os.Stdout, "fresh", func(in io.Reader, out io.Writer) error {
+		return confirmFresh(in, out, databaseURL)
+	})
+}
+
+// confirmRollbackInteractive runs confirmRollback through
+// confirmInteractive.
+func confirmRollbackInteractive() error {
+	return confirmInteractive(os.Stdin, os.Stdout, "the rollback", confirmRollback)
+}
+
+// confirmRepairInteractive runs confirmRepair through confirmInteractive.
+func confirmRepairInteractive(action string) error {
+	return confirmInteractive(os.Stdin, os.Stdout, "the repair", func(in io.Reader, out io.Writer) error {
+		return confirmRepair(in, out, action)
+	})
+}
+
+// confirmOnlyInteractive runs confirmOnly through confirmInteractive.
+func confirmOnlyInteractive(name string) error {
+	return confirmInteractive(os.Stdin, os.Stdout, "applying "+name+" out of order", func(in io.Reader, out io.Writer) error {
+		return confirmOnly(in, out, name)
+	})
+}
+
+// confirmRestoreInteractive runs confirmRestore through confirmInteractive.
+func confirmRestoreInteractive(databaseURL, input string) error {
+	return confirmInteractive(os.Stdin, os.Stdout, "the restore", func(in io.Reader, out io.Writer) error {
+		return confirmRestore(in, out, databaseURL, input)
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// createMigration writes empty up and down files for a new migration named
+// name into dir and returns their paths. On error it leaves neither file
+// behind.
+func createMigration(dir, name string) ([]string, error) {
+	var namer migrations.MigrationNamer
+
+	normalized, err := namer.Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := migrations.Scan(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	next := 1
+	for _, m := range existing {
+		if m.Name == normalized {
+			return nil, fmt.Errorf("migration %q already exists as %s", normalized, namer.Base(m.Sequence, m.Name))
+		}
+		if m.Sequence >= next {
+			next = m.Sequence + 1
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	base := namer.Base(next, normalized)
+	header := fmt.Sprintf("-- Migration: %s\n-- Created at: %s\n", base, time.Now().UTC().Format(time.RFC3339))
+
+	// Remove what was written if a file fails, so that a retry does not
+	// find half a migration in the way.
+	var paths []string
+	fail := func(err error) ([]string, error) {
+		for _, path := range paths {
+			os.Remove(path)
+		}
+		return nil, err
+	}
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, base+"."+direction+".sql")
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return fail(err)
+		}
+		paths = append(paths, path)
+		_, err = file.WriteString(header)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	return paths, nil
+}
+
+func runCreate(cfg Config) int {
+	if len(cfg.Args) != 1 {
+		printer.Error("Usage: migrate create <name>")
+		return 1
+	}
+
+	paths, err := createMigration(cfg.SQLDir(), cfg.Args[0])
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	for _, path := range paths {
+		printer.Success("Created %s", path)
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+
+	paths, err := createMigration(dir, "Add Users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "000001_add_users.up.sql"), filepath.Join(dir, "000001_add_users.down.sql")}
+	if len(paths) != 2 || paths[0] != want[0] || paths[1] != want[1] {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+
+	if _, err := createMigration(dir, "add_users"); err == nil {
+		t.Error("createMigration succeeded for a name that already exists")
+	}
+}
+
+func TestCreateMigrationRemovesUpFileWhenDownFails(t *testing.T) {
+	dir := t.TempDir()
+	// A directory where the down file goes makes creating it fail; Scan
+	// ignores directories, so the name itself is free.
+	blocker := filepath.Join(dir, "000001_add_users.down.sql")
+	if err := os.Mkdir(blocker, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := createMigration(dir, "add_users"); err == nil {
+		t.Fatal("createMigration succeeded although the down file could not be written")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "000001_add_users.up.sql")); !os.IsNotExist(err) {
+		t.Errorf("the up file was left behind: %v", err)
+	}
+	if info, err := os.Stat(blocker); err != nil || !info.IsDir() {
+		t.Errorf("the existing %s was removed: %v", blocker, err)
+	}
+
+	if err := os.Remove(blocker); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createMigration(dir, "add_users"); err != nil {
+		t.Errorf("createMigration after the failure: %v", err)
+	}
+}
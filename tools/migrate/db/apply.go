@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"crypto/sha512"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ApplyUp runs up, the up SQL of the migration with version and name, and
+// records it as applied, creating the tracking table if need be, in one
+// transaction.
+func ApplyUp(ctx context.Context, conn *sql.DB, version int64, name string, up []byte) error {
+	checksum := sha512.Sum384(up)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := EnsureTable(ctx, tx); err != nil {
+		return fmt.Errorf("create %s: %w", RecordsTable(), err)
+	}
+	start := time.Now()
+	if _, err := tx.ExecContext(ctx, string(up)); err != nil {
+		return err
+	}
+	if err := RecordApplied(ctx, tx, version, name, checksum[:], time.Since(start)); err != nil {
+		return fmt.Errorf("record migration: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ApplyDown runs down, the down SQL of the migration with version, and
+// removes it from the tracking table, in one transaction.
+func ApplyDown(ctx context.Context, conn *sql.DB, version int64, down []byte) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, string(down)); err != nil {
+		return err
+	}
+	if _, err := RecordRolledBack(ctx, tx, version); err != nil {
+		return fmt.Errorf("record rollback: %w", err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PostgresRepository reads migration state from RecordsTable beside the
+// tracking table (MigrationsTable) or golang-migrate's schema_migrations,
+// whichever exists. A database with neither has no migrations applied.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository returns a Repository backed by db.
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+func (r *PostgresRepository) Status(ctx context.Context, migrations []Migration) ([]Migration, error) {
+	result := make([]Migration, len(migrations))
+	copy(result, migrations)
+
+	// MIGRATE_TABLE_NAME=schema_migrations names golang-migrate's table,
+	// which records no individual migrations.
+	if tableLayout() != schemaMigrationsLayout {
+		exists, err := r.tableExists(ctx, QuotedRecordsTable())
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return r.trackingTableStatus(ctx, result)
+		}
+		// The SeaORM migrator's table alone records no SQL file migration.
+		exists, err = r.tableExists(ctx, QuotedMigrationsTable())
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return result, nil
+		}
+	}
+
+	exists, err := r.tableExists(ctx, schemaMigrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return r.schemaMigrationsStatus(ctx, result)
+	}
+
+	return result, nil
+}
+
+// HasTrackingTable reports whether the database has a tracking table of
+// either kind, or RecordsTable, which it lacks until the first migration
+// runs.
+func (r *PostgresRepository) HasTrackingTable(ctx context.Context) (bool, error) {
+	for _, table := range []string{QuotedMigrationsTable(), QuotedRecordsTable(), schemaMigrationsTable} {
+		if exists, err := r.tableExists(ctx, table); err != nil || exists {
+			return exists, err
+		}
+	}
+	return false, nil
+}
+
+// AppliedTimes returns when each migration the tracking table records was
+// applied, keyed by the version the SeaORM migrator prints, such as
+// m20240101_000001_create_wallets_table. It is empty for a database without
+// the table and for a table in another migrator's layout.
+func (r *PostgresRepository) AppliedTimes(ctx context.Context) (map[string]time.Time, error) {
+	times := make(map[string]time.Time)
+	if tableLayout() != seaqlLayout {
+		return times, nil
+	}
+	exists, err := r.tableExists(ctx, QuotedMigrationsTable())
+	if err != nil || !exists {
+		return times, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT version, applied_at FROM "+QuotedMigrationsTable())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version string
+		var appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		times[version] = time.Unix(appliedAt, 0)
+	}
+	return times, rows.Err()
+}
+
+func (r *PostgresRepository) tableExists(ctx context.Context, table string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists)
+	return exists, err
+}
+
+// trackingTableStatus marks the migrations RecordsTable records as applied.
+func (r *PostgresRepository) trackingTableStatus(ctx context.Context, migrations []Migration) ([]Migration, error) {
+	applied, err := Applied(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]time.Time, len(applied))
+	for _, m := range applied {
+		byVersion[m.Version] = m.AppliedAt
+	}
+
+	for i := range migrations {
+		if at, ok := byVersion[migrations[i].Version]; ok {
+			migrations[i].Applied = true
+			migrations[i].AppliedAt = &at
+		}
+	}
+	return migrations, nil
+}
+
+// schemaMigrationsStatus marks every migration up to the single version
+// recorded by golang-migrate. A dirty version failed part-way and counts as
+// pending.
+func (r *PostgresRepository) schemaMigrationsStatus(ctx context.Context, migrations []Migration) ([]Migration, error) {
+	var version int64
+	var dirty bool
+	err := r.db.QueryRowContext(ctx, "SELECT version, dirty FROM "+schemaMigrationsTable+" LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return migrations, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range migrations {
+		v := migrations[i].Version
+		migrations[i].Applied = v < version || (v == version && !dirty)
+	}
+	return migrations, nil
+}
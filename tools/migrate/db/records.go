@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Querier is what *sql.DB and *sql.Tx have in common for reading.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// errSchemaMigrations is returned for what golang-migrate's
+// schema_migrations, which holds a single version, cannot record.
+var errSchemaMigrations = errors.New("schema_migrations records only golang-migrate's current version, not individual migrations")
+
+// EnsureTable creates RecordsTable, in the layout the tracking table's name
+// implies, if it does not exist.
+func EnsureTable(ctx context.Context, tx *sql.Tx) error {
+	switch tableLayout() {
+	case sqlxLayout:
+		return sqlxEnsureTable(ctx, tx)
+	case schemaMigrationsLayout:
+		return errSchemaMigrations
+	default:
+		return seaqlEnsureTable(ctx, tx)
+	}
+}
+
+// RecordApplied records in RecordsTable, within tx, that the migration with
+// version and name was applied successfully now, as the migrator that keeps
+// the table would. checksum is the SHA-384 of its up file, which
+// _sqlx_migrations records and the SeaORM layout does not. A row left by a
+// failed attempt is overwritten.
+func RecordApplied(ctx context.Context, tx *sql.Tx, version int64, name string, checksum []byte, executionTime time.Duration) error {
+	switch tableLayout() {
+	case sqlxLayout:
+		return sqlxRecordApplied(ctx, tx, version, name, checksum, executionTime)
+	case schemaMigrationsLayout:
+		return errSchemaMigrations
+	default:
+		return seaqlRecordApplied(ctx, tx, version, name)
+	}
+}
+
+// RecordRolledBack removes the RecordsTable row of the migration with
+// version within tx, as the migrator does when it reverts a migration. It
+// reports whether there was one.
+func RecordRolledBack(ctx context.Context, tx *sql.Tx, version int64) (bool, error) {
+	var result sql.Result
+	var err error
+	switch tableLayout() {
+	case sqlxLayout:
+		result, err = sqlxRecordRolledBack(ctx, tx, version)
+	case schemaMigrationsLayout:
+		return false, errSchemaMigrations
+	default:
+		result, err = seaqlRecordRolledBack(ctx, tx, version)
+	}
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// RecordRenamed changes the name the RecordsTable row of the migration
+// with version records, within tx, to name. schema_migrations records no
+// names and is left alone.
+func RecordRenamed(ctx context.Context, tx *sql.Tx, version int64, name string) error {
+	switch tableLayout() {
+	case sqlxLayout:
+		return sqlxRecordRenamed(ctx, tx, version, name)
+	case schemaMigrationsLayout:
+		return nil
+	default:
+		return seaqlRecordRenamed(ctx, tx, version, name)
+	}
+}
+
+// ClearRecords removes the rows of every SQL file migration within tx and
+// returns how many there were. The rows seaql_migrations keeps for the
+// migrations written in Rust are in another table and stay.
+func ClearRecords(ctx context.Context, tx *sql.Tx) (int64, error) {
+	var result sql.Result
+	var err error
+	switch tableLayout() {
+	case sqlxLayout:
+		result, err = sqlxClearRecords(ctx, tx)
+	case schemaMigrationsLayout:
+		return 0, errSchemaMigrations
+	default:
+		result, err = seaqlClearRecords(ctx, tx)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Applied returns the SQL file migrations RecordsTable records as applied,
+// oldest version first.
+func Applied(ctx context.Context, q Querier) ([]AppliedMigration, error) {
+	var applied []AppliedMigration
+	var err error
+	switch tableLayout() {
+	case sqlxLayout:
+		applied, err = sqlxApplied(ctx, q)
+	case schemaMigrationsLayout:
+		return nil, errSchemaMigrations
+	default:
+		applied, err = seaqlApplied(ctx, q)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", RecordsTable(), err)
+	}
+	return applied, nil
+}
@@ -0,0 +1,86 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/migratetest"
+)
+
+// These tests run against a PostgreSQL container and are skipped without
+// docker.
+
+func TestMain(m *testing.M) {
+	os.Exit(migratetest.Main(m))
+}
+
+func TestSeaQLRecords(t *testing.T) {
+	conn := migratetest.NewDB(t)
+	ctx := context.Background()
+
+	if err := db.ApplyUp(ctx, conn, 1, "create_users", []byte("CREATE TABLE users (id BIGINT PRIMARY KEY)")); err != nil {
+		t.Fatal(err)
+	}
+	// A migration written in Rust, which has no SQL file, recorded by the
+	// SeaORM migrator.
+	if _, err := conn.Exec(`CREATE TABLE seaql_migrations (version VARCHAR PRIMARY KEY, applied_at BIGINT NOT NULL);
+INSERT INTO seaql_migrations (version, applied_at) VALUES ('m20240101_000001_create_wallets_table', 0)`); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := db.Applied(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || applied[0].Version != 1 || applied[0].Name != "create_users" || applied[0].AppliedAt.IsZero() {
+		t.Fatalf("Applied() = %+v, want 000001_create_users alone", applied)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := db.RecordRenamed(ctx, tx, 1, "add_users"); err != nil {
+		t.Fatal(err)
+	}
+	var version string
+	if err := tx.QueryRow("SELECT version FROM seaql_migrations_sql").Scan(&version); err != nil || version != "000001_add_users" {
+		t.Errorf("after RecordRenamed the row's version = %q, %v; want 000001_add_users", version, err)
+	}
+	if removed, err := db.ClearRecords(ctx, tx); err != nil || removed != 1 {
+		t.Errorf("ClearRecords() = %d, %v; want the one SQL file migration removed", removed, err)
+	}
+	if removed, err := db.RecordRolledBack(ctx, tx, 1); err != nil || removed {
+		t.Errorf("RecordRolledBack() after ClearRecords = %v, %v; want nothing to remove", removed, err)
+	}
+	// The migrator fails on a row of its table it has no migration for.
+	var rows int
+	if err := tx.QueryRow("SELECT count(*) FROM seaql_migrations").Scan(&rows); err != nil || rows != 1 {
+		t.Errorf("seaql_migrations has %d row(s), %v; want only the Rust migration's", rows, err)
+	}
+}
+
+func TestAppliedTimes(t *testing.T) {
+	conn := migratetest.NewDB(t)
+	ctx := context.Background()
+	repo := db.NewPostgresRepository(conn)
+
+	if times, err := repo.AppliedTimes(ctx); err != nil || len(times) != 0 {
+		t.Fatalf("AppliedTimes() without a tracking table = %v, %v; want none", times, err)
+	}
+
+	if _, err := conn.Exec(`CREATE TABLE seaql_migrations (version VARCHAR PRIMARY KEY, applied_at BIGINT NOT NULL);
+INSERT INTO seaql_migrations (version, applied_at) VALUES ('m20240101_000001_create_wallets_table', 1704067200)`); err != nil {
+		t.Fatal(err)
+	}
+	times, err := repo.AppliedTimes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at := times["m20240101_000001_create_wallets_table"]; at.Unix() != 1704067200 {
+		t.Errorf("AppliedTimes() = %v, want m20240101_000001_create_wallets_table applied at 1704067200", times)
+	}
+}
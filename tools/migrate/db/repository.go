@@ -0,0 +1,38 @@
+// Package db reads and records migration state straight in the database,
+// without going through Cargo.
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Migration is a migration known to the tool and whether it has been
+// applied to the database.
+type Migration struct {
+	Version int64
+	Name    string
+	Applied bool
+	// AppliedAt is nil when the migration is pending or the tracking table
+	// does not record when it ran.
+	AppliedAt *time.Time
+}
+
+// AppliedMigration is a SQL file migration RecordsTable records as applied.
+type AppliedMigration struct {
+	Version int64
+	// Name is the migration's name as in its file name, with underscores.
+	Name      string
+	AppliedAt time.Time
+	// ExecutionTime and Checksum are zero when the table does not record
+	// them, as the SeaORM layout does not.
+	ExecutionTime time.Duration
+	Checksum      []byte
+}
+
+// Repository reports which migrations have been applied to a database.
+type Repository interface {
+	// Status returns migrations with Applied and AppliedAt filled in from
+	// the database, in the same order.
+	Status(ctx context.Context, migrations []Migration) ([]Migration, error)
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// createSeaQLTable is the tracking table the SeaORM migrator creates on its
+// first run, with %s for its name. RecordsTable has the same columns.
+const createSeaQLTable = `
+CREATE TABLE IF NOT EXISTS %s (
+    version VARCHAR NOT NULL PRIMARY KEY,
+    applied_at BIGINT NOT NULL
+)`
+
+// seaqlSequence is the sequence number of a row's version, such as 42 for
+// 000042_add_orders.
+const seaqlSequence = `substring(version from '^([0-9]+)_')::bigint`
+
+// seaqlVersion is the version recorded for the migration with version and
+// name: its file name without the .up.sql.
+func seaqlVersion(version int64, name string) string {
+	return fmt.Sprintf("%06d_%s", version, name)
+}
+
+func seaqlEnsureTable(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(createSeaQLTable, QuotedRecordsTable()))
+	return err
+}
+
+// seaqlRecordApplied replaces any row with the migration's sequence number,
+// so that a row recorded under an earlier name does not stay behind.
+func seaqlRecordApplied(ctx context.Context, tx *sql.Tx, version int64, name string) error {
+	if _, err := seaqlRecordRolledBack(ctx, tx, version); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO "+QuotedRecordsTable()+" (version, applied_at) VALUES ($1, extract(epoch FROM now())::bigint)",
+		seaqlVersion(version, name))
+	return err
+}
+
+func seaqlRecordRolledBack(ctx context.Context, tx *sql.Tx, version int64) (sql.Result, error) {
+	return tx.ExecContext(ctx, "DELETE FROM "+QuotedRecordsTable()+" WHERE "+seaqlSequence+" = $1", version)
+}
+
+func seaqlRecordRenamed(ctx context.Context, tx *sql.Tx, version int64, name string) error {
+	_, err := tx.ExecContext(ctx, "UPDATE "+QuotedRecordsTable()+" SET version = $1 WHERE "+seaqlSequence+" = $2",
+		seaqlVersion(version, name), version)
+	return err
+}
+
+func seaqlClearRecords(ctx context.Context, tx *sql.Tx) (sql.Result, error) {
+	return tx.ExecContext(ctx, "DELETE FROM "+QuotedRecordsTable())
+}
+
+// seaqlApplied reads the rows of the SQL file migrations, oldest first.
+// Every row is of a migration that was applied; none is written for a
+// failed one.
+func seaqlApplied(ctx context.Context, q Querier) ([]AppliedMigration, error) {
+	rows, err := q.QueryContext(ctx, "SELECT version, applied_at FROM "+QuotedRecordsTable())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applied []AppliedMigration
+	for rows.Next() {
+		var version string
+		var appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		sequence, name, ok := strings.Cut(version, "_")
+		n, err := strconv.ParseInt(sequence, 10, 64)
+		if !ok || err != nil {
+			continue
+		}
+		applied = append(applied, AppliedMigration{Version: n, Name: name, AppliedAt: time.Unix(appliedAt, 0)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(applied, func(i, j int) bool { return applied[i].Version < applied[j].Version })
+	return applied, nil
+}
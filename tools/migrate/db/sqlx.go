@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// createSQLXTable is the tracking table sqlx creates on its first run, with
+// %s for its name.
+const createSQLXTable = `
+CREATE TABLE IF NOT EXISTS %s (
+    version BIGINT PRIMARY KEY,
+    description TEXT NOT NULL,
+    installed_on TIMESTAMPTZ NOT NULL DEFAULT now(),
+    success BOOLEAN NOT NULL,
+    checksum BYTEA NOT NULL,
+    execution_time BIGINT NOT NULL
+)`
+
+func sqlxEnsureTable(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(createSQLXTable, QuotedMigrationsTable()))
+	return err
+}
+
+// sqlxRecordApplied records the migration as sqlx would: the description
+// is the name with spaces for underscores. A row left by a failed attempt
+// is overwritten.
+func sqlxRecordApplied(ctx context.Context, tx *sql.Tx, version int64, name string, checksum []byte, executionTime time.Duration) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO `+QuotedMigrationsTable()+` (version, description, installed_on, success, checksum, execution_time)
+		VALUES ($1, $2, NOW(), TRUE, $3, $4)
+		ON CONFLICT (version) DO UPDATE
+		SET description = EXCLUDED.description, installed_on = EXCLUDED.installed_on,
+		    success = TRUE, checksum = EXCLUDED.checksum, execution_time = EXCLUDED.execution_time`,
+		version, strings.ReplaceAll(name, "_", " "), checksum, executionTime.Nanoseconds())
+	return err
+}
+
+func sqlxRecordRolledBack(ctx context.Context, tx *sql.Tx, version int64) (sql.Result, error) {
+	return tx.ExecContext(ctx, "DELETE FROM "+QuotedMigrationsTable()+" WHERE version = $1", version)
+}
+
+func sqlxRecordRenamed(ctx context.Context, tx *sql.Tx, version int64, name string) error {
+	_, err := tx.ExecContext(ctx, "UPDATE "+QuotedMigrationsTable()+" SET description = $1 WHERE version = $2",
+		strings.ReplaceAll(name, "_", " "), version)
+	return err
+}
+
+func sqlxClearRecords(ctx context.Context, tx *sql.Tx) (sql.Result, error) {
+	return tx.ExecContext(ctx, "DELETE FROM "+QuotedMigrationsTable())
+}
+
+// sqlxApplied reads the rows of successful migrations, oldest first.
+func sqlxApplied(ctx context.Context, q Querier) ([]AppliedMigration, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT version, description, installed_on, execution_time, checksum FROM "+QuotedMigrationsTable()+" WHERE success ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applied []AppliedMigration
+	for rows.Next() {
+		var m AppliedMigration
+		var description string
+		var executionTime int64
+		if err := rows.Scan(&m.Version, &description, &m.AppliedAt, &executionTime, &m.Checksum); err != nil {
+			return nil, err
+		}
+		// sqlx records the name with spaces for underscores.
+		m.Name = strings.ReplaceAll(description, " ", "_")
+		m.ExecutionTime = time.Duration(executionTime)
+		applied = append(applied, m)
+	}
+	return applied, rows.Err()
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultMigrationsTable is the tracking table used when MIGRATE_TABLE_NAME
+// is not set: the one the SeaORM migrator keeps.
+const DefaultMigrationsTable = "seaql_migrations"
+
+// sqlxMigrationsTable is the table sqlx records each migration in, with its
+// checksum and execution time.
+const sqlxMigrationsTable = "_sqlx_migrations"
+
+// schemaMigrationsTable is the table golang-migrate records its single
+// version in.
+const schemaMigrationsTable = "schema_migrations"
+
+// layout is the set of columns a tracking table has.
+type layout int
+
+const (
+	// seaqlLayout is SeaORM's: version, the migration's full name, and
+	// applied_at, in Unix seconds.
+	seaqlLayout layout = iota
+	// sqlxLayout is sqlx's: version, description, installed_on, success,
+	// checksum and execution_time.
+	sqlxLayout
+	// schemaMigrationsLayout is golang-migrate's: one version and whether
+	// it is dirty.
+	schemaMigrationsLayout
+)
+
+// tableLayout returns the layout of MigrationsTable, told by its name:
+// _sqlx_migrations and schema_migrations are the tables of sqlx and
+// golang-migrate, any other name is the SeaORM migrator's.
+func tableLayout() layout {
+	name := MigrationsTable()
+	name = name[strings.LastIndex(name, ".")+1:]
+	switch name {
+	case sqlxMigrationsTable:
+		return sqlxLayout
+	case schemaMigrationsTable:
+		return schemaMigrationsLayout
+	default:
+		return seaqlLayout
+	}
+}
+
+// Getenv looks up MIGRATE_TABLE_NAME. The migrate command points it at its
+// own lookup so that the name can come from a .env file.
+var Getenv = os.Getenv
+
+// tableName matches a table name, optionally qualified by its schema.
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)?$`)
+
+// MigrationsTable returns the name of the tracking table: MIGRATE_TABLE_NAME
+// when set, otherwise DefaultMigrationsTable.
+func MigrationsTable() string {
+	if name := strings.TrimSpace(Getenv("MIGRATE_TABLE_NAME")); name != "" {
+		return name
+	}
+	return DefaultMigrationsTable
+}
+
+// CheckMigrationsTable reports an error if MIGRATE_TABLE_NAME is not a
+// plain table name, such as migrations or public.migrations.
+func CheckMigrationsTable() error {
+	if name := MigrationsTable(); !tableName.MatchString(name) {
+		return fmt.Errorf("invalid MIGRATE_TABLE_NAME %q (expected a table name such as migrations or public.migrations)", name)
+	}
+	return nil
+}
+
+// QuotedMigrationsTable returns MigrationsTable quoted for use in SQL.
+func QuotedMigrationsTable() string {
+	return pgx.Identifier(strings.Split(MigrationsTable(), ".")).Sanitize()
+}
+
+// recordsSuffix names the table beside seaql_migrations that the SQL file
+// migrations are recorded in.
+const recordsSuffix = "_sql"
+
+// RecordsTable returns the table the SQL file migrations applied by this
+// tool are recorded in. For sqlx and golang-migrate, whose migrators read
+// the same .sql files, it is MigrationsTable. The SeaORM migrator fails on
+// a row of MigrationsTable it has no Rust migration for, so for its layout
+// the rows are kept in a table of the same columns named after it with
+// _sql appended, such as seaql_migrations_sql.
+func RecordsTable() string {
+	if tableLayout() != seaqlLayout {
+		return MigrationsTable()
+	}
+	return MigrationsTable() + recordsSuffix
+}
+
+// QuotedRecordsTable returns RecordsTable quoted for use in SQL.
+func QuotedRecordsTable() string {
+	return pgx.Identifier(strings.Split(RecordsTable(), ".")).Sanitize()
+}
@@ -0,0 +1,36 @@
+package db
+
+import "testing"
+
+func TestTableLayout(t *testing.T) {
+	defer func(getenv func(string) string) { Getenv = getenv }(Getenv)
+
+	for name, want := range map[string]layout{
+		"":                        seaqlLayout,
+		"seaql_migrations":        seaqlLayout,
+		"legacy.migrations":       seaqlLayout,
+		"_sqlx_migrations":        sqlxLayout,
+		"public._sqlx_migrations": sqlxLayout,
+		"schema_migrations":       schemaMigrationsLayout,
+	} {
+		Getenv = func(string) string { return name }
+		if got := tableLayout(); got != want {
+			t.Errorf("MIGRATE_TABLE_NAME=%q: tableLayout() = %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestRecordsTable(t *testing.T) {
+	defer func(getenv func(string) string) { Getenv = getenv }(Getenv)
+
+	for name, want := range map[string]string{
+		"":                  "seaql_migrations_sql",
+		"legacy.migrations": "legacy.migrations_sql",
+		"_sqlx_migrations":  "_sqlx_migrations",
+	} {
+		Getenv = func(string) string { return name }
+		if got := RecordsTable(); got != want {
+			t.Errorf("MIGRATE_TABLE_NAME=%q: RecordsTable() = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/diagnostics"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+)
+
+// runDiagnoseSlow prints the migrations that took longer than --threshold
+// to apply, slowest first, read from the tracking table or, with
+// --log-file, from the saved output of a migration run.
+func runDiagnoseSlow(cfg Config) int {
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	var reporter *diagnostics.SlowMigrationReporter
+	if cfg.LogFile != "" {
+		f, err := os.Open(cfg.LogFile)
+		if err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		defer f.Close()
+		reporter = diagnostics.NewLogReporter(f, cfg.Threshold)
+	} else {
+		conn, err := pg.Open(ctx, cfg.DatabaseURL)
+		if err != nil {
+			printer.Error("Error: connect to database: %v", err)
+			return 1
+		}
+		defer conn.Close()
+		reporter = diagnostics.NewDatabaseReporter(conn, cfg.Threshold)
+	}
+
+	slow, err := reporter.Slow(ctx)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if len(slow) == 0 {
+		printer.Success("No migration took longer than %s", cfg.Threshold)
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MIGRATION\tAPPLIED AT\tDURATION")
+	for _, m := range slow {
+		appliedAt := "-"
+		if m.AppliedAt != nil {
+			appliedAt = m.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", m.Name, appliedAt, m.Duration.Round(time.Millisecond))
+	}
+	w.Flush()
+	return 0
+}
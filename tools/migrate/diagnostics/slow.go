@@ -0,0 +1,127 @@
+// Package diagnostics finds the migrations that slowed a migration run
+// down, from the tracking table or from a saved log of the run.
+package diagnostics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/output"
+)
+
+// Migration is a migration and how long it took to apply. AppliedAt is nil
+// when the source does not record when it ran.
+type Migration struct {
+	Name      string
+	AppliedAt *time.Time
+	Duration  time.Duration
+}
+
+// Source lists the applied migrations with their execution times.
+type Source interface {
+	Migrations(ctx context.Context) ([]Migration, error)
+}
+
+// SlowMigrationReporter reports the migrations in Source that took longer
+// than Threshold to apply.
+type SlowMigrationReporter struct {
+	Source    Source
+	Threshold time.Duration
+}
+
+// NewDatabaseReporter returns a reporter that reads execution times from the
+// tracking table of conn.
+func NewDatabaseReporter(conn *sql.DB, threshold time.Duration) *SlowMigrationReporter {
+	return &SlowMigrationReporter{Source: databaseSource{conn}, Threshold: threshold}
+}
+
+// NewLogReporter returns a reporter that reads execution times from r, the
+// saved output of a migration run.
+func NewLogReporter(r io.Reader, threshold time.Duration) *SlowMigrationReporter {
+	return &SlowMigrationReporter{Source: logSource{r}, Threshold: threshold}
+}
+
+// Slow returns the migrations slower than the threshold, slowest first.
+func (r *SlowMigrationReporter) Slow(ctx context.Context) ([]Migration, error) {
+	all, err := r.Source.Migrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var slow []Migration
+	for _, m := range all {
+		if m.Duration > r.Threshold {
+			slow = append(slow, m)
+		}
+	}
+	sort.SliceStable(slow, func(i, j int) bool {
+		if slow[i].Duration != slow[j].Duration {
+			return slow[i].Duration > slow[j].Duration
+		}
+		return slow[i].Name < slow[j].Name
+	})
+	return slow, nil
+}
+
+// databaseSource reads the execution time db.RecordsTable records for every
+// successfully applied migration, which _sqlx_migrations does and the
+// SeaORM layout does not.
+type databaseSource struct {
+	conn *sql.DB
+}
+
+func (s databaseSource) Migrations(ctx context.Context) ([]Migration, error) {
+	table := db.QuotedRecordsTable()
+	var exists bool
+	if err := s.conn.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("database has no %s table; use a saved log of the run instead", db.RecordsTable())
+	}
+
+	applied, err := db.Applied(ctx, s.conn)
+	if err != nil {
+		return nil, fmt.Errorf("read execution times: %w", err)
+	}
+
+	var list []Migration
+	for i, m := range applied {
+		if m.ExecutionTime == 0 {
+			continue
+		}
+		list = append(list, Migration{
+			Name:      fmt.Sprintf("%06d_%s", m.Version, m.Name),
+			AppliedAt: &applied[i].AppliedAt,
+			Duration:  m.ExecutionTime,
+		})
+	}
+	if len(list) == 0 && len(applied) > 0 {
+		return nil, fmt.Errorf("%s records no execution times; use a saved log of the run instead", db.RecordsTable())
+	}
+	return list, nil
+}
+
+// logSource picks the "Migration ... applied in ..." lines the migration
+// binary prints out of a saved log.
+type logSource struct {
+	r io.Reader
+}
+
+func (s logSource) Migrations(context.Context) ([]Migration, error) {
+	timings := output.NewTimingParser(s.r)
+	if _, err := io.Copy(io.Discard, timings); err != nil {
+		return nil, fmt.Errorf("read log: %w", err)
+	}
+
+	var list []Migration
+	for name, d := range timings.Timings() {
+		list = append(list, Migration{Name: name, Duration: d})
+	}
+	return list, nil
+}
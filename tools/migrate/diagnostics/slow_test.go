@@ -0,0 +1,75 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeSource struct {
+	migrations []Migration
+	err        error
+}
+
+func (s fakeSource) Migrations(context.Context) ([]Migration, error) {
+	return s.migrations, s.err
+}
+
+func TestSlowFiltersAndSorts(t *testing.T) {
+	r := &SlowMigrationReporter{
+		Source: fakeSource{migrations: []Migration{
+			{Name: "000001_create_users", Duration: 2 * time.Second},
+			{Name: "000002_backfill_orders", Duration: 12 * time.Second},
+			{Name: "000003_index_orders", Duration: 5 * time.Second},
+			{Name: "000004_add_fees", Duration: 8 * time.Second},
+			{Name: "000005_add_limits", Duration: 8 * time.Second},
+		}},
+		Threshold: 5 * time.Second,
+	}
+	slow, err := r.Slow(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, m := range slow {
+		names = append(names, m.Name)
+	}
+	want := []string{"000002_backfill_orders", "000004_add_fees", "000005_add_limits"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Slow() = %v, want %v", names, want)
+	}
+}
+
+func TestSlowReturnsSourceError(t *testing.T) {
+	sourceErr := errors.New("connection refused")
+	r := &SlowMigrationReporter{Source: fakeSource{err: sourceErr}}
+	if _, err := r.Slow(context.Background()); !errors.Is(err, sourceErr) {
+		t.Errorf("Slow() error = %v, want %v", err, sourceErr)
+	}
+}
+
+func TestLogReporter(t *testing.T) {
+	log := strings.Join([]string{
+		"Applying migration 'm20240101_000001_create_wallets_table'",
+		"Migration m20240101_000001_create_wallets_table applied in 6.5s",
+		"Applying migration 'm20240102_000001_create_orders_table'",
+		"Migration 'm20240102_000001_create_orders_table' applied in 350ms",
+		"Migration m20240103_000001_backfill applied in 90.0s",
+	}, "\n")
+
+	slow, err := NewLogReporter(strings.NewReader(log), time.Second).Slow(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Migration{
+		{Name: "m20240103_000001_backfill", Duration: 90 * time.Second},
+		{Name: "m20240101_000001_create_wallets_table", Duration: 6500 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(slow, want) {
+		t.Errorf("Slow() = %+v, want %+v", slow, want)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/crypto-bot/tools/migrate/vcs"
+)
+
+// runDiff prints the migrations the --pr pull request adds compared with
+// --base-branch, read from the GitHub API with GITHUB_TOKEN, up migrations
+// first, each under a header naming the pull request, so that they can be
+// reviewed or fed to psql in a review environment. Existing migrations the
+// pull request changes are only warned about.
+func runDiff(cfg Config) int {
+	pr, err := vcs.ParsePullRequestURL(cfg.PR)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	dir, err := repoRelative(cfg.ProjectRoot, cfg.SQLDir())
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	client := &vcs.GitHubClient{Token: getenv("GITHUB_TOKEN"), Client: &http.Client{}}
+	diff, err := client.PRDiff(ctx, pr, cfg.BaseBranch, dir)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	for _, f := range diff.Files {
+		if f.Status != "added" {
+			printer.Warn("Warning: PR #%d changes the existing migration %s (%s)", pr.Number, f.Path, f.Status)
+		}
+	}
+	added := diff.Added()
+	if len(added) == 0 {
+		printer.Info("PR #%d adds no migrations to %s compared with %s", pr.Number, dir, cfg.BaseBranch)
+		return 0
+	}
+
+	write := func(w io.Writer) error {
+		for _, down := range []bool{false, true} {
+			for _, f := range added {
+				if strings.HasSuffix(f.Path, ".down.sql") != down {
+					continue
+				}
+				content := f.Content
+				if !strings.HasSuffix(content, "\n") {
+					content += "\n"
+				}
+				if _, err := fmt.Fprintf(w, "-- Added in PR #%d: %s\n%s\n", pr.Number, f.Path, content); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if cfg.Output == "" {
+		err = write(os.Stdout)
+	} else {
+		err = writeOutput(cfg.Output, write)
+	}
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if cfg.Output != "" {
+		printer.Success("%d migration file(s) added in PR #%d written to %s", len(added), pr.Number, cfg.Output)
+	}
+	return 0
+}
+
+// repoRelative returns dir as a slash-separated path relative to the
+// repository root, as the GitHub API names files.
+func repoRelative(root, dir string) (string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absRoot, absDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("migration directory %s is outside the repository %s", dir, root)
+	}
+	return filepath.ToSlash(rel), nil
+}
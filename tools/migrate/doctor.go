@@ -0,0 +1,80 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/doctor"
+)
+
+// minimumGoVersion is the go directive of this tool's go.mod.
+const minimumGoVersion = "1.21"
+
+// runDoctor runs every doctor check in turn, printing ✓ or ✗ and, for a
+// failure, what to do about it. With --fix, a failed check that can repair
+// itself does so and is run again.
+func runDoctor(cfg Config) int {
+	checks := []doctor.Check{
+		doctor.GoVersionSufficient{Minimum: minimumGoVersion},
+		doctor.CargoInstalled{Bin: cargoBinName(cfg)},
+		doctor.EnvFileExists{
+			Path:    filepath.Join(cfg.ProjectRoot, ".env"),
+			Example: filepath.Join(cfg.ProjectRoot, ".env.example"),
+		},
+		doctor.MigrationDirExists{Dir: cfg.MigrationDir},
+		doctor.DatabaseReachable{Getenv: getenv, Validate: validateDatabaseURL, Timeout: 5 * time.Second},
+	}
+
+	ctx, cancel := cfg.commandContext()
+	defer cancel()
+
+	failed := 0
+	for _, check := range checks {
+		detail, err := check.Run(ctx)
+		if fixer, ok := check.(doctor.Fixer); ok && err != nil && cfg.Fix {
+			if fixErr := fixer.Fix(ctx); fixErr != nil {
+				printer.Warn("Could not fix %s: %v", check.Name(), fixErr)
+			} else {
+				// The fix may have created a .env file that later checks
+				// read from the environment.
+				if loadErr := loadEnv(cfg); loadErr != nil {
+					printer.Warn("Warning: failed to load .env: %v", loadErr)
+				}
+				applyFileConfig(cfg.File)
+				detail, err = check.Run(ctx)
+				if err == nil {
+					detail = "fixed, " + detail
+				}
+			}
+		}
+
+		if err == nil {
+			printer.Success("✓ %s (%s)", check.Name(), detail)
+			continue
+		}
+		failed++
+		printer.Error("✗ %s: %v", check.Name(), err)
+		if suggestion := doctor.Suggestion(err); suggestion != "" {
+			printer.Info("  → %s", suggestion)
+		}
+	}
+
+	if failed > 0 {
+		printer.Error("%d of %d checks failed", failed, len(checks))
+		return 1
+	}
+	printer.Success("All checks passed")
+	return 0
+}
+
+// cargoBinName returns the Cargo binary the checks look for: --cargo-bin,
+// CARGO_BIN or cargo.
+func cargoBinName(cfg Config) string {
+	if cfg.CargoBin != "" {
+		return cfg.CargoBin
+	}
+	if bin := getenv("CARGO_BIN"); bin != "" {
+		return bin
+	}
+	return "cargo"
+}
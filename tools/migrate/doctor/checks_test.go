@@ -0,0 +1,77 @@
+package doctor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version, minimum string
+		want             bool
+	}{
+		{"1.21", "1.21", true},
+		{"1.21.0", "1.21", true},
+		{"1.22.3", "1.21", true},
+		{"1.20.14", "1.21", false},
+		{"1.9", "1.21", false},
+		{"2.0", "1.21", true},
+		{"1.21rc2", "1.21", true},
+		{"devel +abc123", "1.21", true},
+	}
+	for _, tt := range tests {
+		if got := versionAtLeast(tt.version, tt.minimum); got != tt.want {
+			t.Errorf("versionAtLeast(%q, %q) = %v, want %v", tt.version, tt.minimum, got, tt.want)
+		}
+	}
+}
+
+func TestEnvFileExistsFix(t *testing.T) {
+	dir := t.TempDir()
+	check := EnvFileExists{Path: filepath.Join(dir, ".env"), Example: filepath.Join(dir, ".env.example")}
+	if err := os.WriteFile(check.Example, []byte("DATABASE_URL=postgres://localhost/db\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := check.Run(context.Background())
+	if err == nil || Suggestion(err) == "" {
+		t.Fatalf("Run() error = %v, want a failure with a suggestion", err)
+	}
+	if err := check.Fix(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := check.Run(context.Background()); err != nil {
+		t.Fatalf("Run() after Fix() = %v", err)
+	}
+	data, err := os.ReadFile(check.Path)
+	if err != nil || string(data) != "DATABASE_URL=postgres://localhost/db\n" {
+		t.Fatalf(".env = %q, %v; want a copy of .env.example", data, err)
+	}
+	if err := check.Fix(context.Background()); err == nil {
+		t.Error("Fix() overwrote an existing .env")
+	}
+}
+
+func TestMigrationDirExists(t *testing.T) {
+	dir := t.TempDir()
+	check := MigrationDirExists{Dir: dir}
+	if _, err := check.Run(context.Background()); err == nil {
+		t.Fatal("Run() succeeded without a Cargo.toml")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Cargo.toml"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := check.Run(context.Background()); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+}
+
+func TestDatabaseReachableWithoutURL(t *testing.T) {
+	t.Setenv("DATABASE_URL", "")
+	_, err := DatabaseReachable{}.Run(context.Background())
+	if err == nil || err.Error() != "DATABASE_URL is not set" {
+		t.Fatalf("Run() error = %v, want DATABASE_URL is not set", err)
+	}
+}
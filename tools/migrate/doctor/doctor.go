@@ -0,0 +1,85 @@
+// Package doctor diagnoses the setup problems that most often stop the
+// migration tool from working on a new machine.
+package doctor
+
+import (
+	"context"
+	"errors"
+)
+
+// Check is a single diagnosis. Run returns a short detail to show when the
+// check passes, such as the version found, or an error describing what is
+// wrong. A *Failure error carries a suggestion for fixing it.
+type Check interface {
+	Name() string
+	Run(ctx context.Context) (string, error)
+}
+
+// Fixer is implemented by checks that can repair what they diagnose.
+type Fixer interface {
+	Fix(ctx context.Context) error
+}
+
+// Failure is a failed check's problem and what to do about it.
+type Failure struct {
+	Problem    string
+	Suggestion string
+}
+
+func (f *Failure) Error() string {
+	return f.Problem
+}
+
+// Suggestion returns the suggestion carried by err, or an empty string.
+func Suggestion(err error) string {
+	var failure *Failure
+	if errors.As(err, &failure) {
+		return failure.Suggestion
+	}
+	return ""
+}
+
+// Status is whether a check passed.
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+// Result is the outcome of one check, for callers that report on checks
+// themselves rather than printing them as they run.
+type Result struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	// Message is the check's detail when it passes and the problem when it
+	// fails.
+	Message    string `json:"message"`
+	Suggestion string `json:"-"`
+}
+
+// RunChecks runs every check in turn, whether or not the ones before it
+// passed, and returns their results in the same order.
+func RunChecks(ctx context.Context, checks []Check) []Result {
+	results := make([]Result, len(checks))
+	for i, check := range checks {
+		detail, err := check.Run(ctx)
+		results[i] = Result{Name: check.Name(), Status: StatusOK, Message: detail}
+		if err != nil {
+			results[i].Status = StatusFail
+			results[i].Message = err.Error()
+			results[i].Suggestion = Suggestion(err)
+		}
+	}
+	return results
+}
+
+// Passed reports whether every result is ok.
+func Passed(results []Result) bool {
+	for _, r := range results {
+		if r.Status != StatusOK {
+			return false
+		}
+	}
+	return true
+}
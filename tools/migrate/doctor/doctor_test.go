@@ -0,0 +1,39 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fakeCheck struct {
+	name   string
+	detail string
+	err    error
+}
+
+func (c fakeCheck) Name() string                        { return c.name }
+func (c fakeCheck) Run(context.Context) (string, error) { return c.detail, c.err }
+
+func TestRunChecks(t *testing.T) {
+	results := RunChecks(context.Background(), []Check{
+		fakeCheck{name: "first", err: &Failure{Problem: "broken", Suggestion: "fix it"}},
+		fakeCheck{name: "second", detail: "fine"},
+		fakeCheck{name: "third", err: errors.New("plain error")},
+	})
+	want := []Result{
+		{Name: "first", Status: StatusFail, Message: "broken", Suggestion: "fix it"},
+		{Name: "second", Status: StatusOK, Message: "fine"},
+		{Name: "third", Status: StatusFail, Message: "plain error"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("RunChecks() = %+v, want %+v", results, want)
+	}
+	if Passed(results) {
+		t.Error("Passed() = true with failing checks")
+	}
+	if !Passed(results[1:2]) {
+		t.Error("Passed() = false with only passing checks")
+	}
+}
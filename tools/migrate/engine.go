@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/engine"
+	"github.com/crypto-bot/tools/migrate/runner"
+)
+
+// migrationEngine returns the engine named by MIGRATE_ENGINE, which the
+// configuration file's engine sets, or cargo.
+func migrationEngine(cfg Config) (string, error) {
+	name := getenv("MIGRATE_ENGINE")
+	if name == "" {
+		return engine.Names[0], nil
+	}
+	if !slices.Contains(engine.Names, name) {
+		return "", fmt.Errorf("unknown MIGRATE_ENGINE %q (expected %s)", name, strings.Join(engine.Names, ", "))
+	}
+	if name != "cargo" && cfg.Command == "status" && cfg.Format != "text" {
+		return "", fmt.Errorf("status --format %s reads the SeaORM migrator's output and needs the cargo engine", cfg.Format)
+	}
+	if name != "cargo" && cfg.Only != "" {
+		return "", fmt.Errorf("up --only applies the SQL file itself, recording it in the SeaORM migrator's table, and needs the cargo engine, not %s", name)
+	}
+	if name != "cargo" && cfg.ApplyMissingOnly {
+		return "", fmt.Errorf("up --apply-missing-only reads and writes the SeaORM migrator's table itself and needs the cargo engine, not %s", name)
+	}
+	if name != "cargo" && cfg.Command == "up" && cfg.Parallelism > 1 && cfg.ShardsFile == "" {
+		return "", fmt.Errorf("up --parallelism applies the SQL files itself, recording them in the SeaORM migrator's table, and needs the cargo engine, not %s", name)
+	}
+	return name, nil
+}
+
+// newMigrationRunner returns the runner for the configured engine and
+// run, with the CLI's streams and exec. Cargo is found as usual; the other
+// engines' CLIs are taken from PATH unless MIGRATE_ENGINE_BIN points
+// elsewhere.
+func newMigrationRunner(cfg Config, stdout, stderr io.Writer, exec runner.Exec) (*runner.MigrationRunner, error) {
+	bin := getenv("MIGRATE_ENGINE_BIN")
+	if cfg.Engine == "cargo" {
+		bin = cfg.CargoBin
+		if bin == "" {
+			var err error
+			if bin, err = findCargo(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	opts := runner.Options{
+		MigrationDir: cfg.MigrationDir,
+		SQLDir:       cfg.SQLDir(),
+		DatabaseURL:  cfg.DatabaseURL,
+		Engine:       cfg.Engine,
+		Bin:          bin,
+		DryRun:       cfg.DryRun,
+		ExtraArgs:    cfg.ExtraArgs,
+		Env:          environment.Environ(),
+		Stdin:        os.Stdin,
+		Stdout:       stdout,
+		Stderr:       stderr,
+		Exec:         exec,
+	}
+	// Only a table named explicitly is passed on, so that a migrator
+	// without --migration-table keeps working by default.
+	if getenv("MIGRATE_TABLE_NAME") != "" {
+		opts.MigrationsTable = db.MigrationsTable()
+	}
+	return runner.New(opts)
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+)
+
+// CargoRunner runs the SeaORM migration binary in the migration crate with
+// cargo run, which builds it first if necessary.
+type CargoRunner struct {
+	Bin  string
+	Exec Exec
+}
+
+func (r CargoRunner) Up(ctx context.Context, opts Options) error {
+	return r.run(ctx, "up", opts)
+}
+
+func (r CargoRunner) Down(ctx context.Context, opts Options) error {
+	return r.run(ctx, "down", opts)
+}
+
+func (r CargoRunner) Status(ctx context.Context, opts Options) error {
+	return r.run(ctx, "status", opts)
+}
+
+func (r CargoRunner) Fresh(ctx context.Context, opts Options) error {
+	return r.run(ctx, "fresh", opts)
+}
+
+func (r CargoRunner) Reset(ctx context.Context, opts Options) error {
+	return r.run(ctx, "reset", opts)
+}
+
+func (r CargoRunner) run(ctx context.Context, command string, opts Options) error {
+	return r.Exec(ctx, r.Command(command, opts))
+}
+
+// Command returns the invocation of the migration binary's command. The
+// database URL is passed in the environment, where the migrator reads it.
+func (r CargoRunner) Command(command string, opts Options) *exec.Cmd {
+	args := []string{"run", "--", command}
+	if opts.Steps > 0 {
+		args = append(args, "--num", strconv.Itoa(opts.Steps))
+	}
+	if opts.DryRun {
+		args = append(args, "--dry-run")
+	}
+	if opts.MigrationsTable != "" {
+		args = append(args, "--migration-table", opts.MigrationsTable)
+	}
+	args = append(args, opts.ExtraArgs...)
+
+	cmd := exec.Command(r.Bin, args...)
+	cmd.Dir = opts.Dir
+	cmd.Env = opts.environ("DATABASE_URL=" + opts.DatabaseURL)
+	return cmd
+}
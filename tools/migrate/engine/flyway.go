@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+)
+
+// FlywayRunner runs the Flyway CLI against the .sql files in Options.SQLDir,
+// which must follow Flyway's V<version>__<name>.sql naming. Flyway applies
+// every pending migration at once, so Options.Steps is not supported, and
+// rolling back (undo) needs a Flyway edition that has it.
+type FlywayRunner struct {
+	Bin  string
+	Exec Exec
+}
+
+func (r FlywayRunner) Up(ctx context.Context, opts Options) error {
+	if opts.Steps > 0 {
+		return errors.New("flyway applies every pending migration; --steps and --target need the cargo or golang-migrate engine")
+	}
+	return r.run(ctx, opts, "migrate")
+}
+
+func (r FlywayRunner) Down(ctx context.Context, opts Options) error {
+	if opts.Steps > 1 {
+		return errors.New("flyway undoes one migration at a time; --steps and --target need the cargo or golang-migrate engine")
+	}
+	return r.run(ctx, opts, "undo")
+}
+
+func (r FlywayRunner) Status(ctx context.Context, opts Options) error {
+	return r.run(ctx, opts, "info")
+}
+
+func (r FlywayRunner) Fresh(ctx context.Context, opts Options) error {
+	if err := r.run(ctx, opts, "clean", "-cleanDisabled=false"); err != nil {
+		return err
+	}
+	return r.run(ctx, opts, "migrate")
+}
+
+// Reset drops everything Flyway manages, which is the closest it comes to
+// rolling back every migration.
+func (r FlywayRunner) Reset(ctx context.Context, opts Options) error {
+	return r.run(ctx, opts, "clean", "-cleanDisabled=false")
+}
+
+func (r FlywayRunner) run(ctx context.Context, opts Options, command string, args ...string) error {
+	cmd, err := r.Command(opts, command, args...)
+	if err != nil {
+		return err
+	}
+	return r.Exec(ctx, cmd)
+}
+
+// Command returns the invocation of Flyway's command. The connection is
+// passed as FLYWAY_URL, FLYWAY_USER and FLYWAY_PASSWORD rather than on the
+// command line, where the password would be visible to other users.
+func (r FlywayRunner) Command(opts Options, command string, args ...string) (*exec.Cmd, error) {
+	if opts.DryRun {
+		return nil, errors.New("flyway has no dry run here; --dry-run needs the cargo engine")
+	}
+	env, err := flywayEnv(opts.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	cmdArgs := []string{"-locations=filesystem:" + opts.SQLDir, command}
+	cmdArgs = append(cmdArgs, args...)
+	cmdArgs = append(cmdArgs, opts.ExtraArgs...)
+
+	cmd := exec.Command(r.Bin, cmdArgs...)
+	cmd.Dir = opts.Dir
+	cmd.Env = opts.environ(env...)
+	return cmd, nil
+}
+
+// flywayEnv turns a postgres:// URL into Flyway's JDBC URL and credentials.
+func flywayEnv(databaseURL string) ([]string, error) {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("DATABASE_URL is not a valid URL: %w", err)
+	}
+	jdbc := url.URL{Scheme: "jdbc:postgresql", Host: u.Host, Path: u.Path, RawQuery: u.RawQuery}
+	env := []string{"FLYWAY_URL=" + jdbc.String()}
+	if u.User != nil {
+		env = append(env, "FLYWAY_USER="+u.User.Username())
+		if password, ok := u.User.Password(); ok {
+			env = append(env, "FLYWAY_PASSWORD="+password)
+		}
+	}
+	return env, nil
+}
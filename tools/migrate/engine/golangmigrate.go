@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strconv"
+)
+
+// GolangMigrateRunner runs the golang-migrate CLI against the .sql files in
+// Options.SQLDir, which follow its NNNNNN_name.up.sql and .down.sql naming.
+type GolangMigrateRunner struct {
+	Bin  string
+	Exec Exec
+}
+
+func (r GolangMigrateRunner) Up(ctx context.Context, opts Options) error {
+	var args []string
+	if opts.Steps > 0 {
+		args = []string{strconv.Itoa(opts.Steps)}
+	}
+	return r.run(ctx, opts, "up", args...)
+}
+
+// Down rolls back opts.Steps migrations, or one, like the migrator does.
+// golang-migrate's own down without a count rolls back everything.
+func (r GolangMigrateRunner) Down(ctx context.Context, opts Options) error {
+	steps := opts.Steps
+	if steps == 0 {
+		steps = 1
+	}
+	return r.run(ctx, opts, "down", strconv.Itoa(steps))
+}
+
+// Status prints the current schema version, which is all golang-migrate
+// records.
+func (r GolangMigrateRunner) Status(ctx context.Context, opts Options) error {
+	return r.run(ctx, opts, "version")
+}
+
+func (r GolangMigrateRunner) Fresh(ctx context.Context, opts Options) error {
+	if err := r.run(ctx, opts, "drop", "-f"); err != nil {
+		return err
+	}
+	return r.run(ctx, opts, "up")
+}
+
+func (r GolangMigrateRunner) Reset(ctx context.Context, opts Options) error {
+	return r.run(ctx, opts, "down", "-all")
+}
+
+func (r GolangMigrateRunner) run(ctx context.Context, opts Options, command string, args ...string) error {
+	cmd, err := r.Command(opts, command, args...)
+	if err != nil {
+		return err
+	}
+	return r.Exec(ctx, cmd)
+}
+
+// Command returns the invocation of golang-migrate's command. Its options,
+// including opts.ExtraArgs, must come before the command.
+func (r GolangMigrateRunner) Command(opts Options, command string, args ...string) (*exec.Cmd, error) {
+	if opts.DryRun {
+		return nil, errors.New("golang-migrate has no dry run; --dry-run needs the cargo engine")
+	}
+	cmdArgs := []string{"-path", opts.SQLDir, "-database", opts.DatabaseURL}
+	cmdArgs = append(cmdArgs, opts.ExtraArgs...)
+	cmdArgs = append(cmdArgs, command)
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := exec.Command(r.Bin, cmdArgs...)
+	cmd.Dir = opts.Dir
+	cmd.Env = opts.environ()
+	return cmd, nil
+}
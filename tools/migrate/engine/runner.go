@@ -0,0 +1,101 @@
+// Package engine runs migrations with one of several migration tools: the
+// project's SeaORM migrator through Cargo, golang-migrate, or Flyway.
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Names lists the supported engines; the first is the default.
+var Names = []string{"cargo", "golang-migrate", "flyway"}
+
+// Options describe a migration run.
+type Options struct {
+	// Dir is the migration crate, and SQLDir the directory of .sql
+	// migration files in it that file-based engines read.
+	Dir    string
+	SQLDir string
+
+	DatabaseURL string
+	// Steps limits how many migrations Up and Down apply; 0 means all
+	// pending migrations for Up and one for Down.
+	Steps  int
+	DryRun bool
+	// MigrationsTable is the tracking table the migrator is told to use;
+	// empty leaves it at the migrator's default.
+	MigrationsTable string
+	// ExtraArgs are passed to the engine's CLI verbatim.
+	ExtraArgs []string
+	// Env is the environment the engine's CLI runs in; nil means the
+	// process environment.
+	Env []string
+}
+
+// environ returns the environment a command runs in, with extra added.
+func (o Options) environ(extra ...string) []string {
+	env := o.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	return append(append([]string(nil), env...), extra...)
+}
+
+// Exec runs a command a Runner has prepared. The caller supplies it so that
+// output relaying, signal handling and timeouts are the same whichever
+// engine runs; it sets the command's standard streams.
+type Exec func(ctx context.Context, cmd *exec.Cmd) error
+
+// Runner performs migrations with a particular engine, running its CLI
+// through an Exec. An operation the engine cannot perform, or an option it
+// does not support, is an error returned before anything runs.
+type Runner interface {
+	Up(ctx context.Context, opts Options) error
+	Down(ctx context.Context, opts Options) error
+	Status(ctx context.Context, opts Options) error
+	// Fresh drops everything in the database and applies every migration.
+	Fresh(ctx context.Context, opts Options) error
+	// Reset rolls back every applied migration.
+	Reset(ctx context.Context, opts Options) error
+}
+
+// New returns the Runner for the engine called name, running bin through
+// exec. An empty bin uses the engine's CLI from PATH.
+func New(name, bin string, exec Exec) (Runner, error) {
+	switch name {
+	case "cargo":
+		return CargoRunner{Bin: orDefault(bin, "cargo"), Exec: exec}, nil
+	case "golang-migrate":
+		return GolangMigrateRunner{Bin: orDefault(bin, "migrate"), Exec: exec}, nil
+	case "flyway":
+		return FlywayRunner{Bin: orDefault(bin, "flyway"), Exec: exec}, nil
+	}
+	return nil, fmt.Errorf("unknown migration engine %q (expected %s)", name, strings.Join(Names, ", "))
+}
+
+// Run performs the operation called command with r.
+func Run(ctx context.Context, r Runner, command string, opts Options) error {
+	switch command {
+	case "up":
+		return r.Up(ctx, opts)
+	case "down":
+		return r.Down(ctx, opts)
+	case "status":
+		return r.Status(ctx, opts)
+	case "fresh":
+		return r.Fresh(ctx, opts)
+	case "reset":
+		return r.Reset(ctx, opts)
+	}
+	return fmt.Errorf("the migration engine cannot run %s", command)
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"context"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// recorder is an Exec that records the commands it is given instead of
+// running them.
+type recorder struct {
+	cmds []*exec.Cmd
+}
+
+func (r *recorder) exec(_ context.Context, cmd *exec.Cmd) error {
+	r.cmds = append(r.cmds, cmd)
+	return nil
+}
+
+func (r *recorder) args() [][]string {
+	args := make([][]string, len(r.cmds))
+	for i, cmd := range r.cmds {
+		args[i] = cmd.Args[1:]
+	}
+	return args
+}
+
+func hasEnv(cmd *exec.Cmd, kv string) bool {
+	return slices.Contains(cmd.Env, kv)
+}
+
+const testURL = "postgres://app:secret@db:5432/wallets?sslmode=disable"
+
+func TestCargoRunner(t *testing.T) {
+	rec := &recorder{}
+	runner, err := New("cargo", "/usr/bin/cargo", rec.exec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Dir: "/repo/migration", DatabaseURL: testURL, Steps: 2, DryRun: true, ExtraArgs: []string{"-v"}}
+	if err := Run(context.Background(), runner, "down", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := rec.cmds[0]
+	want := []string{"run", "--", "down", "--num", "2", "--dry-run", "-v"}
+	if !slices.Equal(cmd.Args[1:], want) {
+		t.Errorf("args = %q, want %q", cmd.Args[1:], want)
+	}
+	if cmd.Dir != opts.Dir || !hasEnv(cmd, "DATABASE_URL="+testURL) {
+		t.Errorf("Dir = %q, DATABASE_URL in env = %v", cmd.Dir, hasEnv(cmd, "DATABASE_URL="+testURL))
+	}
+}
+
+func TestCargoRunnerMigrationsTable(t *testing.T) {
+	rec := &recorder{}
+	runner, _ := New("cargo", "/usr/bin/cargo", rec.exec)
+	opts := Options{Dir: "/repo/migration", DatabaseURL: testURL, MigrationsTable: "schema_migrations"}
+	if err := Run(context.Background(), runner, "up", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"run", "--", "up", "--migration-table", "schema_migrations"}
+	if got := rec.args()[0]; !slices.Equal(got, want) {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+}
+
+func TestGolangMigrateRunner(t *testing.T) {
+	cli := func(args ...string) []string {
+		return append([]string{"-path", "/repo/migration/migrations", "-database", testURL}, args...)
+	}
+	tests := []struct {
+		command string
+		steps   int
+		want    [][]string
+	}{
+		{"up", 0, [][]string{cli("up")}},
+		{"up", 3, [][]string{cli("up", "3")}},
+		{"down", 0, [][]string{cli("down", "1")}},
+		{"status", 0, [][]string{cli("version")}},
+		{"fresh", 0, [][]string{cli("drop", "-f"), cli("up")}},
+		{"reset", 0, [][]string{cli("down", "-all")}},
+	}
+	for _, tt := range tests {
+		rec := &recorder{}
+		runner, _ := New("golang-migrate", "", rec.exec)
+		opts := Options{Dir: "/repo/migration", SQLDir: "/repo/migration/migrations", DatabaseURL: testURL, Steps: tt.steps}
+		if err := Run(context.Background(), runner, tt.command, opts); err != nil {
+			t.Fatalf("%s: %v", tt.command, err)
+		}
+		if got := rec.args(); !slices.EqualFunc(got, tt.want, slices.Equal[[]string]) {
+			t.Errorf("%s --steps %d ran %q, want %q", tt.command, tt.steps, got, tt.want)
+		}
+		if rec.cmds[0].Path != "migrate" && !strings.HasSuffix(rec.cmds[0].Path, "/migrate") {
+			t.Errorf("%s ran %s, want migrate", tt.command, rec.cmds[0].Path)
+		}
+	}
+
+	runner, _ := New("golang-migrate", "", (&recorder{}).exec)
+	if err := runner.Up(context.Background(), Options{DryRun: true}); err == nil {
+		t.Error("Up with DryRun succeeded")
+	}
+}
+
+func TestFlywayRunner(t *testing.T) {
+	rec := &recorder{}
+	runner, _ := New("flyway", "", rec.exec)
+	opts := Options{SQLDir: "/repo/sql", DatabaseURL: testURL}
+	if err := runner.Up(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := rec.cmds[0]
+	if want := []string{"-locations=filesystem:/repo/sql", "migrate"}; !slices.Equal(cmd.Args[1:], want) {
+		t.Errorf("args = %q, want %q", cmd.Args[1:], want)
+	}
+	for _, kv := range []string{"FLYWAY_URL=jdbc:postgresql://db:5432/wallets?sslmode=disable", "FLYWAY_USER=app", "FLYWAY_PASSWORD=secret"} {
+		if !hasEnv(cmd, kv) {
+			t.Errorf("env is missing %s", kv)
+		}
+	}
+	for _, arg := range cmd.Args {
+		if strings.Contains(arg, "secret") {
+			t.Errorf("password passed on the command line: %q", arg)
+		}
+	}
+
+	opts.Steps = 1
+	if err := runner.Up(context.Background(), opts); err == nil {
+		t.Error("Up with Steps succeeded")
+	}
+}
+
+func TestNewUnknownEngine(t *testing.T) {
+	if _, err := New("liquibase", "", nil); err == nil {
+		t.Error("New(liquibase) succeeded")
+	}
+}
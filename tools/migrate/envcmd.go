@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/crypto-bot/tools/migrate/config"
+)
+
+// runEnv prints the value every variable the tool reads resolves to once
+// the process environment, the .env files and the configuration file are
+// merged, with credentials masked. With --all it prints the whole
+// environment Cargo runs in instead. Secret stores are not asked, so it
+// works without DATABASE_URL and can show why it is not being loaded.
+func runEnv(cfg Config) int {
+	if cfg.All {
+		for _, kv := range sortedEnviron(environment.Environ()) {
+			key, value, _ := strings.Cut(kv, "=")
+			fmt.Printf("%s=%s\n", key, maskEnvValue(key, value))
+		}
+		return 0
+	}
+
+	keys := append([]string(nil), toolVariables...)
+	known := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		known[key] = true
+	}
+	// Pick up MIGRATE_* variables the tool does not read too, which are
+	// usually misspellings of ones it does.
+	for _, kv := range environment.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(key, "MIGRATE_") && !known[key] {
+			keys = append(keys, key)
+			known[key] = true
+		}
+	}
+	sort.Strings(keys)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VARIABLE\tVALUE")
+	for _, key := range keys {
+		value := "-"
+		if v := getenv(key); v != "" {
+			value = maskEnvValue(key, v)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", key, value)
+	}
+	w.Flush()
+	return 0
+}
+
+// sortedEnviron returns environ sorted by key, keeping only the last value
+// of a key that appears more than once, as exec.Cmd does.
+func sortedEnviron(environ []string) []string {
+	byKey := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		key, _, _ := strings.Cut(kv, "=")
+		byKey[key] = kv
+	}
+	sorted := make([]string, 0, len(byKey))
+	for _, kv := range byKey {
+		sorted = append(sorted, kv)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// maskEnvValue hides the credential in the value of the variable called key:
+// all of it for credentials, the path of a webhook URL, and the password of
+// a connection string.
+func maskEnvValue(key, value string) string {
+	switch {
+	case config.IsSensitive(key):
+		return "***"
+	case key == "MIGRATE_WEBHOOK_URL":
+		return maskWebhookURL(value)
+	default:
+		return maskDatabaseURLs(value)
+	}
+}
+
+// runListEnvironments prints the environments of the configuration file,
+// which --env selects, with their database URLs masked.
+func runListEnvironments(cfg Config) int {
+	names := cfg.File.ListEnvironments()
+	if len(names) == 0 {
+		file := cfg.File.Path
+		if file == "" {
+			file = "migrate.yaml"
+		}
+		printer.Warn("No environments defined; add them under environments: in %s", file)
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ENVIRONMENT\tDATABASE_URL")
+	for _, name := range names {
+		env := cfg.File.Environments[name]
+		url := env.DatabaseURLSecret
+		if url == "" {
+			url = maskDatabaseURL(env.DatabaseURL)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", name, url)
+	}
+	w.Flush()
+	return 0
+}
@@ -0,0 +1,16 @@
+// Package exitcodes defines the exit codes of migrate status --exit-code,
+// which CI scripts branch on.
+package exitcodes
+
+const (
+	// ExitAllApplied means every migration has been applied.
+	ExitAllApplied = 0
+	// ExitPending means at least one migration has not been applied.
+	ExitPending = 1
+	// ExitTableMissing means the database has no migration tracking table
+	// yet, as on a fresh database.
+	ExitTableMissing = 2
+	// ExitError means the state could not be read, for example because
+	// the database is unreachable.
+	ExitError = 3
+)
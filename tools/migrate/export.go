@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/crypto-bot/tools/migrate/export"
+)
+
+// runExport writes every up migration to --output, or stdout, as a single
+// script for psql.
+func runExport(cfg Config) int {
+	exporter := export.NewFileExporter(cfg.SQLDir())
+
+	if cfg.Output == "" {
+		if err := exporter.Export(os.Stdout); err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		return 0
+	}
+
+	if err := writeOutput(cfg.Output, exporter.Export); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	printer.Success("Migrations exported to %s", cfg.Output)
+	return 0
+}
+
+// writeOutput writes path with write. It writes next to path first so that
+// a failure never leaves a partial file behind.
+func writeOutput(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = write(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+// Package export turns the migration files into a single SQL script that
+// can be applied with psql where Cargo is not available.
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// Exporter writes migrations as one SQL script.
+type Exporter interface {
+	Export(w io.Writer) error
+}
+
+// Section markers used by dbmate-style migrations that keep both directions
+// in one file.
+const (
+	upMarker   = "-- migrate:up"
+	downMarker = "-- migrate:down"
+)
+
+// FileExporter concatenates the up migrations in a directory in sequence
+// order, each wrapped in its own transaction and preceded by a comment
+// naming it. The script stops at the first error when run with
+// `psql -f`.
+type FileExporter struct {
+	Dir string
+}
+
+// NewFileExporter returns an Exporter for the migrations in dir.
+func NewFileExporter(dir string) *FileExporter {
+	return &FileExporter{Dir: dir}
+}
+
+func (e *FileExporter) Export(w io.Writer) error {
+	files, err := migrations.Scan(e.Dir)
+	if err != nil {
+		return err
+	}
+
+	var namer migrations.MigrationNamer
+	var b strings.Builder
+	b.WriteString("-- Generated by migrate export; apply with: psql \"$DATABASE_URL\" -f <file>\n")
+	b.WriteString("\\set ON_ERROR_STOP on\n")
+
+	for _, file := range files {
+		if file.UpPath == "" {
+			continue
+		}
+		data, err := os.ReadFile(file.UpPath)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&b, "\n-- Migration: %s\n", namer.Base(file.Sequence, file.Name))
+		b.WriteString("BEGIN;\n")
+		body := strings.TrimSpace(upSection(string(data)))
+		if body != "" {
+			b.WriteString(body + "\n")
+		}
+		b.WriteString("COMMIT;\n")
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// upSection returns the part of a migration between the up and down
+// markers, or all of it when it has no markers.
+func upSection(sql string) string {
+	if i := strings.Index(sql, upMarker); i >= 0 {
+		sql = sql[i+len(upMarker):]
+	}
+	if i := strings.Index(sql, downMarker); i >= 0 {
+		sql = sql[:i]
+	}
+	return sql
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/secrets"
+)
+
+// gcpTimeout bounds each request to Secret Manager and the credential
+// endpoints.
+const gcpTimeout = 5 * time.Second
+
+// resolveGCPSecret sets DATABASE_URL from the latest version of the Secret
+// Manager secret GCP_SECRET_NAME in GCP_PROJECT_ID when both are set. As
+// with Vault, an explicit DATABASE_URL wins.
+func resolveGCPSecret() error {
+	project := getenv("GCP_PROJECT_ID")
+	secret := getenv("GCP_SECRET_NAME")
+	if project == "" || secret == "" || getenv("DATABASE_URL") != "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*gcpTimeout)
+	defer cancel()
+
+	client := &secrets.GCPSecretManagerClient{Client: &http.Client{Timeout: gcpTimeout}, Getenv: getenv}
+	url, err := client.AccessLatest(ctx, project, secret)
+	if err != nil {
+		return fmt.Errorf("read DATABASE_URL from gcp secret manager: %w", err)
+	}
+	environment.Set("DATABASE_URL", strings.TrimSpace(url))
+	return nil
+}
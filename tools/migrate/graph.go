@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/crypto-bot/tools/migrate/graph"
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// runGraph prints the dependencies declared with `-- depends:` comments as
+// a Graphviz DOT digraph, failing if they form a cycle.
+func runGraph(cfg Config) int {
+	var namer migrations.MigrationNamer
+
+	files, err := migrations.Scan(cfg.SQLDir())
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	sequences := make(map[string]int, len(files))
+	g := graph.New[string]()
+	for _, file := range files {
+		name := namer.Base(file.Sequence, file.Name)
+		sequences[name] = file.Sequence
+		g.AddNode(name)
+	}
+
+	failed := false
+	for _, file := range files {
+		if file.UpPath == "" {
+			continue
+		}
+		name := namer.Base(file.Sequence, file.Name)
+		deps, err := migrations.Dependencies(file.UpPath)
+		if err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		for _, dep := range deps {
+			sequence, ok := sequences[dep]
+			if !ok {
+				printer.Error("Error: %s depends on unknown migration %s", name, dep)
+				failed = true
+				continue
+			}
+			if sequence > file.Sequence {
+				printer.Warn("Warning: %s depends on %s, which is applied after it", name, dep)
+			}
+			g.AddEdge(dep, name)
+		}
+	}
+	if failed {
+		return 1
+	}
+
+	if _, err := g.TopologicalSort(); err != nil {
+		var cycle *graph.CycleError[string]
+		if errors.As(err, &cycle) {
+			printer.Error("Error: migrations depend on each other in a cycle:")
+			for _, member := range cycle.Members {
+				printer.Error("  - %s", member)
+			}
+			return 1
+		}
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	if err := graph.WriteDOT(os.Stdout, g, "migrations", func(name string) string { return name }); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+// Package graph resolves dependencies between named items.
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DAG is a directed graph meant to be acyclic, in which an edge from a to b
+// means a must come before b. Nodes keep the order they were added in, and
+// everything that walks the graph is deterministic in that order.
+type DAG[T comparable] struct {
+	nodes []T
+	seen  map[T]bool
+	edges map[T][]T
+}
+
+// New returns an empty DAG.
+func New[T comparable]() *DAG[T] {
+	return &DAG[T]{seen: make(map[T]bool), edges: make(map[T][]T)}
+}
+
+// AddNode adds n if it is not already in the graph.
+func (g *DAG[T]) AddNode(n T) {
+	if !g.seen[n] {
+		g.seen[n] = true
+		g.nodes = append(g.nodes, n)
+	}
+}
+
+// AddEdge records that from must come before to, adding either node if
+// needed.
+func (g *DAG[T]) AddEdge(from, to T) {
+	g.AddNode(from)
+	g.AddNode(to)
+	for _, n := range g.edges[from] {
+		if n == to {
+			return
+		}
+	}
+	g.edges[from] = append(g.edges[from], to)
+}
+
+// Nodes returns the nodes in the order they were added.
+func (g *DAG[T]) Nodes() []T {
+	return append([]T(nil), g.nodes...)
+}
+
+// Successors returns the nodes that must come after n.
+func (g *DAG[T]) Successors(n T) []T {
+	return append([]T(nil), g.edges[n]...)
+}
+
+// CycleError reports a cycle: each member must come before the next, and
+// the last before the first.
+type CycleError[T comparable] struct {
+	Members []T
+}
+
+func (e *CycleError[T]) Error() string {
+	names := make([]string, len(e.Members)+1)
+	for i, m := range e.Members {
+		names[i] = fmt.Sprint(m)
+	}
+	names[len(e.Members)] = fmt.Sprint(e.Members[0])
+	return "dependency cycle: " + strings.Join(names, " -> ")
+}
+
+// Cycle returns the members of a cycle in the graph, or nil if it is
+// acyclic.
+func (g *DAG[T]) Cycle() []T {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[T]int, len(g.nodes))
+	var path []T
+
+	var visit func(n T) []T
+	visit = func(n T) []T {
+		state[n] = visiting
+		path = append(path, n)
+		for _, next := range g.edges[n] {
+			switch state[next] {
+			case visiting:
+				for i, p := range path {
+					if p == next {
+						return append([]T(nil), path[i:]...)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[n] = done
+		return nil
+	}
+
+	for _, n := range g.nodes {
+		if state[n] == unvisited {
+			if cycle := visit(n); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// TopologicalSort orders the nodes so that each comes after everything it
+// depends on. Among nodes that are free to go next, the one added first
+// wins, so a graph without edges keeps its insertion order. It returns a
+// *CycleError if there is no such order.
+func (g *DAG[T]) TopologicalSort() ([]T, error) {
+	if cycle := g.Cycle(); cycle != nil {
+		return nil, &CycleError[T]{Members: cycle}
+	}
+
+	indegree := make(map[T]int, len(g.nodes))
+	for _, n := range g.nodes {
+		for _, next := range g.edges[n] {
+			indegree[next]++
+		}
+	}
+
+	sorted := make([]T, 0, len(g.nodes))
+	placed := make(map[T]bool, len(g.nodes))
+	for len(sorted) < len(g.nodes) {
+		for _, n := range g.nodes {
+			if placed[n] || indegree[n] > 0 {
+				continue
+			}
+			placed[n] = true
+			sorted = append(sorted, n)
+			for _, next := range g.edges[n] {
+				indegree[next]--
+			}
+			break
+		}
+	}
+	return sorted, nil
+}
@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTopologicalSort(t *testing.T) {
+	g := New[string]()
+	for _, n := range []string{"users", "orders", "prices", "indexes"} {
+		g.AddNode(n)
+	}
+	g.AddEdge("prices", "orders")
+	g.AddEdge("users", "orders")
+	g.AddEdge("orders", "indexes")
+
+	got, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"users", "prices", "orders", "indexes"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TopologicalSort() = %v, want %v", got, want)
+	}
+}
+
+func TestTopologicalSortKeepsInsertionOrder(t *testing.T) {
+	g := New[int]()
+	for _, n := range []int{3, 1, 2} {
+		g.AddNode(n)
+	}
+
+	got, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{3, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TopologicalSort() = %v, want %v", got, want)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	g := New[string]()
+	g.AddEdge("a", "b")
+	g.AddEdge("b", "c")
+	g.AddEdge("c", "b")
+	g.AddEdge("c", "d")
+
+	_, err := g.TopologicalSort()
+	var cycle *CycleError[string]
+	if !errors.As(err, &cycle) {
+		t.Fatalf("TopologicalSort() error = %v, want a CycleError", err)
+	}
+	if want := []string{"b", "c"}; !reflect.DeepEqual(cycle.Members, want) {
+		t.Errorf("cycle members = %v, want %v", cycle.Members, want)
+	}
+	if want := "dependency cycle: b -> c -> b"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	g := New[string]()
+	g.AddEdge("000001_users", "000002_orders")
+
+	var b strings.Builder
+	if err := WriteDOT(&b, g, "migrations", func(s string) string { return s }); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph "migrations" {
+  rankdir=LR;
+  node [shape=box];
+  "000001_users";
+  "000002_orders";
+  "000001_users" -> "000002_orders";
+}
+`
+	if b.String() != want {
+		t.Errorf("WriteDOT wrote:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
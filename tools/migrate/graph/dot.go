@@ -0,0 +1,29 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteDOT writes g to w in the Graphviz DOT language as a digraph called
+// name, labelling each node with label(n).
+func WriteDOT[T comparable](w io.Writer, g *DAG[T], name string, label func(T) string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", strconv.Quote(name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, n := range g.Nodes() {
+		fmt.Fprintf(&b, "  %s;\n", strconv.Quote(label(n)))
+	}
+	for _, n := range g.Nodes() {
+		for _, next := range g.Successors(n) {
+			fmt.Fprintf(&b, "  %s -> %s;\n", strconv.Quote(label(n)), strconv.Quote(label(next)))
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/doctor"
+)
+
+// runHealth runs the checks a deployment needs to pass before up: the
+// migration engine is installed, the migration directory is intact and the
+// database is reachable. It prints a table, or with --format json a
+// {"checks": [...]} report, and exits 0 only if every check passes.
+func runHealth(cfg Config) int {
+	ctx, cancel := cfg.commandContext()
+	defer cancel()
+
+	results := doctor.RunChecks(ctx, healthChecks(cfg))
+	if cfg.Format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Checks []doctor.Result `json:"checks"`
+		}{results}); err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CHECK\tSTATUS\tMESSAGE")
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, r.Status, r.Message)
+		}
+		w.Flush()
+	}
+
+	if !doctor.Passed(results) {
+		return 1
+	}
+	return 0
+}
+
+// healthChecks returns the checks health runs.
+func healthChecks(cfg Config) []doctor.Check {
+	var checks []doctor.Check
+	if cfg.Engine == "cargo" {
+		checks = append(checks, doctor.CargoInstalled{Bin: cargoBinName(cfg)})
+	}
+	return append(checks,
+		doctor.MigrationDirExists{Dir: cfg.MigrationDir},
+		doctor.DatabaseReachable{Getenv: getenv, Validate: validateDatabaseURL, Timeout: 5 * time.Second},
+		doctor.MigrationsUnchanged{Getenv: getenv, Dir: cfg.SQLDir(), Timeout: listTimeout},
+	)
+}
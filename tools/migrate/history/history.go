@@ -0,0 +1,92 @@
+// Package history looks up when a migration was applied in each of several
+// databases, such as one per environment.
+package history
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+)
+
+// Source is a database to look the migration up in.
+type Source struct {
+	Environment string
+	DatabaseURL string
+}
+
+// Record is what one database knows about the migration. AppliedAt is nil
+// when the migration has not been applied there, Duration is zero when the
+// tracking table does not record it, and Err is set when the database could
+// not be asked.
+type Record struct {
+	Environment string
+	AppliedAt   *time.Time
+	Duration    time.Duration
+	Err         error
+}
+
+// Query looks up the migration with version in every source at once,
+// giving each timeout to answer, and returns the records in the order of
+// sources.
+func Query(ctx context.Context, sources []Source, version int64, timeout time.Duration) []Record {
+	type result struct {
+		index  int
+		record Record
+	}
+	results := make(chan result, len(sources))
+
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source Source) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			record := lookup(ctx, source.DatabaseURL, version)
+			record.Environment = source.Environment
+			results <- result{i, record}
+		}(i, source)
+	}
+	wg.Wait()
+	close(results)
+
+	records := make([]Record, len(sources))
+	for r := range results {
+		records[r.index] = r.record
+	}
+	return records
+}
+
+// lookup reads the db.RecordsTable row of the migration with version from
+// the database at databaseURL. A database without the table has applied
+// nothing.
+func lookup(ctx context.Context, databaseURL string, version int64) Record {
+	conn, err := pg.Open(ctx, databaseURL)
+	if err != nil {
+		return Record{Err: err}
+	}
+	defer conn.Close()
+
+	table := db.QuotedRecordsTable()
+	var exists bool
+	if err := conn.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+		return Record{Err: err}
+	}
+	if !exists {
+		return Record{}
+	}
+
+	applied, err := db.Applied(ctx, conn)
+	if err != nil {
+		return Record{Err: err}
+	}
+	for _, m := range applied {
+		if m.Version == version {
+			return Record{AppliedAt: &m.AppliedAt, Duration: m.ExecutionTime}
+		}
+	}
+	return Record{}
+}
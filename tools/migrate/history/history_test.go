@@ -0,0 +1,33 @@
+package history
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueryKeepsSourceOrder(t *testing.T) {
+	sources := []Source{
+		{Environment: "production", DatabaseURL: "postgres://app@127.0.0.1:1/prod"},
+		{Environment: "staging", DatabaseURL: "postgres://app@127.0.0.1:2/staging"},
+		{Environment: "qa", DatabaseURL: "not a url"},
+	}
+
+	start := time.Now()
+	records := Query(context.Background(), sources, 42, 2*time.Second)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Query took %s, longer than the timeout allows", elapsed)
+	}
+
+	if len(records) != len(sources) {
+		t.Fatalf("got %d records, want %d", len(records), len(sources))
+	}
+	for i, r := range records {
+		if r.Environment != sources[i].Environment {
+			t.Errorf("record %d is for %s, want %s", i, r.Environment, sources[i].Environment)
+		}
+		if r.Err == nil || r.AppliedAt != nil {
+			t.Errorf("%s: AppliedAt = %v, Err = %v; want a connection error", r.Environment, r.AppliedAt, r.Err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/crypto-bot/tools/migrate/output"
+)
+
+// hookPath returns the hook named name ("pre-migrate" or "post-migrate"),
+// taken from envKey or else from the hooks directory in the project root.
+func hookPath(cfg Config, name, envKey string) string {
+	if path := getenv(envKey); path != "" {
+		return path
+	}
+	return filepath.Join(cfg.ProjectRoot, "hooks", name)
+}
+
+// runHook runs the executable at path with the command as its only argument
+// and returns its exit code. A hook that does not exist is skipped; one that
+// exists but is not executable is skipped with a warning. The hook inherits
+// the tool's environment, including variables loaded from .env files, plus
+// DATABASE_URL and any extra variables given.
+func runHook(ctx context.Context, cfg Config, path string, extraEnv ...string) int {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0
+	}
+	if err != nil {
+		printer.Error("Error: hook %s: %v", path, err)
+		return 1
+	}
+	if info.IsDir() || info.Mode()&0o111 == 0 {
+		printer.Warn("Warning: hook %s is not executable, skipping it", path)
+		return 0
+	}
+
+	printer.Info("Running hook: %s %s", path, cfg.Command)
+
+	stdout := newLogWriter(slog.LevelInfo, "stdout")
+	stderr := newLogWriter(slog.LevelError, "stderr")
+
+	cmd := exec.CommandContext(ctx, path, cfg.Command)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if secrets := cfg.redactions(cfg.DatabaseURL); len(secrets) > 0 {
+		cmd.Stdout = output.NewRedactingWriter(stdout, secrets)
+		cmd.Stderr = output.NewRedactingWriter(stderr, secrets)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Env = append(environment.Environ(), "DATABASE_URL="+cfg.DatabaseURL)
+	cmd.Env = append(cmd.Env, extraEnv...)
+
+	err = cmd.Run()
+	for _, w := range []io.Writer{cmd.Stdout, cmd.Stderr} {
+		w.(flusher).Flush()
+	}
+	if err != nil {
+		printer.Error("Hook %s failed: %v", path, err)
+		return 1
+	}
+	return 0
+}
+
+// runPreMigrateHook runs the pre-migrate hook from PRE_MIGRATE_HOOK or
+// ../../hooks/pre-migrate. The migration must not proceed if it fails.
+func runPreMigrateHook(ctx context.Context, cfg Config) int {
+	return runHook(ctx, cfg, hookPath(cfg, "pre-migrate", "PRE_MIGRATE_HOOK"))
+}
+
+// runPostMigrateHook runs the post-migrate hook from POST_MIGRATE_HOOK or
+// ../../hooks/post-migrate, telling it how the migration ended through
+// MIGRATE_EXIT_CODE.
+func runPostMigrateHook(ctx context.Context, cfg Config, exitCode int) int {
+	return runHook(ctx, cfg, hookPath(cfg, "post-migrate", "POST_MIGRATE_HOOK"), "MIGRATE_EXIT_CODE="+strconv.Itoa(exitCode))
+}
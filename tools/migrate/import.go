@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/importcmd"
+	"github.com/crypto-bot/tools/migrate/internal/lock"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"github.com/crypto-bot/tools/migrate/squash"
+)
+
+// runImport adopts a database that has a schema but no migrations: it dumps
+// the schema with pg_dump --schema-only into 000001_initial_schema, writes
+// a down file that drops everything, and records the migration as applied
+// so that up leaves the database alone. With --dry-run it prints the files
+// instead of writing them.
+func runImport(cfg Config) int {
+	var namer migrations.MigrationNamer
+
+	upPath := importcmd.UpPath(cfg.SQLDir())
+	downPath := squash.DownPath(upPath)
+
+	files, err := migrations.Scan(cfg.SQLDir())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if len(files) > 0 {
+		printer.Error("Error: %s already holds %d migration(s); import only adopts a project without any", cfg.SQLDir(), len(files))
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+	if n, err := importcmd.Recorded(ctx, conn); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	} else if n > 0 {
+		printer.Error("Error: %s already records %d migration(s); the database is managed by the migrator already", db.MigrationsTable(), n)
+		return 1
+	}
+
+	var schema bytes.Buffer
+	fmt.Fprintf(&schema, "-- Schema of %s imported on %s.\n\n", databaseName(cfg.DatabaseURL), time.Now().UTC().Format(time.RFC3339))
+	if err := newDumper(cfg.DatabaseURL).Schema(ctx, &schema, db.MigrationsTable(), db.RecordsTable()); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	if cfg.DryRun {
+		printer.Info("Dry run: would write %s:", upPath)
+		fmt.Print(string(squash.Baseline(schema.Bytes())))
+		printer.Info("Dry run: would write %s:", downPath)
+		fmt.Print(squash.DownSQL)
+		printer.Info("Dry run: would record %s as applied in %s", namer.Base(importcmd.Sequence, importcmd.Name), db.RecordsTable())
+		return 0
+	}
+
+	release, err := acquireMigrationLock(context.Background(), cfg.DatabaseURL, cfg.LockTimeout)
+	if errors.Is(err, lock.ErrTimeout) {
+		printer.Error("Error: another migration is running (lock not acquired within %s)", cfg.LockTimeout)
+		return exitLockContention
+	}
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	defer release()
+
+	start := time.Now()
+	exitCode := 0
+	if err := importSchema(ctx, cfg, conn, upPath, schema.Bytes()); err != nil {
+		printer.Error("Import failed: %v", err)
+		exitCode = 1
+	}
+	recordAudit(cfg, start, exitCode, time.Since(start))
+	if exitCode == 0 {
+		printer.Success("Imported the schema of %s as %s", databaseName(cfg.DatabaseURL), upPath)
+	}
+	return exitCode
+}
+
+// importSchema writes the initial migration and records it as applied,
+// removing the files again if it cannot be recorded.
+func importSchema(ctx context.Context, cfg Config, conn *sql.DB, upPath string, schema []byte) error {
+	if err := os.MkdirAll(cfg.SQLDir(), 0o755); err != nil {
+		return err
+	}
+	if err := squash.WriteBaseline(upPath, schema); err != nil {
+		return fmt.Errorf("write migration: %w", err)
+	}
+	printer.Success("✓ Wrote %s and %s", upPath, squash.DownPath(upPath))
+
+	checksum := sha512.Sum384(squash.Baseline(schema))
+	if err := importcmd.Record(ctx, conn, checksum[:]); err != nil {
+		os.Remove(upPath)
+		os.Remove(squash.DownPath(upPath))
+		return fmt.Errorf("record migration: %w", err)
+	}
+	printer.Success("✓ Recorded %s as applied in %s", filepath.Base(strings.TrimSuffix(upPath, ".up.sql")), db.RecordsTable())
+	return nil
+}
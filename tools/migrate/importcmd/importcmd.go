@@ -0,0 +1,85 @@
+// Package importcmd adopts a database whose schema predates the migration
+// tool: it captures the schema as the first migration and records that
+// migration as applied. It is not called import, which is a keyword.
+package importcmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// Sequence and Name make up the initial migration, 000001_initial_schema.
+const (
+	Sequence = 1
+	Name     = "initial_schema"
+)
+
+// UpPath returns the up file of the initial migration in dir.
+func UpPath(dir string) string {
+	var namer migrations.MigrationNamer
+	return filepath.Join(dir, namer.Base(Sequence, Name)+".up.sql")
+}
+
+// Recorded returns the number of migrations the tracking table and
+// db.RecordsTable record, read through q, which is zero for a database
+// without them.
+func Recorded(ctx context.Context, q queryRower) (int64, error) {
+	tables := []string{db.QuotedMigrationsTable()}
+	if db.RecordsTable() != db.MigrationsTable() {
+		tables = append(tables, db.QuotedRecordsTable())
+	}
+
+	var total int64
+	for _, table := range tables {
+		var exists bool
+		if err := q.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if !exists {
+			continue
+		}
+		var n int64
+		if err := q.QueryRowContext(ctx, "SELECT count(*) FROM "+table).Scan(&n); err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// Record creates the tracking table if needed and records the initial
+// migration as applied, with checksum, the SHA-384 of its up file. It
+// fails if the table already records any migration, since the database is
+// then managed by the migrator already.
+func Record(ctx context.Context, conn *sql.DB, checksum []byte) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := db.EnsureTable(ctx, tx); err != nil {
+		return fmt.Errorf("create %s: %w", db.RecordsTable(), err)
+	}
+	n, err := Recorded(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("%s already records %d migration(s)", db.MigrationsTable(), n)
+	}
+	if err := db.RecordApplied(ctx, tx, Sequence, Name, checksum, 0); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// queryRower is what *sql.DB and *sql.Tx have in common for reading a row.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
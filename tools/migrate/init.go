@@ -0,0 +1,80 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// templates holds the migration crate scaffolds written by init, one
+// directory per --template.
+//
+//go:embed templates
+var templates embed.FS
+
+// initTemplates lists the values accepted by --template.
+var initTemplates = []string{"minimal", "full"}
+
+// initPaths are the top-level entries of the migration crate that init
+// refuses to overwrite.
+var initPaths = []string{"Cargo.toml", "src", "migrations"}
+
+// scaffoldMigrationCrate writes the named template into dir and returns the
+// paths of the files it created. It fails without writing anything if any
+// of initPaths already exists in dir.
+func scaffoldMigrationCrate(dir, template string) ([]string, error) {
+	var existing []string
+	for _, name := range initPaths {
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); err == nil {
+			existing = append(existing, p)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("refusing to overwrite existing %s", strings.Join(existing, ", "))
+	}
+
+	root := path.Join("templates", template)
+	var created []string
+	err := fs.WalkDir(templates, root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(name, root)))
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+
+		data, err := templates.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0o644); err != nil {
+			return err
+		}
+		created = append(created, target)
+		return nil
+	})
+	return created, err
+}
+
+// runInit scaffolds the migration crate at --migration-dir.
+func runInit(cfg Config) int {
+	created, err := scaffoldMigrationCrate(cfg.MigrationDir, cfg.Template)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	for _, p := range created {
+		printer.Success("Created %s", p)
+	}
+	return 0
+}
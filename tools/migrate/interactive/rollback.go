@@ -0,0 +1,120 @@
+// Package interactive walks the user through rolling back applied
+// migrations one at a time.
+package interactive
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Migration is an applied migration.
+type Migration struct {
+	Version int64
+	Name    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%06d_%s", m.Version, m.Name)
+}
+
+// Rollback asks, for each applied migration from the newest down, whether
+// to roll it back. The migrator can only roll back the newest applied
+// migration, so keeping one ends the session: nothing older can be rolled
+// back while it is applied.
+type Rollback struct {
+	// Applied returns the applied migrations, newest first. It is called
+	// again after every step, so the prompts follow the database rather than
+	// the list read at the start.
+	Applied func(ctx context.Context) ([]Migration, error)
+	// Down rolls back the newest applied migration.
+	Down func(ctx context.Context) error
+	// Show, if set, writes what rolling back a migration would run to Out
+	// before it is asked about, and the question becomes whether to apply
+	// that rollback. An error ends the session.
+	Show func(w io.Writer, m Migration) error
+	// In supplies the answers and Out receives the prompts.
+	In  io.Reader
+	Out io.Writer
+}
+
+// Run prompts until the user keeps a migration, quits, or no migrations
+// are left, and returns the migrations it rolled back. Answers are y, n
+// (the default) and q; end of input counts as q.
+func (r *Rollback) Run(ctx context.Context) ([]Migration, error) {
+	applied, err := r.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 {
+		fmt.Fprintln(r.Out, "No migrations are applied, nothing to roll back")
+		return nil, nil
+	}
+	fmt.Fprintln(r.Out, "Applied migrations, newest first:")
+	for _, m := range applied {
+		fmt.Fprintf(r.Out, "  - %s\n", m)
+	}
+
+	scanner := bufio.NewScanner(r.In)
+	var rolledBack []Migration
+	for len(applied) > 0 {
+		newest := applied[0]
+		if r.Show != nil {
+			if err := r.Show(r.Out, newest); err != nil {
+				return rolledBack, err
+			}
+		}
+		answer, ok := r.ask(scanner, newest)
+		if !ok {
+			return rolledBack, scanner.Err()
+		}
+		switch answer {
+		case "q", "quit":
+			return rolledBack, nil
+		case "", "n", "no":
+			if len(applied) > 1 {
+				fmt.Fprintf(r.Out, "Keeping %s; the migrations before it cannot be rolled back while it is applied\n", newest)
+			}
+			return rolledBack, nil
+		}
+
+		if err := r.Down(ctx); err != nil {
+			return rolledBack, fmt.Errorf("roll back %s: %w", newest, err)
+		}
+		rolledBack = append(rolledBack, newest)
+
+		if applied, err = r.Applied(ctx); err != nil {
+			return rolledBack, err
+		}
+		if len(applied) > 0 && applied[0] == newest {
+			return rolledBack, errors.New(newest.String() + " is still applied after rolling it back")
+		}
+	}
+	fmt.Fprintln(r.Out, "No migrations are left to roll back")
+	return rolledBack, nil
+}
+
+// ask prompts for m until the answer is y, n or q, returning it in lower
+// case, or false at the end of input.
+func (r *Rollback) ask(scanner *bufio.Scanner, m Migration) (string, bool) {
+	for {
+		if r.Show != nil {
+			fmt.Fprint(r.Out, "Apply this rollback? [y/N] ")
+		} else {
+			fmt.Fprintf(r.Out, "Roll back %s? [y/N/q] ", m)
+		}
+		if !scanner.Scan() {
+			fmt.Fprintln(r.Out)
+			return "", false
+		}
+		answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		switch answer {
+		case "y", "yes", "", "n", "no", "q", "quit":
+			return answer, true
+		}
+		fmt.Fprintln(r.Out, "Please answer y, n or q.")
+	}
+}
@@ -0,0 +1,150 @@
+package interactive
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeDatabase holds applied migrations, newest first, and counts the
+// times it was queried.
+type fakeDatabase struct {
+	applied []Migration
+	queries int
+	downErr error
+}
+
+func (f *fakeDatabase) Applied(context.Context) ([]Migration, error) {
+	f.queries++
+	return slices.Clone(f.applied), nil
+}
+
+func (f *fakeDatabase) Down(context.Context) error {
+	if f.downErr != nil {
+		return f.downErr
+	}
+	f.applied = f.applied[1:]
+	return nil
+}
+
+func newFake() *fakeDatabase {
+	return &fakeDatabase{applied: []Migration{{3, "add_index"}, {2, "add_orders"}, {1, "create_users"}}}
+}
+
+func run(t *testing.T, fake *fakeDatabase, input string) ([]Migration, string, error) {
+	t.Helper()
+	var out strings.Builder
+	r := &Rollback{Applied: fake.Applied, Down: fake.Down, In: strings.NewReader(input), Out: &out}
+	rolledBack, err := r.Run(context.Background())
+	return rolledBack, out.String(), err
+}
+
+func TestRollbackStopsAtKeptMigration(t *testing.T) {
+	fake := newFake()
+	rolledBack, out, err := run(t, fake, "y\nn\ny\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Migration{{3, "add_index"}}; !slices.Equal(rolledBack, want) {
+		t.Errorf("rolled back %v, want %v", rolledBack, want)
+	}
+	if len(fake.applied) != 2 {
+		t.Errorf("%d migrations left applied, want 2", len(fake.applied))
+	}
+	if fake.queries != 2 {
+		t.Errorf("queried %d times, want once before and once after the step", fake.queries)
+	}
+	if !strings.Contains(out, "Keeping 000002_add_orders") {
+		t.Errorf("output does not explain why it stopped:\n%s", out)
+	}
+}
+
+func TestRollbackAll(t *testing.T) {
+	fake := newFake()
+	rolledBack, out, err := run(t, fake, "y\nY\nyes\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rolledBack) != 3 || len(fake.applied) != 0 {
+		t.Errorf("rolled back %v, left %v", rolledBack, fake.applied)
+	}
+	for _, prompt := range []string{"Roll back 000003_add_index? [y/N/q]", "Roll back 000001_create_users? [y/N/q]"} {
+		if !strings.Contains(out, prompt) {
+			t.Errorf("output lacks %q:\n%s", prompt, out)
+		}
+	}
+}
+
+func TestRollbackQuitAndEndOfInput(t *testing.T) {
+	for _, input := range []string{"q\n", "", "maybe\nq\n"} {
+		fake := newFake()
+		rolledBack, _, err := run(t, fake, input)
+		if err != nil || len(rolledBack) != 0 || len(fake.applied) != 3 {
+			t.Errorf("input %q: rolled back %v, err %v", input, rolledBack, err)
+		}
+	}
+}
+
+func TestRollbackDownFails(t *testing.T) {
+	fake := newFake()
+	fake.downErr = errors.New("exit status 1")
+	_, _, err := run(t, fake, "y\n")
+	if err == nil || !strings.Contains(err.Error(), "000003_add_index") {
+		t.Errorf("err = %v, want it to name the migration", err)
+	}
+}
+
+func TestRollbackNothingApplied(t *testing.T) {
+	r := &Rollback{
+		Applied: (&fakeDatabase{}).Applied,
+		Down:    func(context.Context) error { t.Fatal("Down called"); return nil },
+		In:      strings.NewReader("y\n"),
+		Out:     io.Discard,
+	}
+	if rolledBack, err := r.Run(context.Background()); err != nil || len(rolledBack) != 0 {
+		t.Errorf("Run() = %v, %v", rolledBack, err)
+	}
+}
+
+func TestRollbackShowsEachBeforeAsking(t *testing.T) {
+	fake := newFake()
+	var out strings.Builder
+	r := &Rollback{
+		Applied: fake.Applied,
+		Down:    fake.Down,
+		Show: func(w io.Writer, m Migration) error {
+			_, err := io.WriteString(w, "<sql of "+m.String()+">\n")
+			return err
+		},
+		In:  strings.NewReader("y\nn\n"),
+		Out: &out,
+	}
+	rolledBack, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Migration{{3, "add_index"}}; !slices.Equal(rolledBack, want) {
+		t.Errorf("rolled back %v, want %v", rolledBack, want)
+	}
+	want := "<sql of 000003_add_index>\nApply this rollback? [y/N] <sql of 000002_add_orders>\nApply this rollback? [y/N] "
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("output lacks %q:\n%s", want, out.String())
+	}
+}
+
+func TestRollbackShowFails(t *testing.T) {
+	fake := newFake()
+	r := &Rollback{
+		Applied: fake.Applied,
+		Down:    func(context.Context) error { t.Fatal("Down called"); return nil },
+		Show:    func(io.Writer, Migration) error { return errors.New("no down file") },
+		In:      strings.NewReader("y\n"),
+		Out:     io.Discard,
+	}
+	if _, err := r.Run(context.Background()); err == nil || err.Error() != "no down file" {
+		t.Errorf("err = %v, want the Show error", err)
+	}
+}
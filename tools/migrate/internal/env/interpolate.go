@@ -0,0 +1,109 @@
+package env
+
+import "strings"
+
+// interpolate expands the variable references in value, looking each one up
+// with lookup:
+//
+//   - $VAR and ${VAR} are replaced by the variable's value, or by nothing
+//     if it is unset.
+//   - ${VAR:-default} is replaced by default when VAR is unset or empty.
+//     The default may itself contain references.
+//   - $$ stands for a literal $.
+//
+// A $ that does not start one of these, including an unterminated ${, is
+// kept as written.
+func interpolate(value string, lookup func(string) (string, bool)) string {
+	if !strings.Contains(value, "$") {
+		return value
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '$' || i+1 == len(value) {
+			b.WriteByte(c)
+			continue
+		}
+
+		switch next := value[i+1]; {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+		case next == '{':
+			end := closingBrace(value, i+2)
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteString(expandBraced(value[i+2:end], lookup))
+			i = end
+		case isNameStart(next):
+			j := i + 2
+			for j < len(value) && isNameByte(value[j]) {
+				j++
+			}
+			v, _ := lookup(value[i+1 : j])
+			b.WriteString(v)
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// expandBraced expands the inside of a ${...} reference. Anything that is
+// not a name, optionally followed by :-default, is kept as written.
+func expandBraced(expr string, lookup func(string) (string, bool)) string {
+	name, fallback, hasDefault := strings.Cut(expr, ":-")
+	if !validName(name) {
+		return "${" + expr + "}"
+	}
+	if v, ok := lookup(name); ok && (v != "" || !hasDefault) {
+		return v
+	}
+	if hasDefault {
+		return interpolate(fallback, lookup)
+	}
+	return ""
+}
+
+// closingBrace returns the index of the } closing a ${ whose contents start
+// at start, allowing nested ${...} in a default, or -1 if there is none.
+func closingBrace(value string, start int) int {
+	depth := 1
+	for i := start; i < len(value); i++ {
+		switch {
+		case value[i] == '$' && i+1 < len(value) && value[i+1] == '{':
+			depth++
+			i++
+		case value[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func validName(name string) bool {
+	if name == "" || !isNameStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isNameByte(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isNameByte(c byte) bool {
+	return isNameStart(c) || c >= '0' && c <= '9'
+}
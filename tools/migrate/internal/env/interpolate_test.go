@@ -0,0 +1,63 @@
+package env
+
+import (
+	"strings"
+	"testing"
+)
+
+func lookupIn(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	vars := map[string]string{"USER": "deploy", "HOST": "db", "EMPTY": ""}
+	tests := []struct {
+		value, want string
+	}{
+		{"plain", "plain"},
+		{"$USER@$HOST", "deploy@db"},
+		{"${USER}_suffix", "deploy_suffix"},
+		{"$USER_suffix", ""},
+		{"${MISSING}", ""},
+		{"${MISSING:-fallback}", "fallback"},
+		{"${EMPTY:-fallback}", "fallback"},
+		{"${HOST:-fallback}", "db"},
+		{"${MISSING:-${HOST}:5432}", "db:5432"},
+		{"${MISSING:-}", ""},
+		{"cost: $$5", "cost: $5"},
+		{"trailing $", "trailing $"},
+		{"$1 and $-", "$1 and $-"},
+		{"${unterminated", "${unterminated"},
+		{"${not a name}", "${not a name}"},
+	}
+	for _, tt := range tests {
+		if got := interpolate(tt.value, lookupIn(vars)); got != tt.want {
+			t.Errorf("interpolate(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func FuzzInterpolate(f *testing.F) {
+	for _, seed := range []string{"", "plain", "$A", "${A}", "${A:-b}", "${B:-${A}}", "$$", "${", "$}", "${A:-${", "}$"} {
+		f.Add(seed)
+	}
+	vars := map[string]string{"A": "value-of-a"}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		got := interpolate(value, lookupIn(vars))
+
+		// Text without a $ is left alone.
+		if !strings.Contains(value, "$") && got != value {
+			t.Errorf("interpolate(%q) = %q, want it unchanged", value, got)
+		}
+
+		// Escaping every $ as $$ always yields the original text.
+		escaped := strings.ReplaceAll(value, "$", "$$")
+		if got := interpolate(escaped, lookupIn(vars)); got != value {
+			t.Errorf("interpolate(%q) = %q, want %q", escaped, got, value)
+		}
+	})
+}
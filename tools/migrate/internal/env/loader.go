@@ -0,0 +1,219 @@
+// Package env loads .env files for the tool without touching the process
+// environment.
+package env
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// EnvLoader reads .env files into memory once and answers lookups from them
+// together with the process environment. It never calls os.Setenv, so it is
+// safe to use from several goroutines, alongside code that reads the process
+// environment itself.
+//
+// A variable set in the process environment takes precedence over the
+// files, and a value given to Set takes precedence over both.
+type EnvLoader struct {
+	// paths are the files to read, highest precedence first.
+	paths    []string
+	optional bool
+
+	once sync.Once
+	err  error
+
+	mu        sync.RWMutex
+	loaded    map[string]string
+	overrides map[string]string
+}
+
+// NewEnvLoader returns a loader for rootDir/.env.<appEnv> followed by
+// rootDir/.env. Values from the environment-specific file take precedence
+// over the base file. Missing files are skipped; with an empty appEnv only
+// rootDir/.env is read.
+func NewEnvLoader(rootDir, appEnv string) *EnvLoader {
+	var paths []string
+	if appEnv != "" {
+		paths = append(paths, filepath.Join(rootDir, ".env."+appEnv))
+	}
+	paths = append(paths, filepath.Join(rootDir, ".env"))
+	return &EnvLoader{paths: paths, optional: true}
+}
+
+// NewFileEnvLoader returns a loader for the given .env files. Later files
+// take precedence over earlier ones, as with Docker Compose's env_file.
+// Unlike with NewEnvLoader, every file must exist.
+func NewFileEnvLoader(paths []string) *EnvLoader {
+	reversed := make([]string, len(paths))
+	for i, path := range paths {
+		reversed[len(paths)-1-i] = path
+	}
+	return &EnvLoader{paths: reversed}
+}
+
+// Load reads the loader's files. Only the first call reads them; later and
+// concurrent calls wait for it and return the same error.
+func (l *EnvLoader) Load() error {
+	l.once.Do(func() {
+		for _, path := range l.paths {
+			if err := l.loadFile(path); err != nil && !(l.optional && os.IsNotExist(err)) {
+				l.err = err
+				return
+			}
+		}
+	})
+	return l.err
+}
+
+// loadFile records every variable defined in the .env file at path that is
+// not already set. References to other variables in a value are expanded
+// from the variables known when the line is read, so a line can use the
+// variables defined by the lines before it; single-quoted values are taken
+// literally, as in a shell.
+func (l *EnvLoader) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		key, value, ok := parseLine(line)
+		if !ok || l.Getenv(key) != "" {
+			continue
+		}
+		if !singleQuoted(line) {
+			value = interpolate(value, l.Lookup)
+		}
+		l.mu.Lock()
+		if l.loaded == nil {
+			l.loaded = make(map[string]string)
+		}
+		l.loaded[key] = value
+		l.mu.Unlock()
+	}
+	return scanner.Err()
+}
+
+// Keys returns the names of the variables read from the loader's files, in
+// sorted order.
+func (l *EnvLoader) Keys() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	keys := make([]string, 0, len(l.loaded))
+	for key := range l.loaded {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ReadFile returns the variables defined in the .env file at path, without
+// consulting the environment: references in a value are expanded only from
+// the lines before it. When a key is defined twice the first definition
+// wins, as when loading.
+func ReadFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	vars := make(map[string]string)
+	lookup := func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		key, value, ok := parseLine(line)
+		if _, seen := vars[key]; !ok || seen {
+			continue
+		}
+		if !singleQuoted(line) {
+			value = interpolate(value, lookup)
+		}
+		vars[key] = value
+	}
+	return vars, scanner.Err()
+}
+
+// Lookup returns the value of key, from Set, a non-empty process
+// environment variable or the loaded files, in that order, and whether it
+// is set at all.
+func (l *EnvLoader) Lookup(key string) (string, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if value, ok := l.overrides[key]; ok {
+		return value, true
+	}
+	value, ok := os.LookupEnv(key)
+	if value != "" {
+		return value, true
+	}
+	if loaded, found := l.loaded[key]; found {
+		return loaded, true
+	}
+	return value, ok
+}
+
+// Getenv returns the value of key, or "" if it is not set.
+func (l *EnvLoader) Getenv(key string) string {
+	value, _ := l.Lookup(key)
+	return value
+}
+
+// Set makes key resolve to value, over both the process environment and
+// the loaded files.
+func (l *EnvLoader) Set(key, value string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.overrides == nil {
+		l.overrides = make(map[string]string)
+	}
+	l.overrides[key] = value
+}
+
+// Environ returns the process environment with the loaded and Set values
+// merged in, as KEY=value pairs for exec.Cmd.Env.
+func (l *EnvLoader) Environ() []string {
+	process := os.Environ()
+	seen := make(map[string]bool, len(process))
+	environ := make([]string, 0, len(process))
+	for _, kv := range process {
+		key, _, _ := strings.Cut(kv, "=")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		environ = append(environ, key+"="+l.Getenv(key))
+	}
+
+	l.mu.RLock()
+	var extra []string
+	for _, vars := range []map[string]string{l.loaded, l.overrides} {
+		for key := range vars {
+			if !seen[key] {
+				seen[key] = true
+				extra = append(extra, key)
+			}
+		}
+	}
+	l.mu.RUnlock()
+
+	sort.Strings(extra)
+	for _, key := range extra {
+		environ = append(environ, key+"="+l.Getenv(key))
+	}
+	return environ
+}
@@ -0,0 +1,97 @@
+package env
+
+import "strings"
+
+// parseLine parses one line of a .env file into a key and value. It
+// reports false for blank lines, comments and lines without a key.
+//
+// Unquoted values are used as written, minus surrounding whitespace. A value
+// wrapped in double or single quotes has the quotes removed and \" and \'
+// unescaped; double-quoted values additionally turn \n, \r and \t into the
+// characters they name, and \\ into a single backslash. Anything after the
+// closing quote is ignored, which allows trailing comments. A value whose
+// opening quote is never closed is taken literally.
+func parseLine(line string) (key, value string, ok bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+
+	key, value, found := strings.Cut(line, "=")
+	if !found {
+		return "", "", false
+	}
+	key = strings.TrimSpace(strings.TrimPrefix(key, "export "))
+	if key == "" {
+		return "", "", false
+	}
+
+	value = strings.TrimSpace(value)
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		if unquoted, ok := unquote(value); ok {
+			return key, unquoted, true
+		}
+	}
+	return key, value, true
+}
+
+// unquote returns the contents of the quoted string at the start of s, and
+// false if it is not terminated.
+func unquote(s string) (string, bool) {
+	quote := s[0]
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.String(), true
+		case c == '\\' && i+1 < len(s):
+			next := s[i+1]
+			if r, ok := unescape(quote, next); ok {
+				b.WriteByte(r)
+				i++
+				continue
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false
+}
+
+// unescape returns the character that a backslash followed by c stands for
+// inside a value quoted with quote.
+func unescape(quote, c byte) (byte, bool) {
+	switch c {
+	case '"', '\'':
+		return c, true
+	}
+	if quote != '"' {
+		return 0, false
+	}
+	switch c {
+	case 'n':
+		return '\n', true
+	case 'r':
+		return '\r', true
+	case 't':
+		return '\t', true
+	case '\\':
+		return '\\', true
+	}
+	return 0, false
+}
+
+// singleQuoted reports whether the value on line, a line parseLine accepts,
+// is wrapped in single quotes.
+func singleQuoted(line string) bool {
+	_, value, _ := strings.Cut(line, "=")
+	value = strings.TrimSpace(value)
+	if value == "" || value[0] != '\'' {
+		return false
+	}
+	_, ok := unquote(value)
+	return ok
+}
@@ -0,0 +1,83 @@
+package env
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line      string
+		key, want string
+	}{
+		{"DATABASE_URL=postgres://localhost/db", "DATABASE_URL", "postgres://localhost/db"},
+		{"  KEY =  value  ", "KEY", "value"},
+		{"KEY=a=b", "KEY", "a=b"},
+		{"KEY=", "KEY", ""},
+		{"export KEY=value", "KEY", "value"},
+		{"KEY=unquoted # kept", "KEY", "unquoted # kept"},
+		{`KEY="quoted value"`, "KEY", "quoted value"},
+		{`KEY='single quoted'`, "KEY", "single quoted"},
+		{`DATABASE_URL="postgres://user:p@ss\"word@host/db"`, "DATABASE_URL", `postgres://user:p@ss"word@host/db`},
+		{`KEY='it\'s'`, "KEY", "it's"},
+		{`KEY="line1\nline2"`, "KEY", "line1\nline2"},
+		{`KEY='line1\nline2'`, "KEY", `line1\nline2`},
+		{`KEY="back\\slash"`, "KEY", `back\slash`},
+		{`KEY="unknown \q escape"`, "KEY", `unknown \q escape`},
+		{`KEY="value" # comment`, "KEY", "value"},
+		{`KEY=""`, "KEY", ""},
+		{`KEY="unterminated`, "KEY", `"unterminated`},
+	}
+
+	for _, tt := range tests {
+		key, value, ok := parseLine(tt.line)
+		if !ok || key != tt.key || value != tt.want {
+			t.Errorf("parseLine(%q) = %q, %q, %v; want %q, %q, true", tt.line, key, value, ok, tt.key, tt.want)
+		}
+	}
+}
+
+func TestParseLineSkipsNonAssignments(t *testing.T) {
+	for _, line := range []string{"", "   ", "# KEY=value", "  # comment", "no equals sign", "=value"} {
+		if key, value, ok := parseLine(line); ok {
+			t.Errorf("parseLine(%q) = %q, %q, true; want false", line, key, value)
+		}
+	}
+}
+
+func FuzzParseEnvLine(f *testing.F) {
+	for _, seed := range []string{
+		"KEY=value",
+		`KEY="a\"b\nc"`,
+		`KEY='a\'b'`,
+		`KEY="unterminated`,
+		`KEY="\`,
+		"# comment",
+		"export KEY= spaced ",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		key, value, ok := parseLine(line)
+		if !ok {
+			return
+		}
+		if key == "" || key != strings.TrimSpace(key) || strings.Contains(key, "=") {
+			t.Errorf("parseLine(%q) returned invalid key %q", line, key)
+		}
+		if len(value) > len(line) {
+			t.Errorf("parseLine(%q) returned value %q longer than the line", line, value)
+		}
+
+		// Values without quotes or escapes must parse exactly as the
+		// original split-on-the-first-equals parser did.
+		trimmed := strings.TrimSpace(line)
+		if !strings.ContainsAny(trimmed, `"'\`) && !strings.HasPrefix(trimmed, "export ") {
+			_, raw, _ := strings.Cut(trimmed, "=")
+			if value != strings.TrimSpace(raw) {
+				t.Errorf("parseLine(%q) value = %q, want %q", line, value, strings.TrimSpace(raw))
+			}
+		}
+	})
+}
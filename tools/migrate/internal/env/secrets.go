@@ -0,0 +1,31 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// fileSecretKeys lists the variables that may instead be provided as a path
+// in <KEY>_FILE, the convention used by the official Docker images for
+// secrets mounted as files.
+var fileSecretKeys = []string{"DATABASE_URL"}
+
+// ResolveFileSecrets sets each supported variable from the trimmed contents
+// of the file named by its _FILE counterpart. A variable that is already set
+// is left untouched so that explicit values keep the highest priority.
+func (l *EnvLoader) ResolveFileSecrets() error {
+	for _, key := range fileSecretKeys {
+		path := l.Getenv(key + "_FILE")
+		if path == "" || l.Getenv(key) != "" {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read %s_FILE: %w", key, err)
+		}
+		l.Set(key, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
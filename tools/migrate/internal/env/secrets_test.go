@@ -0,0 +1,47 @@
+package env
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveFileSecretsReadsDatabaseURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "database_url")
+	writeFile(t, path, "postgres://user:pass@db:5432/app\n")
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DATABASE_URL_FILE", path)
+
+	loader := NewFileEnvLoader(nil)
+	if err := loader.ResolveFileSecrets(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := loader.Getenv("DATABASE_URL"), "postgres://user:pass@db:5432/app"; got != want {
+		t.Errorf("DATABASE_URL = %q, want %q", got, want)
+	}
+}
+
+func TestResolveFileSecretsKeepsExplicitValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "database_url")
+	writeFile(t, path, "postgres://from-file")
+	t.Setenv("DATABASE_URL", "postgres://explicit")
+	t.Setenv("DATABASE_URL_FILE", path)
+
+	loader := NewFileEnvLoader(nil)
+	if err := loader.ResolveFileSecrets(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := loader.Getenv("DATABASE_URL"), "postgres://explicit"; got != want {
+		t.Errorf("DATABASE_URL = %q, want %q", got, want)
+	}
+}
+
+func TestResolveFileSecretsMissingFile(t *testing.T) {
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DATABASE_URL_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	if err := NewFileEnvLoader(nil).ResolveFileSecrets(); err == nil {
+		t.Fatal("expected an error for a missing DATABASE_URL_FILE")
+	}
+}
@@ -0,0 +1,86 @@
+// Package lock serializes migration runs across processes using a PostgreSQL
+// session-level advisory lock.
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MigrationKey is the advisory lock key shared by every instance of the tool.
+const MigrationKey int64 = 0x6d696772617465 // "migrate"
+
+const (
+	initialBackoff = 100 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+)
+
+// ErrTimeout is returned by Acquire when the lock is still held by another
+// session after the timeout has elapsed.
+var ErrTimeout = errors.New("timed out waiting for migration lock")
+
+// Lock is a held advisory lock. Advisory locks belong to the session that
+// took them, so the lock keeps its own connection open until Release.
+type Lock struct {
+	conn *sql.Conn
+	key  int64
+}
+
+// Acquire takes the advisory lock identified by key, retrying with
+// exponential back-off until the lock is granted or timeout elapses. A
+// timeout of 0 waits for as long as ctx allows.
+func Acquire(ctx context.Context, db *sql.DB, key int64, timeout time.Duration) (*Lock, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	backoff := initialBackoff
+	for {
+		var acquired bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired)
+		if err != nil {
+			conn.Close()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, ErrTimeout
+			}
+			return nil, fmt.Errorf("acquire advisory lock: %w", err)
+		}
+		if acquired {
+			return &Lock{conn: conn, key: key}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, ErrTimeout
+			}
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// Release unlocks the advisory lock and closes the underlying connection.
+func (l *Lock) Release(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	if closeErr := l.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+package lock_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/internal/lock"
+	"github.com/crypto-bot/tools/migrate/migratetest"
+)
+
+// These tests run against a PostgreSQL container and are skipped without
+// docker.
+
+func TestMain(m *testing.M) {
+	os.Exit(migratetest.Main(m))
+}
+
+// testKey keeps the tests clear of a migration the tool itself may run
+// against the same server.
+const testKey int64 = 0x6c6f636b74657374 // "locktest"
+
+func TestAcquireContended(t *testing.T) {
+	db := migratetest.NewDB(t)
+	ctx := context.Background()
+
+	first, err := lock.Acquire(ctx, db, testKey, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if _, err := lock.Acquire(ctx, db, testKey, 300*time.Millisecond); !errors.Is(err, lock.ErrTimeout) {
+		t.Fatalf("second Acquire while the lock is held: err = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("second Acquire gave up after %s, want about 300ms", elapsed)
+	}
+
+	if err := first.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	second, err := lock.Acquire(ctx, db, testKey, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+	second.Release(ctx)
+}
+
+func TestAcquireNoTimeoutWaits(t *testing.T) {
+	db := migratetest.NewDB(t)
+	ctx := context.Background()
+
+	first, err := lock.Acquire(ctx, db, testKey, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		l, err := lock.Acquire(ctx, db, testKey, 0)
+		if err == nil {
+			l.Release(ctx)
+		}
+		acquired <- err
+	}()
+
+	select {
+	case err := <-acquired:
+		t.Fatalf("Acquire with no timeout returned while the lock was held: %v", err)
+	case <-time.After(time.Second):
+	}
+
+	if err := first.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("Acquire with no timeout: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Acquire with no timeout did not return after the lock was released")
+	}
+}
+
+// TestLockReleasedWhenSessionEnds checks that a process that dies holding
+// the lock does not keep it: its session ending releases it.
+func TestLockReleasedWhenSessionEnds(t *testing.T) {
+	db := migratetest.NewDB(t)
+	ctx := context.Background()
+
+	if _, err := lock.Acquire(ctx, db, testKey, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// End the holder's session as the server does when its process exits.
+	var terminated bool
+	err := db.QueryRowContext(ctx, `
+		SELECT pg_terminate_backend(pid) FROM pg_locks
+		WHERE locktype = 'advisory' AND granted
+		  AND classid = ($1::bigint >> 32)::oid AND objid = ($1::bigint & 4294967295)::oid`,
+		testKey).Scan(&terminated)
+	if errors.Is(err, sql.ErrNoRows) {
+		t.Fatal("the lock is not held")
+	}
+	if err != nil || !terminated {
+		t.Fatalf("terminate the lock holder: %v", err)
+	}
+
+	l, err := lock.Acquire(ctx, db, testKey, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Acquire after the holder's session ended: %v", err)
+	}
+	l.Release(ctx)
+}
@@ -0,0 +1,46 @@
+// Package pg opens PostgreSQL connections for the parts of the tool that talk
+// to the database directly instead of going through Cargo.
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Open connects to databaseURL and verifies that the server is reachable.
+func Open(ctx context.Context, databaseURL string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// OpenWithStatementTimeout is Open for sessions whose statements the server
+// cancels after timeout, so that a slow database makes a query fail rather
+// than hang.
+func OpenWithStatementTimeout(ctx context.Context, databaseURL string, timeout time.Duration) (*sql.DB, error) {
+	cfg, err := pgx.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	cfg.RuntimeParams["statement_timeout"] = strconv.FormatInt(timeout.Milliseconds(), 10)
+
+	db := stdlib.OpenDB(*cfg)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,100 @@
+// Package telemetry exports OpenTelemetry traces of migration runs.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// shutdownTimeout bounds exporting the trace before the tool exits.
+const shutdownTimeout = 5 * time.Second
+
+// Tracer records one span per migration run. A nil *Tracer is valid and
+// records nothing, which is what the tool uses when no endpoint is set.
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// New returns a Tracer exporting over OTLP/HTTP to endpointURL, such as
+// http://otel-collector:4318.
+func New(ctx context.Context, endpointURL, version string) (*Tracer, error) {
+	// Export failures surface asynchronously; log them like every other
+	// diagnostic instead of through the standard library logger.
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		slog.Warn(fmt.Sprintf("Warning: tracing: %v", err))
+	}))
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpointURL))
+	if err != nil {
+		return nil, err
+	}
+
+	res := resource.NewSchemaless(
+		semconv.ServiceName("migrate"),
+		semconv.ServiceVersion(version),
+	)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	return &Tracer{provider: provider, tracer: provider.Tracer("github.com/crypto-bot/tools/migrate")}, nil
+}
+
+// Span is an in-progress migration run.
+type Span struct {
+	span  trace.Span
+	start time.Time
+}
+
+// Start opens a root span named migrate.<command> against database.
+func (t *Tracer) Start(command, database string) *Span {
+	if t == nil {
+		return nil
+	}
+	_, span := t.tracer.Start(context.Background(), "migrate."+command,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			attribute.String("db.name", database),
+		))
+	return &Span{span: span, start: time.Now()}
+}
+
+// End records the run's duration and exit code and closes the span.
+func (s *Span) End(exitCode int) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(
+		attribute.Int64("migrate.duration_ms", time.Since(s.start).Milliseconds()),
+		attribute.Int("migrate.exit_code", exitCode),
+	)
+	if exitCode != 0 {
+		s.span.SetStatus(codes.Error, "migration failed")
+	} else {
+		s.span.SetStatus(codes.Ok, "")
+	}
+	s.span.End()
+}
+
+// Shutdown exports any spans still buffered.
+func (t *Tracer) Shutdown() error {
+	if t == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return t.provider.Shutdown(ctx)
+}
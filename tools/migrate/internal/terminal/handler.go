@@ -0,0 +1,129 @@
+package terminal
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	green  = "\x1b[32m"
+	yellow = "\x1b[33m"
+	red    = "\x1b[31m"
+	bold   = "\x1b[1m"
+	reset  = "\x1b[0m"
+)
+
+// StreamKey is the attribute under which output relayed from a child process
+// records the stream it was written to, "stdout" or "stderr". Handler
+// writes such records to that stream verbatim.
+const StreamKey = "stream"
+
+// Handler is a slog.Handler for people reading a terminal. It prints just the
+// message and any attributes as key=value, coloring the line by level when
+// the destination is a terminal. Info and success go to stdout; warnings and
+// errors go to stderr.
+type Handler struct {
+	level    slog.Leveler
+	out      io.Writer
+	err      io.Writer
+	colorOut bool
+	colorErr bool
+
+	mu     *sync.Mutex
+	attrs  []slog.Attr
+	prefix string
+}
+
+// NewHandler returns a Handler for the process's stdout and stderr that
+// drops records below level. Colors are used only for streams that are
+// terminals, and never when noColor is set or the NO_COLOR environment
+// variable is present (see https://no-color.org).
+func NewHandler(noColor bool, level slog.Leveler) *Handler {
+	noColor = noColor || os.Getenv("NO_COLOR") != ""
+	return &Handler{
+		level:    level,
+		out:      os.Stdout,
+		err:      os.Stderr,
+		colorOut: !noColor && IsTerminal(os.Stdout),
+		colorErr: !noColor && IsTerminal(os.Stderr),
+		mu:       &sync.Mutex{},
+	}
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	w, color := h.out, h.colorOut
+	if r.Level >= slog.LevelWarn {
+		w, color = h.err, h.colorErr
+	}
+
+	var line strings.Builder
+	line.WriteString(r.Message)
+	raw := false
+	write := func(a slog.Attr) {
+		if a.Key == StreamKey && h.prefix == "" {
+			raw = true
+			if a.Value.String() == "stderr" {
+				w = h.err
+			} else {
+				w = h.out
+			}
+			return
+		}
+		line.WriteString(" " + h.prefix + a.Key + "=" + a.Value.String())
+	}
+	for _, a := range h.attrs {
+		write(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		write(a)
+		return true
+	})
+
+	text := line.String()
+	if raw {
+		text = r.Message
+	} else if color {
+		text = levelColor(r.Level) + text + reset
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(w, text+"\n")
+	return err
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	for i := len(h.attrs); i < len(clone.attrs); i++ {
+		clone.attrs[i].Key = h.prefix + clone.attrs[i].Key
+	}
+	return &clone
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return red
+	case level >= slog.LevelWarn:
+		return bold + yellow
+	case level >= LevelSuccess:
+		return green
+	default:
+		return yellow
+	}
+}
@@ -0,0 +1,54 @@
+// Package terminal renders the tool's human-facing output.
+package terminal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// LevelSuccess sits between info and warning so that a successful outcome
+// can be highlighted on a terminal; JSON logs report it as INFO.
+const LevelSuccess = slog.LevelInfo + 2
+
+// Printer is a printf-style front end to a slog.Logger for status lines.
+type Printer struct {
+	logger *slog.Logger
+}
+
+// NewPrinter returns a Printer that logs to logger.
+func NewPrinter(logger *slog.Logger) *Printer {
+	return &Printer{logger: logger}
+}
+
+// IsTerminal reports whether f is attached to a terminal.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Info logs status information.
+func (p *Printer) Info(format string, args ...any) {
+	p.log(slog.LevelInfo, format, args...)
+}
+
+// Success logs a successful outcome.
+func (p *Printer) Success(format string, args ...any) {
+	p.log(LevelSuccess, format, args...)
+}
+
+// Warn logs a warning.
+func (p *Printer) Warn(format string, args ...any) {
+	p.log(slog.LevelWarn, format, args...)
+}
+
+// Error logs a failure.
+func (p *Printer) Error(format string, args ...any) {
+	p.log(slog.LevelError, format, args...)
+}
+
+func (p *Printer) log(level slog.Level, format string, args ...any) {
+	p.logger.Log(context.Background(), level, fmt.Sprintf(format, args...))
+}
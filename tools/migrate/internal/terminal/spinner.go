@@ -0,0 +1,96 @@
+package terminal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const spinnerInterval = 100 * time.Millisecond
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// clearLine returns the cursor to the start of the line and erases it.
+const clearLine = "\r\x1b[K"
+
+// Spinner animates a single status line on stderr, with the time elapsed
+// since Start, until Stop is called. It renders only when both stdout and
+// stderr are terminals and neither NO_COLOR nor CI is set; otherwise Start
+// and Stop do nothing.
+type Spinner struct {
+	w       io.Writer
+	message string
+	enabled bool
+
+	start    time.Time
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSpinner returns a stopped spinner that shows message.
+func NewSpinner(message string) *Spinner {
+	return &Spinner{
+		w:       os.Stderr,
+		message: message,
+		enabled: os.Getenv("NO_COLOR") == "" && os.Getenv("CI") == "" &&
+			IsTerminal(os.Stdout) && IsTerminal(os.Stderr),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Start begins animating the spinner in a goroutine.
+func (s *Spinner) Start() {
+	s.start = time.Now()
+	if !s.enabled {
+		close(s.done)
+		return
+	}
+	go s.run()
+}
+
+// Elapsed returns the time since Start.
+func (s *Spinner) Elapsed() time.Duration {
+	return time.Since(s.start)
+}
+
+// Stop halts the animation and overwrites the spinner line with final, or
+// just erases it when final is empty. Calling Stop more than once, or on a
+// spinner that was never started, has no effect.
+func (s *Spinner) Stop(final string) {
+	if s.start.IsZero() {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		if !s.enabled {
+			return
+		}
+		fmt.Fprint(s.w, clearLine)
+		if final != "" {
+			fmt.Fprintln(s.w, final)
+		}
+	})
+}
+
+func (s *Spinner) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		elapsed := s.Elapsed().Truncate(time.Second)
+		fmt.Fprintf(s.w, "%s%s %s (%s)", clearLine, spinnerFrames[frame%len(spinnerFrames)], s.message, elapsed)
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/crypto-bot/tools/migrate/lint"
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// runLint checks every up migration against the lint rules, with the
+// severities configured under lint.rules in the configuration file. It
+// fails if any violation is an error; warnings are only reported.
+func runLint(cfg Config) int {
+	linter, err := lint.NewLinter(lint.Rules(), cfg.File.Lint.Rules)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	files, err := migrations.Scan(cfg.SQLDir())
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	var errors, warnings int
+	for _, file := range files {
+		if file.UpPath == "" {
+			continue
+		}
+		violations, err := linter.LintFile(file.UpPath)
+		if err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		for _, v := range violations {
+			if v.Severity == lint.SeverityError {
+				errors++
+				printer.Error("%s", v)
+			} else {
+				warnings++
+				printer.Warn("%s", v)
+			}
+		}
+	}
+
+	switch {
+	case errors > 0:
+		printer.Error("%d error(s) and %d warning(s) in %d migration(s)", errors, warnings, len(files))
+		return 1
+	case warnings > 0:
+		printer.Warn("%d warning(s) in %d migration(s)", warnings, len(files))
+	default:
+		printer.Success("No problems found in %d migration(s)", len(files))
+	}
+	return 0
+}
@@ -0,0 +1,119 @@
+// Package lint checks migration SQL for mistakes that only show up once a
+// migration meets production data, such as new nullable columns or tables
+// dropped by an up migration.
+package lint
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Severity is how serious a violation is. Errors fail the lint run;
+// warnings are only reported.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	// SeverityOff disables a rule in the configuration.
+	SeverityOff Severity = "off"
+)
+
+// Violation is a problem found in a migration file.
+type Violation struct {
+	File     string
+	Line     int
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s [%s]", v.File, v.Line, v.Severity, v.Message, v.Rule)
+}
+
+// Rule checks the statements of one up migration.
+type Rule interface {
+	// Name identifies the rule in output and in the lint configuration.
+	Name() string
+	// Severity is the rule's default severity.
+	Severity() Severity
+	Check(file string, statements []Statement) []Violation
+}
+
+// Rules returns the built-in rules.
+func Rules() []Rule {
+	return []Rule{
+		AddColumnNullable{},
+		DropTable{},
+		MissingSemicolon{},
+		AlterTableLock{},
+	}
+}
+
+// Linter runs a set of rules over migration files.
+type Linter struct {
+	rules    []Rule
+	severity map[string]Severity
+}
+
+// NewLinter returns a Linter running rules, with the severities in
+// overrides, keyed by rule name, replacing the rules' defaults. A rule
+// overridden to off is not run.
+func NewLinter(rules []Rule, overrides map[string]string) (*Linter, error) {
+	l := &Linter{severity: make(map[string]Severity)}
+	names := make([]string, len(rules))
+	for i, rule := range rules {
+		names[i] = rule.Name()
+		l.severity[rule.Name()] = rule.Severity()
+	}
+
+	for name, value := range overrides {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("lint: unknown rule %q (expected one of %s)", name, strings.Join(names, ", "))
+		}
+		switch severity := Severity(strings.ToLower(value)); severity {
+		case SeverityError, SeverityWarning, SeverityOff:
+			l.severity[name] = severity
+		default:
+			return nil, fmt.Errorf("lint: rule %s: unknown severity %q (expected error, warning or off)", name, value)
+		}
+	}
+
+	for _, rule := range rules {
+		if l.severity[rule.Name()] != SeverityOff {
+			l.rules = append(l.rules, rule)
+		}
+	}
+	return l, nil
+}
+
+// LintFile checks the migration file at path, returning its violations
+// ordered by line.
+func (l *Linter) LintFile(path string) ([]Violation, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return l.Lint(path, string(src)), nil
+}
+
+// Lint checks src, the contents of the migration file called file.
+func (l *Linter) Lint(file, src string) []Violation {
+	statements := Split(src)
+	var violations []Violation
+	for _, rule := range l.rules {
+		for _, v := range rule.Check(file, statements) {
+			v.Rule = rule.Name()
+			v.Severity = l.severity[rule.Name()]
+			violations = append(violations, v)
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Line < violations[j].Line
+	})
+	return violations
+}
@@ -0,0 +1,110 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	src := `-- create the table; not a statement
+CREATE TABLE a (
+    note text DEFAULT 'x;y',   -- trailing; comment
+    "odd;name" int
+);
+/* block /* nested; */ still comment; */
+CREATE FUNCTION f() RETURNS trigger AS $body$
+BEGIN
+    RETURN NEW; -- inside the body
+END;
+$body$ LANGUAGE plpgsql;
+INSERT INTO a (note) VALUES (E'it\'s; fine')
+-- migrate:down
+DROP TABLE a;
+`
+	statements := Split(src)
+	if len(statements) != 3 {
+		t.Fatalf("Split() returned %d statements, want 3: %+v", len(statements), statements)
+	}
+	want := []struct {
+		prefix     string
+		line       int
+		terminated bool
+	}{
+		{"CREATE TABLE a", 2, true},
+		{"CREATE FUNCTION f()", 7, true},
+		{"INSERT INTO a", 12, false},
+	}
+	for i, w := range want {
+		s := statements[i]
+		if !strings.HasPrefix(s.SQL, w.prefix) || s.Line != w.line || s.Terminated != w.terminated {
+			t.Errorf("statement %d = {%q, line %d, terminated %v}, want prefix %q, line %d, terminated %v",
+				i, s.SQL, s.Line, s.Terminated, w.prefix, w.line, w.terminated)
+		}
+	}
+	if strings.Contains(statements[0].SQL, "trailing") {
+		t.Errorf("comment kept in %q", statements[0].SQL)
+	}
+}
+
+func lintRules(t *testing.T, src string, overrides map[string]string) []Violation {
+	t.Helper()
+	linter, err := NewLinter(Rules(), overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return linter.Lint("000001_test.up.sql", src)
+}
+
+func rulesOf(violations []Violation) []string {
+	rules := make([]string, len(violations))
+	for i, v := range violations {
+		rules[i] = v.Rule
+	}
+	return rules
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{"nullable column", "ALTER TABLE orders ADD COLUMN note text;", []string{"add-column-nullable"}},
+		{"not null column", "ALTER TABLE orders ADD COLUMN note text NOT NULL DEFAULT '';", nil},
+		{"default column", "ALTER TABLE orders ADD note text DEFAULT 'a, b', ADD COLUMN id2 serial PRIMARY KEY;", nil},
+		{"constraint", "ALTER TABLE orders ADD CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES users (id) NOT VALID;", nil},
+		{"validated constraint", "ALTER TABLE orders ADD CONSTRAINT positive CHECK (amount > 0);", []string{"alter-table-lock"}},
+		{"drop table", "DROP TABLE IF EXISTS legacy_orders CASCADE;", []string{"drop-table"}},
+		{"drop column", "ALTER TABLE orders DROP COLUMN legacy;", nil},
+		{"type change", "ALTER TABLE orders ALTER COLUMN amount TYPE numeric(20, 8);", []string{"alter-table-lock"}},
+		{"set not null", "ALTER TABLE orders ALTER amount SET NOT NULL;", []string{"alter-table-lock"}},
+		{"missing semicolon", "CREATE INDEX orders_user ON orders (user_id)", []string{"missing-semicolon"}},
+		{"drop table in a string", "INSERT INTO notes VALUES ('DROP TABLE orders');", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rulesOf(lintRules(t, tt.sql, nil))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLinterOverrides(t *testing.T) {
+	src := "DROP TABLE legacy;\nALTER TABLE orders ADD COLUMN note text"
+	violations := lintRules(t, src, map[string]string{"drop-table": "warning", "missing-semicolon": "off"})
+	if len(violations) != 2 {
+		t.Fatalf("violations = %v, want drop-table and add-column-nullable", violations)
+	}
+	if violations[0].Rule != "drop-table" || violations[0].Severity != SeverityWarning || violations[0].Line != 1 {
+		t.Errorf("violations[0] = %+v, want a drop-table warning on line 1", violations[0])
+	}
+
+	if _, err := NewLinter(Rules(), map[string]string{"no-such-rule": "error"}); err == nil {
+		t.Error("NewLinter() accepted an unknown rule")
+	}
+	if _, err := NewLinter(Rules(), map[string]string{"drop-table": "fatal"}); err == nil {
+		t.Error("NewLinter() accepted an unknown severity")
+	}
+}
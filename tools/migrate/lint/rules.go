@@ -0,0 +1,155 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	alterTable    = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?("(?:[^"]|"")+"|[\w.]+)\s+(.*)$`)
+	addColumn     = regexp.MustCompile(`(?is)^ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?("(?:[^"]|"")+"|\w+)`)
+	addConstraint = regexp.MustCompile(`(?is)^ADD\s+(?:CONSTRAINT|PRIMARY\s+KEY|UNIQUE|FOREIGN\s+KEY|CHECK|EXCLUDE)\b`)
+	notNullable   = regexp.MustCompile(`(?is)\b(?:NOT\s+NULL|DEFAULT|PRIMARY\s+KEY|GENERATED)\b`)
+	dropTable     = regexp.MustCompile(`(?is)^DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(.+?)(?:\s+(?:CASCADE|RESTRICT))?$`)
+	changeType    = regexp.MustCompile(`(?is)^ALTER\s+(?:COLUMN\s+)?("(?:[^"]|"")+"|\w+)\s+(?:SET\s+DATA\s+)?TYPE\b`)
+	setNotNull    = regexp.MustCompile(`(?is)^ALTER\s+(?:COLUMN\s+)?("(?:[^"]|"")+"|\w+)\s+SET\s+NOT\s+NULL\b`)
+	validated     = regexp.MustCompile(`(?is)^ADD\s+(?:CONSTRAINT\s+\S+\s+)?(FOREIGN\s+KEY|CHECK)\b`)
+	notValid      = regexp.MustCompile(`(?is)\bNOT\s+VALID\s*$`)
+)
+
+// alterTableActions returns the table an ALTER TABLE statement changes and
+// its comma-separated actions, or false for any other statement.
+func alterTableActions(sql string) (string, []string, bool) {
+	m := alterTable.FindStringSubmatch(sql)
+	if m == nil {
+		return "", nil, false
+	}
+	return m[1], splitTopLevel(m[2]), true
+}
+
+// splitTopLevel splits s at the commas that are outside parentheses,
+// string literals and quoted identifiers.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, last := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[last:i]))
+			last = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(s[last:]))
+}
+
+// AddColumnNullable flags columns added without NOT NULL or a DEFAULT, which
+// leaves every existing row with NULL in them.
+type AddColumnNullable struct{}
+
+func (AddColumnNullable) Name() string       { return "add-column-nullable" }
+func (AddColumnNullable) Severity() Severity { return SeverityWarning }
+
+func (AddColumnNullable) Check(file string, statements []Statement) []Violation {
+	var violations []Violation
+	for _, stmt := range statements {
+		table, actions, ok := alterTableActions(stmt.SQL)
+		if !ok {
+			continue
+		}
+		for _, action := range actions {
+			m := addColumn.FindStringSubmatch(action)
+			if m == nil || addConstraint.MatchString(action) || notNullable.MatchString(action) {
+				continue
+			}
+			violations = append(violations, Violation{
+				File:    file,
+				Line:    stmt.Line,
+				Message: fmt.Sprintf("column %s is added to %s without NOT NULL or DEFAULT, so existing rows get NULL", m[1], table),
+			})
+		}
+	}
+	return violations
+}
+
+// DropTable flags tables dropped by an up migration, which loses their data
+// for good once it is applied.
+type DropTable struct{}
+
+func (DropTable) Name() string       { return "drop-table" }
+func (DropTable) Severity() Severity { return SeverityError }
+
+func (DropTable) Check(file string, statements []Statement) []Violation {
+	var violations []Violation
+	for _, stmt := range statements {
+		if m := dropTable.FindStringSubmatch(stmt.SQL); m != nil {
+			violations = append(violations, Violation{
+				File:    file,
+				Line:    stmt.Line,
+				Message: fmt.Sprintf("DROP TABLE %s in an up migration destroys its data", m[1]),
+			})
+		}
+	}
+	return violations
+}
+
+// MissingSemicolon flags a final statement without a terminating
+// semicolon, which breaks when the migration is concatenated with others,
+// as export does.
+type MissingSemicolon struct{}
+
+func (MissingSemicolon) Name() string       { return "missing-semicolon" }
+func (MissingSemicolon) Severity() Severity { return SeverityError }
+
+func (MissingSemicolon) Check(file string, statements []Statement) []Violation {
+	if len(statements) == 0 || statements[len(statements)-1].Terminated {
+		return nil
+	}
+	last := statements[len(statements)-1]
+	return []Violation{{File: file, Line: last.Line, Message: "statement is not terminated by a semicolon"}}
+}
+
+// AlterTableLock flags ALTER TABLE actions that hold an ACCESS EXCLUSIVE
+// lock while they rewrite or scan the whole table. Inside the migration's
+// transaction the lock is kept until it commits, blocking every reader and
+// writer of the table meanwhile.
+type AlterTableLock struct{}
+
+func (AlterTableLock) Name() string       { return "alter-table-lock" }
+func (AlterTableLock) Severity() Severity { return SeverityWarning }
+
+func (AlterTableLock) Check(file string, statements []Statement) []Violation {
+	var violations []Violation
+	for _, stmt := range statements {
+		table, actions, ok := alterTableActions(stmt.SQL)
+		if !ok {
+			continue
+		}
+		for _, action := range actions {
+			var message string
+			if m := changeType.FindStringSubmatch(action); m != nil {
+				message = fmt.Sprintf("changing the type of %s.%s rewrites the table under an ACCESS EXCLUSIVE lock", table, m[1])
+			} else if m := setNotNull.FindStringSubmatch(action); m != nil {
+				message = fmt.Sprintf("SET NOT NULL on %s.%s scans the table under an ACCESS EXCLUSIVE lock; add a CHECK (%s IS NOT NULL) NOT VALID constraint and validate it first", table, m[1], m[1])
+			} else if m := validated.FindStringSubmatch(action); m != nil && !notValid.MatchString(action) {
+				message = fmt.Sprintf("adding a %s constraint to %s checks every row under lock; add it NOT VALID and run VALIDATE CONSTRAINT separately", strings.ToUpper(strings.Join(strings.Fields(m[1]), " ")), table)
+			}
+			if message != "" {
+				violations = append(violations, Violation{File: file, Line: stmt.Line, Message: message})
+			}
+		}
+	}
+	return violations
+}
@@ -0,0 +1,151 @@
+package lint
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Statement is one SQL statement of a migration file.
+type Statement struct {
+	// SQL is the statement without its terminating semicolon, with each
+	// comment replaced by a space. String literals and quoted identifiers
+	// are kept as written.
+	SQL string
+	// Line is the line the statement starts on, counting from 1.
+	Line int
+	// Terminated reports whether the statement ends in a semicolon.
+	Terminated bool
+}
+
+// Split scans src into statements. It understands the lexical structure of
+// PostgreSQL well enough not to split inside comments, string literals
+// (including E'...' escape strings and dollar-quoted ones) or quoted
+// identifiers, without parsing the statements themselves. Scanning stops
+// at a `-- migrate:down` line, so a single-file migration's down section is
+// not included.
+func Split(src string) []Statement {
+	var (
+		statements []Statement
+		buf        strings.Builder
+		line       = 1
+		start      = 0
+	)
+	emit := func(terminated bool) {
+		if sql := strings.TrimSpace(buf.String()); sql != "" {
+			statements = append(statements, Statement{SQL: sql, Line: start, Terminated: terminated})
+		}
+		buf.Reset()
+		start = 0
+	}
+	// take copies src[i:j] into the statement and returns j.
+	take := func(i, j int) int {
+		line += strings.Count(src[i:j], "\n")
+		buf.WriteString(src[i:j])
+		return j
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		if start == 0 && !unicode.IsSpace(rune(c)) && c != ';' && !strings.HasPrefix(src[i:], "--") && !strings.HasPrefix(src[i:], "/*") {
+			start = line
+		}
+		switch {
+		case strings.HasPrefix(src[i:], "--"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			if strings.EqualFold(strings.TrimSpace(src[i:i+end]), "-- migrate:down") {
+				emit(false)
+				return statements
+			}
+			buf.WriteByte(' ')
+			i += end
+		case strings.HasPrefix(src[i:], "/*"):
+			end := blockCommentEnd(src, i)
+			line += strings.Count(src[i:end], "\n")
+			buf.WriteByte(' ')
+			i = end
+		case c == '\'':
+			escapes := i > 0 && (src[i-1] == 'E' || src[i-1] == 'e') && (i == 1 || !isIdentByte(src[i-2]))
+			i = take(i, quotedEnd(src, i, '\'', escapes))
+		case c == '"':
+			i = take(i, quotedEnd(src, i, '"', false))
+		case c == '$' && (i == 0 || !isIdentByte(src[i-1])):
+			if tag, ok := dollarTag(src[i:]); ok {
+				end := strings.Index(src[i+len(tag):], tag)
+				if end < 0 {
+					i = take(i, len(src))
+				} else {
+					i = take(i, i+len(tag)+end+len(tag))
+				}
+				continue
+			}
+			i = take(i, i+1)
+		case c == ';':
+			emit(true)
+			i++
+		default:
+			i = take(i, i+1)
+		}
+	}
+	emit(false)
+	return statements
+}
+
+// blockCommentEnd returns the offset just past the comment starting at i.
+// PostgreSQL block comments nest.
+func blockCommentEnd(src string, i int) int {
+	depth := 0
+	for i < len(src) {
+		switch {
+		case strings.HasPrefix(src[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(src[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(src)
+}
+
+// quotedEnd returns the offset just past the quoted text starting at i.
+// A doubled quote stands for itself, as does a backslash-escaped one when
+// escapes is set.
+func quotedEnd(src string, i int, quote byte, escapes bool) int {
+	for j := i + 1; j < len(src); j++ {
+		switch {
+		case escapes && src[j] == '\\':
+			j++
+		case src[j] == quote && j+1 < len(src) && src[j+1] == quote:
+			j++
+		case src[j] == quote:
+			return j + 1
+		}
+	}
+	return len(src)
+}
+
+// dollarTag returns the opening $tag$ of a dollar-quoted string at the
+// start of s.
+func dollarTag(s string) (string, bool) {
+	for j := 1; j < len(s); j++ {
+		switch {
+		case s[j] == '$':
+			return s[:j+1], true
+		case !isIdentByte(s[j]) || (j == 1 && s[j] >= '0' && s[j] <= '9'):
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"github.com/crypto-bot/tools/migrate/tags"
+)
+
+// listTimeout bounds connecting to and querying the database for list.
+const listTimeout = 30 * time.Second
+
+// runList prints every migration file in the SQL directory with its status
+// and tags, read directly from the database so that Cargo is not needed.
+func runList(cfg Config) int {
+	files, err := migrations.Scan(cfg.SQLDir())
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if len(files) == 0 {
+		printer.Info("No migrations found in %s", cfg.SQLDir())
+		return 0
+	}
+
+	state := make([]db.Migration, len(files))
+	for i, file := range files {
+		state[i] = db.Migration{Version: int64(file.Sequence), Name: file.Name}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	state, err = db.NewPostgresRepository(conn).Status(ctx, state)
+	if err != nil {
+		printer.Error("Error: read migration state: %v", err)
+		return 1
+	}
+	list, err := tags.NewPostgresTagger(conn).List(ctx)
+	if err != nil {
+		printer.Error("Error: read tags: %v", err)
+		return 1
+	}
+
+	writeMigrationTable(os.Stdout, state, tags.ByMigration(list))
+	return 0
+}
+
+// writeMigrationTable writes the migrations in state to out with their
+// status and the tags that label them.
+func writeMigrationTable(out io.Writer, state []db.Migration, tagsByMigration map[string][]string) {
+	var namer migrations.MigrationNamer
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT\tTAGS")
+	for _, m := range state {
+		status, appliedAt, tagNames := "Pending", "-", "-"
+		if m.Applied {
+			status = "Applied"
+		}
+		if m.AppliedAt != nil {
+			appliedAt = m.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		if names := tagsByMigration[namer.Base(int(m.Version), m.Name)]; len(names) > 0 {
+			tagNames = strings.Join(names, ", ")
+		}
+		fmt.Fprintf(w, "%06d\t%s\t%s\t%s\t%s\n", m.Version, m.Name, status, appliedAt, tagNames)
+	}
+	w.Flush()
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/crypto-bot/tools/migrate/internal/terminal"
+	"github.com/crypto-bot/tools/migrate/output"
+)
+
+// setupLogging configures the default slog logger, which printer writes
+// through, from LOG_FORMAT (text, json, gcp or aws, default text) and
+// LOG_LEVEL (debug, info, warn or error, default info). Text output is meant
+// for people; JSON goes to stderr, one object per line, for log collectors.
+// The gcp and aws formats are JSON with the field names Cloud Logging and
+// CloudWatch Logs recognise.
+func setupLogging(noColor bool) error {
+	level := slog.LevelInfo
+	if raw := getenv("LOG_LEVEL"); raw != "" {
+		if err := level.UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("invalid LOG_LEVEL %q (expected debug, info, warn or error)", raw)
+		}
+	}
+
+	var handler slog.Handler
+	switch format := strings.ToLower(getenv("LOG_FORMAT")); format {
+	case "", "text":
+		handler = terminal.NewHandler(noColor, level)
+	case "json", "gcp", "aws":
+		replace := reportSuccessAsInfo
+		if format != "json" {
+			replace = cloudLogAttr(format)
+		}
+		handler = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+			Level:       level,
+			ReplaceAttr: replace,
+		})
+		// The spinner redraws its line in place, which would corrupt a
+		// stream of JSON objects.
+		spinnerDisabled = true
+	default:
+		return fmt.Errorf("invalid LOG_FORMAT %q (expected text, json, gcp or aws)", format)
+	}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	printer = terminal.NewPrinter(logger)
+	return nil
+}
+
+func reportSuccessAsInfo(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.LevelKey {
+		if level, ok := a.Value.Any().(slog.Level); ok && level == terminal.LevelSuccess {
+			a.Value = slog.StringValue(slog.LevelInfo.String())
+		}
+	}
+	return a
+}
+
+// cloudLogAttr returns the ReplaceAttr function that renames the standard
+// fields for provider: Cloud Logging reads severity, with WARNING rather
+// than WARN, and message; CloudWatch Logs, as Lambda's JSON format,
+// timestamp, level and message.
+func cloudLogAttr(provider string) func([]string, slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		a = reportSuccessAsInfo(groups, a)
+		if len(groups) > 0 {
+			return a
+		}
+		switch a.Key {
+		case slog.MessageKey:
+			a.Key = "message"
+		case slog.TimeKey:
+			if provider == "aws" {
+				a.Key = "timestamp"
+			}
+		case slog.LevelKey:
+			if provider == "gcp" {
+				a.Key = "severity"
+				if a.Value.String() == slog.LevelWarn.String() {
+					a.Value = slog.StringValue("WARNING")
+				}
+			}
+		}
+		return a
+	}
+}
+
+// cargoRunLine matches the line Cargo prints as it launches the binary.
+var cargoRunLine = regexp.MustCompile(`^\s*Running\s`)
+
+// logWriter relays a child process's output to slog one line at a time.
+// Lines are logged at level, except that Cargo's own build progress is
+// always logged as info.
+type logWriter struct {
+	level  slog.Level
+	stream string
+	buf    []byte
+}
+
+func newLogWriter(level slog.Level, stream string) *logWriter {
+	return &logWriter{level: level, stream: stream}
+}
+
+func (l *logWriter) Write(b []byte) (int, error) {
+	l.buf = append(l.buf, b...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		l.log(string(bytes.TrimRight(l.buf[:i], "\r")))
+		l.buf = l.buf[i+1:]
+	}
+}
+
+// Flush logs any final line that was not terminated by a newline.
+func (l *logWriter) Flush() {
+	if len(l.buf) > 0 {
+		l.log(string(l.buf))
+		l.buf = nil
+	}
+}
+
+func (l *logWriter) log(line string) {
+	level := l.level
+	if cargoBuildLine.MatchString(line) || cargoRunLine.MatchString(line) {
+		level = slog.LevelInfo
+	}
+	slog.Default().Log(context.Background(), level, line, terminal.StreamKey, l.stream)
+}
+
+// filteredWriter feeds a child's stderr through an output.StderrFilter on
+// its way to w, so that the database errors in it can be repeated once the
+// child has exited. Flush waits until everything written has reached w.
+type filteredWriter struct {
+	pipe   *io.PipeWriter
+	filter *output.StderrFilter
+	done   chan struct{}
+}
+
+func newFilteredWriter(w io.Writer) *filteredWriter {
+	pr, pw := io.Pipe()
+	fw := &filteredWriter{pipe: pw, filter: output.NewStderrFilter(pr), done: make(chan struct{})}
+	go func() {
+		defer close(fw.done)
+		io.Copy(w, fw.filter)
+		pr.Close()
+		if f, ok := w.(flusher); ok {
+			f.Flush()
+		}
+	}()
+	return fw
+}
+
+func (fw *filteredWriter) Write(b []byte) (int, error) {
+	return fw.pipe.Write(b)
+}
+
+// Flush ends the stream. It is safe to call more than once.
+func (fw *filteredWriter) Flush() {
+	fw.pipe.Close()
+	<-fw.done
+}
+
+// Errors returns the database errors recognised in the output.
+func (fw *filteredWriter) Errors() []string {
+	<-fw.done
+	return fw.filter.Errors()
+}
@@ -1,85 +1,978 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
+	"slices"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/audit"
+	"github.com/crypto-bot/tools/migrate/config"
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/exitcodes"
+	"github.com/crypto-bot/tools/migrate/internal/env"
+	"github.com/crypto-bot/tools/migrate/internal/lock"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/internal/telemetry"
+	"github.com/crypto-bot/tools/migrate/internal/terminal"
+	"github.com/crypto-bot/tools/migrate/output"
+	"github.com/crypto-bot/tools/migrate/retry"
+)
+
+const (
+	// exitLockContention is returned when another process holds the
+	// migration lock, so orchestrators can tell contention apart from a
+	// failed migration.
+	exitLockContention = 2
+	// exitTimeout follows the POSIX timeout(1) convention.
+	exitTimeout = 124
+	// exitCargoPanic is the exit code of a panicking Rust binary. The
+	// sea-orm migrator panics when it cannot connect to the database, while
+	// failing migrations make it exit with 1.
+	exitCargoPanic = 101
 )
 
+// printer renders every status line the tool prints. It is replaced by
+// setupLogging once the configuration is known.
+var printer = terminal.NewPrinter(slog.New(terminal.NewHandler(false, slog.LevelInfo)))
+
 func main() {
+	os.Exit(run())
+}
+
+// tracer records a span per migration run when --otel-endpoint is set; it is
+// nil, and records nothing, otherwise.
+var tracer *telemetry.Tracer
+
+// environment is the process environment with the .env files and the
+// settings resolved from the configuration file and secret stores merged
+// in. The tool reads its variables, and passes them to the commands it
+// runs, through environment rather than the process environment.
+var environment = env.NewFileEnvLoader(nil)
+
+// getenv returns the value of key in environment.
+func getenv(key string) string {
+	return environment.Getenv(key)
+}
+
+func run() int {
+	db.Getenv = getenv
+
 	if len(os.Args) < 2 {
 		printUsage()
-		os.Exit(1)
+		return 1
+	}
+
+	cfg, err := parseConfig(os.Args[1:])
+	printer = terminal.NewPrinter(slog.New(terminal.NewHandler(cfg.NoColor, slog.LevelInfo)))
+	if err != nil {
+		printer.Error("Error: %v", err)
+		printUsage()
+		return 1
 	}
 
-	loadEnvFile()
+	if cfg.ListEnvironments {
+		return runListEnvironments(cfg)
+	}
 
-	if os.Getenv("DATABASE_URL") == "" {
-		fmt.Println("Error: DATABASE_URL environment variable is not set")
-		os.Exit(1)
+	if err := loadEnv(cfg); err != nil {
+		printer.Error("Error: failed to load .env: %v", err)
+		return 1
 	}
 
-	command := os.Args[1]
-	if command != "up" && command != "down" && command != "status" && command != "fresh" {
-		fmt.Printf("Unknown command: %s\n", command)
-		printUsage()
-		os.Exit(1)
+	applyFileConfig(cfg.File)
+	if cfg.VerifyDatabaseURL != "" {
+		environment.Set("DATABASE_URL", cfg.VerifyDatabaseURL)
 	}
 
-	fmt.Printf("Running migration: %s\n", command)
+	if err := db.CheckMigrationsTable(); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
 
-	projectRoot := filepath.Join("..", "..")
-	migrationDir := filepath.Join(projectRoot, "migration")
+	// MIGRATE_EXTRA_ARGS come before the arguments after --, so that those
+	// given on the command line can override them.
+	extra, err := config.ParseExtraArgs(getenv("MIGRATE_EXTRA_ARGS"))
+	if err != nil {
+		printer.Error("Error: invalid MIGRATE_EXTRA_ARGS: %v", err)
+		return 1
+	}
+	if extra != nil {
+		cfg.ExtraArgs = append(extra, cfg.ExtraArgs...)
+	}
 
-	cmd := exec.Command("cargo", "run", "--", command)
-	cmd.Dir = migrationDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	cmd.Env = os.Environ()
+	if cfg.Engine, err = migrationEngine(cfg); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
 
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("Migration failed: %v\n", err)
-		os.Exit(1)
+	if cfg.CloudRun {
+		if err := setupCloudRun(&cfg); err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
 	}
 
-	fmt.Println("Migration completed successfully")
-}
+	if err := setupLogging(cfg.NoColor); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if cfg.RedactLogs {
+		enableRedaction(cfg)
+	}
+
+	if cfg.OtelEndpoint != "" {
+		tracer, err = telemetry.New(context.Background(), cfg.OtelEndpoint, Version)
+		if err != nil {
+			printer.Error("Error: set up tracing: %v", err)
+			return 1
+		}
+		defer func() {
+			if err := tracer.Shutdown(); err != nil {
+				printer.Warn("Warning: failed to export trace: %v", err)
+			}
+		}()
+	}
+
+	if cfg.Command == "env" {
+		return runEnv(cfg)
+	}
+
+	if err := environment.ResolveFileSecrets(); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
 
-func loadEnvFile() {
-	envPath := filepath.Join("..", "..", ".env")
-	file, err := os.Open(envPath)
+	if cfg.Command == "version" {
+		return runVersion()
+	}
+
+	if cfg.Command == "config" {
+		switch cfg.Args[0] {
+		case "diff":
+			return runConfigDiff(cfg)
+		case "encrypt", "decrypt":
+			return runConfigCrypt(cfg)
+		}
+		return runConfigValidate(cfg)
+	}
+
+	if cfg.Command == "audit-log" {
+		return runAuditLogExport(cfg)
+	}
+
+	if cfg.Command == "doctor" {
+		return runDoctor(cfg)
+	}
+
+	if cfg.Command == "compare" {
+		return runCompare(cfg)
+	}
+
+	if cfg.Command == "init" {
+		return runInit(cfg)
+	}
+
+	if cfg.Command == "create" {
+		if !checkMigrationDir(cfg) {
+			return 1
+		}
+		return runCreate(cfg)
+	}
+
+	if cfg.Command == "export" {
+		if !checkMigrationDir(cfg) {
+			return 1
+		}
+		return runExport(cfg)
+	}
+
+	if cfg.Command == "generate-changelog" {
+		return runGenerateChangelog(cfg)
+	}
+
+	if cfg.Command == "lint" {
+		return runLint(cfg)
+	}
+
+	if cfg.Command == "diff" {
+		return runDiff(cfg)
+	}
+
+	if cfg.Command == "graph" {
+		if !checkMigrationDir(cfg) {
+			return 1
+		}
+		return runGraph(cfg)
+	}
+
+	// diagnose-slow needs no database when it reads a log.
+	if cfg.Command == "diagnose-slow" && cfg.LogFile != "" {
+		return runDiagnoseSlow(cfg)
+	}
+
+	if cfg.Command == "test" {
+		if !checkMigrationDir(cfg) {
+			return 1
+		}
+		return runTest(cfg)
+	}
+
+	if cfg.ShardsFile != "" {
+		if !checkMigrationDir(cfg) || !checkTarget(cfg) {
+			return 1
+		}
+		return runShards(cfg)
+	}
+
+	if err := resolveConfigSecret(cfg.File, cfg.VaultTimeout); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if err := resolveVaultSecret(cfg.VaultTimeout); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if err := resolveGCPSecret(); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if err := resolveSSMParameter(); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	cleanup, err := fetchMigrationDir(&cfg)
 	if err != nil {
-		return
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	defer cleanup()
+
+	// health reports a missing DATABASE_URL as a failed check.
+	if cfg.Command == "health" {
+		return runHealth(cfg)
+	}
+
+	cfg.DatabaseURL = getenv("DATABASE_URL")
+	if cfg.DatabaseURL == "" {
+		printer.Error("Error: DATABASE_URL environment variable is not set")
+		return 1
+	}
+
+	if cfg.Command == "ping" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runPing(cfg)
+	}
+
+	if cfg.Command == "diagnose-slow" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runDiagnoseSlow(cfg)
+	}
+
+	if cfg.Command == "snapshot" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runSnapshot(cfg)
+	}
+
+	if cfg.Command == "seed" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runSeed(cfg)
+	}
+
+	if cfg.Command == "backup" || cfg.Command == "restore" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		if cfg.Command == "restore" {
+			return runRestore(cfg)
+		}
+		return runBackup(cfg)
+	}
+
+	if !checkMigrationDir(cfg) {
+		return 1
+	}
+
+	if cfg.Command == "verify" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runVerify(cfg)
+	}
+
+	if cfg.Command == "benchmark" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runBenchmark(cfg)
+	}
+
+	if cfg.Command == "clean" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runClean(cfg)
+	}
+
+	if cfg.Command == "tidy" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runTidy(cfg)
+	}
+
+	if cfg.Command == "plan" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runPlan(cfg)
+	}
+
+	if cfg.Command == "list" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runList(cfg)
+	}
+
+	if cfg.Command == "check" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runCheck(cfg)
+	}
+
+	if cfg.Command == "import" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runImport(cfg)
+	}
+
+	if cfg.Command == "tag" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runTag(cfg)
+	}
+
+	if cfg.ExitCode {
+		if !checkDatabaseURL(cfg) {
+			return exitcodes.ExitError
+		}
+		return runStatusExitCode(cfg)
+	}
+
+	if !cfg.AppliedSince.IsZero() {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runStatusSince(cfg)
+	}
+
+	if cfg.SinceTag != "" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runStatusSinceTag(cfg)
+	}
+
+	if cfg.WatchStatus {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runStatusWatch(cfg)
+	}
+
+	if cfg.Command == "version-history" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runVersionHistory(cfg)
+	}
+
+	if cfg.PendingCount {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runPendingCount(cfg)
+	}
+
+	if !checkTarget(cfg) {
+		return 1
+	}
+
+	if cfg.Command == "watch" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runWatch(cfg)
+	}
+
+	if cfg.Command == "rollback" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runRollback(cfg)
+	}
+
+	if cfg.Command == "repair" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runRepair(cfg)
+	}
+
+	if cfg.Command == "squash" {
+		if !checkDatabaseURL(cfg) {
+			return 1
+		}
+		return runSquash(cfg)
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	if cfg.Command == "serve" {
+		if !checkDatabaseURL(cfg) {
+			return 1
 		}
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) == 2 {
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-			if os.Getenv(key) == "" {
-				os.Setenv(key, value)
+		return runServe(cfg)
+	}
+
+	if cfg.Only != "" {
+		if _, err := findMigration(cfg, cfg.Only); err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		printer.Warn(onlyWarning)
+		if !cfg.Yes {
+			if err := confirmOnlyInteractive(cfg.Only); err != nil {
+				printer.Error("Error: %v", err)
+				return 1
 			}
 		}
 	}
+
+	if cfg.Command == "fresh" && !cfg.Yes {
+		if err := confirmFreshInteractive(cfg.DatabaseURL); err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+	}
+
+	if cfg.CloudRun {
+		return runCloudRun(cfg)
+	}
+	return migrateDatabase(context.Background(), cfg)
+}
+
+// migrateDatabase runs the configured Cargo command against
+// cfg.DatabaseURL while holding the migration lock, and records the outcome.
+func migrateDatabase(ctx context.Context, cfg Config) (exitCode int) {
+	span := tracer.Start(cfg.Command, databaseName(cfg.DatabaseURL))
+	defer func() { span.End(exitCode) }()
+
+	if !checkDatabaseURL(cfg) {
+		return 1
+	}
+
+	if cfg.Command != "status" {
+		release, err := acquireMigrationLock(ctx, cfg.DatabaseURL, cfg.LockTimeout)
+		if errors.Is(err, lock.ErrTimeout) {
+			if cfg.LockTimeout > 0 {
+				printer.Error("Error: another migration is running (lock not acquired within %s)", cfg.LockTimeout)
+			} else {
+				printer.Error("Error: another migration is running (lock not acquired before the deadline)")
+			}
+			return exitLockContention
+		}
+		if err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		defer release()
+	}
+
+	if cfg.SafeMode && !cfg.DryRun {
+		if code := checkConnections(ctx, cfg); code != 0 {
+			return code
+		}
+	}
+
+	if cfg.Format != "text" {
+		return runStatusFormatted(cfg)
+	}
+
+	if cfg.Target != "" {
+		steps, err := targetSteps(cfg)
+		if err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		if steps == 0 {
+			printer.Success("Already at %s, nothing to do", cfg.Target)
+			return 0
+		}
+		printer.Info("Migrating %s %d step(s) to %s", cfg.Command, steps, cfg.Target)
+		cfg.Steps = steps
+	}
+
+	if cfg.Phase != "" {
+		steps, held, err := phaseSteps(cfg)
+		if err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		for _, name := range held {
+			printer.Warn("Warning: %s is %s but waits behind a pending migration of the other phase", name, cfg.Phase)
+		}
+		if steps == 0 {
+			printer.Success("No pending %s migrations, nothing to do", cfg.Phase)
+			return 0
+		}
+		printer.Info("Applying %d %s migration(s)", steps, cfg.Phase)
+		cfg.Steps = steps
+	}
+
+	if cfg.Max > 0 {
+		cfg.Steps = cfg.Max
+	}
+
+	if cfg.Command == "status" {
+		return retryMigration(cfg)
+	}
+
+	if cfg.BackupBeforeMigrate && !cfg.DryRun {
+		if _, err := backupDatabase(ctx, cfg, ""); err != nil {
+			printer.Error("Error: backup failed, not running the migration: %v", err)
+			return 1
+		}
+	}
+
+	hooks := !cfg.DryRun
+	if hooks {
+		if code := runPreMigrateHook(ctx, cfg); code != 0 {
+			printer.Error("Error: pre-migrate hook failed, not running the migration")
+			return code
+		}
+	}
+
+	start := time.Now()
+	if cfg.Command == "up" && cfg.Only != "" {
+		exitCode = runOnlyUp(cfg)
+	} else if cfg.Command == "up" && cfg.ApplyMissingOnly {
+		exitCode = runApplyMissingUp(cfg)
+	} else if cfg.Command == "up" && cfg.Parallelism > 1 && cfg.ShardsFile == "" {
+		exitCode = runParallelUp(cfg)
+	} else {
+		exitCode = retryMigration(cfg)
+	}
+	duration := time.Since(start)
+
+	if hooks {
+		if code := runPostMigrateHook(ctx, cfg, exitCode); code != 0 && exitCode == 0 {
+			exitCode = code
+		}
+	}
+
+	recordAudit(cfg, start, exitCode, duration)
+	notifyWebhook(cfg.Command, exitCode, duration)
+	pushMetrics(cfg.Command, exitCode, duration)
+
+	if cfg.Max > 0 && exitCode == 0 && !cfg.DryRun {
+		reportRemaining(cfg)
+	}
+	return exitCode
+}
+
+// checkDatabaseURL reports whether cfg.DatabaseURL looks usable, printing
+// every problem found. It always succeeds with --skip-validation.
+func checkDatabaseURL(cfg Config) bool {
+	if cfg.SkipValidation {
+		return true
+	}
+
+	problems := validateDatabaseURL(cfg.DatabaseURL)
+	if len(problems) == 0 {
+		return true
+	}
+
+	printer.Error("Error: DATABASE_URL is invalid:")
+	for _, problem := range problems {
+		printer.Error("  - %s", problem)
+	}
+	printer.Error("Use --skip-validation if your connection string uses an unusual format.")
+	return false
+}
+
+// loadEnv loads the --env-file files, or the project's .env files when none
+// are given, and makes them the environment the tool reads from.
+func loadEnv(cfg Config) error {
+	loader := env.NewEnvLoader(cfg.ProjectRoot, os.Getenv("APP_ENV"))
+	if len(cfg.EnvFiles) > 0 {
+		loader = env.NewFileEnvLoader(cfg.EnvFiles)
+	}
+	if err := loader.Load(); err != nil {
+		return err
+	}
+	environment = loader
+	return nil
+}
+
+// auditLogPath returns the audit log's path: MIGRATE_AUDIT_LOG, or
+// migrate_audit.log in the project root.
+func auditLogPath(cfg Config) string {
+	if path := getenv("MIGRATE_AUDIT_LOG"); path != "" {
+		return path
+	}
+	return filepath.Join(cfg.ProjectRoot, "migrate_audit.log")
+}
+
+// recordAudit appends the outcome of a migration run to the audit log.
+func recordAudit(cfg Config, start time.Time, exitCode int, duration time.Duration) {
+	path := auditLogPath(cfg)
+
+	hostname, _ := os.Hostname()
+
+	err := audit.NewAuditLogger(path).Log(audit.AuditEntry{
+		Timestamp:    start.UTC(),
+		Command:      cfg.Command,
+		User:         getenv("USER"),
+		Hostname:     hostname,
+		DatabaseHost: databaseHost(cfg.DatabaseURL),
+		ExitCode:     exitCode,
+		DurationMS:   duration.Milliseconds(),
+	})
+	if err != nil {
+		printer.Warn("Warning: failed to write audit log: %v", err)
+	}
+}
+
+// retryMigration runs the migration, re-running it up to --retry times when
+// it exits with one of the --retry-on codes.
+func retryMigration(cfg Config) int {
+	backoff, _ := retry.ParseBackoff(cfg.RetryBackoff, cfg.RetryDelay, 0)
+	retrier := &retry.Retrier{
+		Retries: cfg.Retry,
+		Backoff: backoff,
+		Retryable: func(exitCode int) bool {
+			return slices.Contains(cfg.RetryOn, exitCode)
+		},
+		OnRetry: func(attempt, exitCode int, delay time.Duration) {
+			printer.Warn("Attempt %d failed with exit code %d; retrying in %s", attempt, exitCode, delay)
+		},
+	}
+
+	return retrier.Run(func(attempt int) int {
+		if attempt > 1 {
+			printer.Info("Attempt %d of %d", attempt, cfg.Retry+1)
+		}
+		return runMigration(cfg)
+	})
+}
+
+// runMigration runs the configured command with the migration engine and
+// returns the exit code of the tool.
+func runMigration(cfg Config) int {
+	printer.Info("Running migration: %s", cfg.Command)
+
+	ctx, cancel := cfg.commandContext()
+	defer cancel()
+
+	var stdout io.Writer = newLogWriter(slog.LevelInfo, "stdout")
+	stderr := newFilteredWriter(newLogWriter(slog.LevelError, "stderr"))
+	var stderrOut io.Writer = stderr
+	if secrets := cfg.redactions(cfg.DatabaseURL); len(secrets) > 0 {
+		stdout = output.NewRedactingWriter(stdout, secrets)
+		stderrOut = output.NewRedactingWriter(stderr, secrets)
+	}
+
+	migrator, err := newMigrationRunner(cfg, stdout, stderrOut, func(ctx context.Context, cmd *exec.Cmd) error {
+		var detector *BuildPhaseDetector
+		if cfg.Engine == "cargo" {
+			detector = NewBuildPhaseDetector(cargoBuildLine)
+			detector.Attach(cmd)
+		}
+		err := runCommand(ctx, cmd)
+		stderr.Flush()
+		if detector != nil {
+			detector.Finish()
+			printPhaseTimes(detector)
+		}
+		return err
+	})
+	if err == nil {
+		err = migrator.Run(ctx, cfg.Command, cfg.Steps)
+	}
+	if err != nil {
+		for _, message := range stderr.Errors() {
+			printer.Error("[MIGRATION ERROR] %s", message)
+		}
+		printer.Error("Migration failed: %v", err)
+		return commandExitCode(err)
+	}
+
+	if cfg.DryRun {
+		printer.Success("Dry run completed successfully")
+		printer.Warn("DRY RUN — no changes were made")
+		return 0
+	}
+
+	printer.Success("Migration completed successfully")
+	return 0
+}
+
+// printPhaseTimes reports how long Cargo spent building separately from how
+// long the migration itself ran.
+func printPhaseTimes(detector *BuildPhaseDetector) {
+	build := detector.BuildTime().Round(time.Millisecond)
+	migration, ok := detector.MigrationTime()
+	if !ok {
+		printer.Info("Build time: %s", build)
+		return
+	}
+	printer.Info("Build time: %s, migration time: %s", build, migration.Round(time.Millisecond))
+}
+
+// commandExitCode maps an error returned by runCommand to the exit code of
+// the tool.
+func commandExitCode(err error) int {
+	var interrupted *interruptedError
+	if errors.As(err, &interrupted) {
+		return interrupted.exitCode()
+	}
+	if errors.Is(err, errTimedOut) {
+		return exitTimeout
+	}
+	return 1
+}
+
+// checkMigrationDir reports whether the configured migration directory
+// exists, printing an error if it does not.
+func checkMigrationDir(cfg Config) bool {
+	if info, err := os.Stat(cfg.MigrationDir); err != nil || !info.IsDir() {
+		printer.Error("Error: migration directory %s does not exist (use --migration-dir to override)", cfg.MigrationDir)
+		return false
+	}
+	return true
+}
+
+// acquireMigrationLock blocks until this process holds the migration advisory
+// lock, or timeout, unless it is 0, elapses or ctx is done. The returned
+// function releases the lock, even once ctx is done.
+func acquireMigrationLock(ctx context.Context, databaseURL string, timeout time.Duration) (func(), error) {
+	db, err := pg.Open(ctx, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+
+	l, err := lock.Acquire(ctx, db, lock.MigrationKey, timeout)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return func() {
+		if err := l.Release(context.WithoutCancel(ctx)); err != nil {
+			printer.Warn("Warning: failed to release migration lock: %v", err)
+		}
+		db.Close()
+	}, nil
 }
 
 func printUsage() {
-	fmt.Println("Usage: migrate <command>")
+	fmt.Println("Usage: migrate <command> [flags] [-- args for the migration binary]")
 	fmt.Println()
 	fmt.Println("Commands:")
-	fmt.Println("  up      Apply pending migrations")
-	fmt.Println("  down    Rollback last migration")
-	fmt.Println("  status  Show migration status")
-	fmt.Println("  fresh   Drop all tables and re-run migrations")
+	fmt.Println("  up                  Apply pending migrations")
+	fmt.Println("  down                Rollback the last migration (or --steps N)")
+	fmt.Println("  status              Show migration status")
+	fmt.Println("  fresh               Drop all tables and re-run migrations")
+	fmt.Println("  watch               Run up whenever a .sql migration file changes (development only)")
+	fmt.Println("  serve               Run a migration for each authorised POST /migrate request (--port, --token; --timeout and --lock-timeout apply to each run)")
+	fmt.Println("  rollback            Roll back every migration applied after --to-date or --to-tag, or choose with --interactive or --interactive-diff")
+	fmt.Println("  repair              Mark a migration as applied or rolled back in the tracking table")
+	fmt.Println("  import              Capture the schema of an existing database as 000001_initial_schema (--from-db)")
+	fmt.Println("  squash              Replace all applied migrations with a baseline dumped from the database (--confirm)")
+	fmt.Println("  init                Scaffold the migration crate (--template minimal or full)")
+	fmt.Println("  create              Scaffold a new migration: migrate create <name>")
+	fmt.Println("  export              Concatenate the up migrations into one SQL script for psql")
+	fmt.Println("  generate-changelog  Print the migrations added between --from and --to as Markdown")
+	fmt.Println("  diff                Print the migrations the GitHub pull request --pr adds, from its API")
+	fmt.Println("  lint                Check the up migrations for risky SQL; rules are set under lint: in migrate.yaml")
+	fmt.Println("  graph               Print the -- depends: links between migrations as a Graphviz DOT digraph")
+	fmt.Println("  test                Run up, down and up again against a throwaway Docker PostgreSQL container")
+	fmt.Println("  list                List migration files and whether each is applied (without Cargo)")
+	fmt.Println("  verify              Run up, down --steps 1 and up on --database-url and fail if the schema changed")
+	fmt.Println("  clean               Report migration files and tracking records without a counterpart (--list-orphaned)")
+	fmt.Println("                      --remove-unapplied deletes the files, --remove-orphaned-records the records, after a backup")
+	fmt.Println("  tidy                Rename migration files to the canonical 000001_name form, with git mv in a repository (--dry-run)")
+	fmt.Println("                      Refuses while migrations are pending; updates the names in the tracking table")
+	fmt.Println("  benchmark           Time re-applying the last --count applied migrations, then print min/max/p50/p95/p99 (without Cargo)")
+	fmt.Println("                      Runs in a transaction that is rolled back, or in --pg-schema S, which is dropped")
+	fmt.Println("  diagnose-slow       List migrations slower than --threshold (default 5s), slowest first (--log-file)")
+	fmt.Println("  plan                Print the SQL of the pending migrations in the order up applies them (without Cargo)")
+	fmt.Println("                      --from and --to limit the range; --format unified-diff diffs against schema.sql")
+	fmt.Println("  check               Fail if an applied migration file was edited or removed (without Cargo)")
+	fmt.Println("  tag                 Label a migration for rollback --to-tag and status --since-tag: migrate tag <tag> --at <migration>")
+	fmt.Println("  version-history     Show when --migration was applied here and in MIGRATE_HISTORY_DB_URLS")
+	fmt.Println("  compare             Diff the schemas of --source and --target databases")
+	fmt.Println("  ping                Check that the database is reachable (without Cargo)")
+	fmt.Println("  snapshot            Write the database schema to a file with pg_dump")
+	fmt.Println("  backup              Dump the whole database with pg_dump -Fc (--output, default ../../backups/)")
+	fmt.Println("  restore             Load a backup into the database with pg_restore (--input)")
+	fmt.Println("  seed                Load the .sql fixtures in --seed-dir into the database (without Cargo)")
+	fmt.Println("  health              Check Cargo, the migration directory and the database; exit 1 unless all pass (--format json)")
+	fmt.Println("  doctor              Check Go, Cargo, .env, the migration directory and the database (--fix)")
+	fmt.Println("  version             Print the version of this tool")
+	fmt.Println("  audit-log           Print the audit log as a table or CSV: migrate audit-log export")
+	fmt.Println("  config              Print the resolved settings with secrets masked: migrate config validate")
+	fmt.Println("                      migrate config diff compares them with --reference (default ../../.env.example)")
+	fmt.Println("                      migrate config encrypt writes ../../.env.encrypted, safe to commit; config decrypt restores .env")
+	fmt.Println("  cloud-run up|down   Run as a Cloud Run or Fargate job: JSON logs for CLOUD_PROVIDER, health checks first,")
+	fmt.Println("                      one thread, a 10m deadline, metrics to Cloud Monitoring or CloudWatch; exits 0 or 1")
+	fmt.Println("  env                 Print the resolved value of every variable the tool reads, with secrets masked (--all)")
+	fmt.Println()
+	fmt.Println("Flags:")
+	fmt.Println("  --dry-run                Print the SQL that would run without applying it (up, down)")
+	fmt.Println("                           (import: print the migration files instead of writing them)")
+	fmt.Println("                           (tidy: print the renames instead of making them)")
+	fmt.Println("  --from-db                Import the schema from DATABASE_URL with pg_dump --schema-only (import)")
+	fmt.Println("  --all                    Print every variable Cargo would run with, not just the tool's own (env)")
+	fmt.Println("  --key K                  Base64 AES-256 key, e.g. from openssl rand -base64 32 (config encrypt|decrypt;")
+	fmt.Println("                           default MIGRATE_ENCRYPT_KEY)")
+	fmt.Println("  --database-url URL       Database to run the cycle on instead of DATABASE_URL, e.g. staging (verify)")
+	fmt.Println("  --threshold D            Report migrations that took longer than D (diagnose-slow)")
+	fmt.Println("  --log-file P             Read execution times from a saved run log, not the database (diagnose-slow)")
+	fmt.Println("  --count N                Number of the last applied migrations benchmark times (default 10)")
+	fmt.Println("  --pg-schema S            Schema benchmark creates to apply the migrations in, instead of a transaction")
+	fmt.Println("  --steps N                Number of migrations to roll back (down)")
+	fmt.Println("  --only M                 Apply only migration M, out of order, after confirmation (up, without Cargo; cargo engine only)")
+	fmt.Println("  --apply-missing-only     Apply only the migration files the tracking table has no record of, out of order (up, without Cargo; cargo engine only)")
+	fmt.Println("  --force                  Apply them without waiting after the out-of-order warning (up --apply-missing-only)")
+	fmt.Println("  --max N                  Apply at most N pending migrations, then report how many remain (up)")
+	fmt.Println("  --pending-count          Print only the number of pending migrations; exit 1 if there are any (status, without Cargo)")
+	fmt.Println("  --exit-code              Exit 0 if all migrations are applied, 1 if some are pending, 2 without a tracking table (status)")
+	fmt.Println("  --target M               Migrate up to, or roll back down to, migration M (up, down)")
+	fmt.Println("                           (compare: database URL diffed against --source)")
+	fmt.Println("  --phase P                Apply only the pending additive or destructive migrations, by their -- phase: header (up)")
+	fmt.Println("  --to-date T              Roll back migrations applied after T, e.g. 2024-01-15T14:30:00Z (rollback)")
+	fmt.Println("  --to-tag T               Roll back the migrations after the one tagged T (rollback)")
+	fmt.Println("  --since D|T              List the migrations applied in the last D (24h) or since time T, without Cargo (status)")
+	fmt.Println("  --watch                  Redraw the status table in place every --interval until Ctrl-C, without Cargo (status)")
+	fmt.Println("  --interval D             How often status --watch refreshes (default 5s)")
+	fmt.Println("  --since-tag T            List the migrations after the one tagged T, without Cargo (status)")
+	fmt.Println("  --at M                   Migration the tag labels (tag)")
+	fmt.Println("  --interactive            Ask about each applied migration, newest first, and roll back one at a time (rollback)")
+	fmt.Println("  --interactive-diff       Like --interactive, showing each migration's .down.sql before asking (rollback)")
+	fmt.Println("  --mark-applied M         Record migration M as applied without running it (repair)")
+	fmt.Println("  --mark-rolled-back M     Remove the record of migration M without running its down file (repair)")
+	fmt.Println("  --migration M            Migration to look up, e.g. 000042_add_orders (version-history)")
+	fmt.Println("  --source U               Database URL whose schema compare diffs against (compare)")
+	fmt.Println("  --yes, -y                Skip the confirmation prompt (fresh, rollback, repair, restore, clean, up --only); needed without a terminal or piped answer")
+	fmt.Println("  --confirm                Same as --yes (repair); required by squash, which has no prompt, and by --env production")
+	fmt.Println("  --lock-timeout D         How long to wait for the migration lock (default 60s; 0 waits indefinitely)")
+	fmt.Println("  --format F               Output format for status, compare and health: text or json (default text)")
+	fmt.Println("                           (status: also table, with a Duration column when timings are reported)")
+	fmt.Println("                           (audit-log: table or csv, default table)")
+	fmt.Println("                           (plan: sql or unified-diff, default sql)")
+	fmt.Println("  --migration-dir P        Path to the migration crate (default ../../migration)")
+	fmt.Println("  --skip-validation        Do not check the format of DATABASE_URL")
+	fmt.Println("  --env-file P             Load P instead of ../../.env; repeat to layer files, later wins")
+	fmt.Println("  --config P               Settings file read instead of ./migrate.yaml or ./migrate.json; flags override it")
+	fmt.Println("  --env E                  Use environment E of the settings file, e.g. its database_url; a bare --env lists them")
+	fmt.Println("  --redact-logs            Mask the DATABASE_URL password and other credentials in all output, e.g. for archived CI logs")
+	fmt.Println("  --no-color               Disable colored output (also honors NO_COLOR)")
+	fmt.Println("  --vault-timeout D        Timeout for fetching DATABASE_URL from Vault (default 5s)")
+	fmt.Println("  --otel-endpoint U        Export a trace span per run over OTLP/HTTP to U, e.g. http://collector:4318")
+	fmt.Println("  --cargo-bin P            Cargo binary to run (overrides CARGO_BIN and PATH)")
+	fmt.Println("  --timeout D              Stop the migration after D; seconds or a duration like 10m")
+	fmt.Println("                           (ping: connection timeout per attempt, default 5s)")
+	fmt.Println("  --retries N              Extra connection attempts before failing (ping)")
+	fmt.Println("  --retry-strategy S       Backoff between ping attempts: fixed, linear, exponential or jitter (default jitter, capped at 30s)")
+	fmt.Println("  --output P               File written by snapshot (default ../../schema.sql), export (default stdout), generate-changelog, diff or backup")
+	fmt.Println("                           (squash: baseline file name, default 000001_baseline.up.sql)")
+	fmt.Println("  --input P                Dump loaded by restore")
+	fmt.Println("  --safe-mode              Refuse to migrate while other sessions are busy on the database, listing them (up)")
+	fmt.Println("  --max-connections N      Busy sessions --safe-mode tolerates besides the tool's own (default 0)")
+	fmt.Println("  --backup-before-migrate  Dump the database to ../../backups/ before applying migrations (up)")
+	fmt.Println("  --from R                 Git revision generate-changelog lists the migrations added since")
+	fmt.Println("  --to R                   Git revision generate-changelog stops at (default HEAD)")
+	fmt.Println("                           (plan: --from M and --to M are the first and last migrations printed)")
+	fmt.Println("  --no-git                 List the migrations numbered above --after N instead of reading git (generate-changelog)")
+	fmt.Println("  --after N                Sequence number --no-git lists the migrations after (default 0)")
+	fmt.Println("  --pr URL                 Pull request diff lists the added migrations of, e.g. https://github.com/org/repo/pull/123")
+	fmt.Println("  --base-branch B          Branch diff compares the pull request with (default main)")
+	fmt.Println("  --fix                    Create a missing .env from .env.example (doctor)")
+	fmt.Println("  --since D                Only export audit entries from the last D, e.g. 24h (audit-log)")
+	fmt.Println("  --seed-dir P             Fixtures loaded by seed, in alphabetical order (default ../../seeds)")
+	fmt.Println("  --template T             Scaffold written by init: minimal (default) or full")
+	fmt.Println("  --watch-delay D          Wait for further changes before watch runs up (default 500ms)")
+	fmt.Println("  --port N                 Port serve listens on (default 8080)")
+	fmt.Println("  --token T                Bearer token serve requires in each request's Authorization header")
+	fmt.Println("  --retry N                Re-run a migration up to N times after a transient failure")
+	fmt.Println("  --retry-delay D          Wait before each retry (default 5s; ping: 500ms)")
+	fmt.Println("  --retry-backoff B        How the delay grows: fixed, linear, exponential or jitter (default fixed)")
+	fmt.Println("  --retry-on CODES         Exit codes worth retrying, comma-separated (default 101: cannot connect)")
+	fmt.Println("  --shards P               Run against every shard listed in the YAML file P")
+	fmt.Println("  --parallel               Migrate shards concurrently (with --shards)")
+	fmt.Println("  --parallelism N          Maximum shards migrated at once (with --parallel)")
+	fmt.Println("                           (up without --shards: migrate itself applies the .sql files, N at once in -- depends: order,")
+	fmt.Println("                           bypassing the Rust migrator and Cargo; cargo engine only)")
+	fmt.Println("  --continue-on-error      Keep migrating remaining shards, or applying seed files, after a failure")
+	fmt.Println("  -- ARGS                  Forward ARGS verbatim to the migration binary, e.g. -- --log-level debug")
+	fmt.Println()
+	fmt.Println("Environment:")
+	fmt.Println("  DATABASE_URL                Database connection string")
+	fmt.Println("  DATABASE_URL_FILE           File containing the connection string (e.g. a mounted secret)")
+	fmt.Println("  VAULT_ADDR                  With VAULT_TOKEN and VAULT_SECRET_PATH, read DATABASE_URL from Vault")
+	fmt.Println("  VAULT_TOKEN                 Vault token (kept in memory only)")
+	fmt.Println("  VAULT_SECRET_PATH           Secret holding a DATABASE_URL field, e.g. secret/data/crypto-bot")
+	fmt.Println("  GCP_PROJECT_ID              With GCP_SECRET_NAME, read DATABASE_URL from Google Cloud Secret Manager")
+	fmt.Println("  GCP_SECRET_NAME             Secret whose latest version holds DATABASE_URL (uses Application Default Credentials)")
+	fmt.Println("  AWS_PARAMETER_NAME          Read DATABASE_URL from this SSM parameter in AWS_REGION (aws builds only)")
+	fmt.Println("  APP_ENV                     Load ../../.env.<APP_ENV> before ../../.env")
+	fmt.Println("  MIGRATE_WEBHOOK_URL         POST the outcome of up/down/fresh to this URL")
+	fmt.Println("  PROMETHEUS_PUSHGATEWAY_URL  Push migration_duration_seconds for up/down/fresh to this Pushgateway")
+	fmt.Println("  MIGRATE_AUDIT_LOG           Append an audit record per run here (default ../../migrate_audit.log)")
+	fmt.Println("  PRE_MIGRATE_HOOK            Run before up/down/fresh (default ../../hooks/pre-migrate); failure aborts")
+	fmt.Println("  POST_MIGRATE_HOOK           Run afterwards with MIGRATE_EXIT_CODE set (default ../../hooks/post-migrate)")
+	fmt.Println("  LOG_FORMAT                  Log output: text (default) or json, one object per line on stderr;")
+	fmt.Println("                              gcp and aws are JSON with Cloud Logging's or CloudWatch's field names")
+	fmt.Println("  CLOUD_PROVIDER              gcp or aws: where cloud-run logs and reports metrics to")
+	fmt.Println("  GITHUB_TOKEN                Token diff reads private repositories' pull requests with")
+	fmt.Println("  LOG_LEVEL                   Minimum level logged: debug, info (default), warn or error")
+	fmt.Println("  CARGO_BIN                   Cargo binary to run when cargo is not in PATH")
+	fmt.Println("  MIGRATE_ENGINE              Migration engine: cargo (default), golang-migrate or flyway; also engine: in migrate.yaml")
+	fmt.Println("  MIGRATE_ENGINE_BIN          The golang-migrate or flyway CLI to run (default migrate or flyway from PATH)")
+	fmt.Println("  MIGRATE_EXTRA_ARGS          Arguments, shell-quoted, passed to the migration binary before any after --")
+	fmt.Println("  MIGRATE_HISTORY_DB_URLS     Comma-separated databases, e.g. staging and QA, that version-history also asks")
+	fmt.Println("  MIGRATE_S3_DIR              Download the migration directory from s3://bucket/prefix first (aws builds only)")
+	fmt.Println("  MIGRATE_ENCRYPT_KEY         Base64 AES-256 key for config encrypt and config decrypt when --key is not given")
+	fmt.Println("  MIGRATE_TABLE_NAME          Migration tracking table (default seaql_migrations; _sqlx_migrations and schema_migrations select those layouts); passed to Cargo as --migration-table; SQL files migrate applies itself are recorded in the name plus _sql, e.g. seaql_migrations_sql")
+	fmt.Println()
+	fmt.Println("Exit codes:")
+	fmt.Println("  1        Migration failed")
+	fmt.Println("  2        Another migration holds the lock")
+	fmt.Println("  124      Migration exceeded --timeout")
+	fmt.Println("  130/143  Interrupted by SIGINT/SIGTERM")
+	fmt.Println()
+	fmt.Println("Exit codes of status --exit-code:")
+	fmt.Println("  0        All migrations are applied")
+	fmt.Println("  1        Some migrations are pending")
+	fmt.Println("  2        The migration tracking table does not exist yet")
+	fmt.Println("  3        The state could not be read")
 }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/metrics"
+)
+
+// pushMetrics reports how long a migration run took to the Prometheus
+// Pushgateway at PROMETHEUS_PUSHGATEWAY_URL. It does nothing when the
+// variable is unset, and push failures only produce a warning.
+func pushMetrics(command string, exitCode int, duration time.Duration) {
+	gateway := getenv("PROMETHEUS_PUSHGATEWAY_URL")
+	if gateway == "" {
+		return
+	}
+
+	hostname, _ := os.Hostname()
+	status := "success"
+	if exitCode != 0 {
+		status = "failure"
+	}
+
+	series := metrics.Series("migration_duration_seconds", map[string]string{
+		"command": command,
+		"status":  status,
+	})
+	err := metrics.Push(metrics.GroupURL(gateway, "migrate", "instance", hostname), map[string]float64{
+		series: duration.Seconds(),
+	})
+	if err != nil {
+		printer.Warn("Warning: failed to push metrics: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const cloudMonitoringEndpoint = "https://monitoring.googleapis.com"
+
+// CloudMonitoring writes gauge points to Google Cloud Monitoring through its
+// REST API, as custom metrics under custom.googleapis.com/migrate/. Cloud
+// Monitoring creates a metric's descriptor the first time it is written.
+type CloudMonitoring struct {
+	Project string
+	// Token returns an OAuth2 access token, as secrets.GCPCredentials does.
+	Token func(ctx context.Context) (string, error)
+
+	// Client defaults to one with the push timeout; Endpoint overrides the
+	// API location, for tests.
+	Client   *http.Client
+	Endpoint string
+}
+
+type timeSeriesRequest struct {
+	TimeSeries []timeSeries `json:"timeSeries"`
+}
+
+type timeSeries struct {
+	Metric struct {
+		Type   string            `json:"type"`
+		Labels map[string]string `json:"labels,omitempty"`
+	} `json:"metric"`
+	Resource struct {
+		Type   string            `json:"type"`
+		Labels map[string]string `json:"labels"`
+	} `json:"resource"`
+	Points []point `json:"points"`
+}
+
+type point struct {
+	Interval struct {
+		EndTime string `json:"endTime"`
+	} `json:"interval"`
+	Value struct {
+		DoubleValue float64 `json:"doubleValue"`
+	} `json:"value"`
+}
+
+// Push writes value, measured at at, as a point of the metric name with
+// labels, e.g. migration_duration_seconds with command and status.
+func (c *CloudMonitoring) Push(ctx context.Context, name string, labels map[string]string, value float64, at time.Time) error {
+	token, err := c.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("get gcp credentials: %w", err)
+	}
+
+	var ts timeSeries
+	ts.Metric.Type = "custom.googleapis.com/migrate/" + name
+	ts.Metric.Labels = labels
+	ts.Resource.Type = "global"
+	ts.Resource.Labels = map[string]string{"project_id": c.Project}
+	var p point
+	p.Interval.EndTime = at.UTC().Format(time.RFC3339Nano)
+	p.Value.DoubleValue = value
+	ts.Points = []point{p}
+	body, err := json.Marshal(timeSeriesRequest{TimeSeries: []timeSeries{ts}})
+	if err != nil {
+		return err
+	}
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = cloudMonitoringEndpoint
+	}
+	u := strings.TrimRight(endpoint, "/") + "/v3/projects/" + url.PathEscape(c.Project) + "/timeSeries"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := c.Client
+	if httpClient == nil {
+		httpClient = client
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("cloud monitoring returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloudMonitoringPush(t *testing.T) {
+	var got timeSeriesRequest
+	var path, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	c := &CloudMonitoring{
+		Project:  "crypto-bot-prod",
+		Token:    func(context.Context) (string, error) { return "ya29.token", nil },
+		Endpoint: server.URL,
+	}
+	at := time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC)
+	labels := map[string]string{"command": "up", "status": "success"}
+	if err := c.Push(context.Background(), "migration_duration_seconds", labels, 1.5, at); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "/v3/projects/crypto-bot-prod/timeSeries"; path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+	if auth != "Bearer ya29.token" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if len(got.TimeSeries) != 1 {
+		t.Fatalf("got %d time series, want 1", len(got.TimeSeries))
+	}
+	ts := got.TimeSeries[0]
+	if ts.Metric.Type != "custom.googleapis.com/migrate/migration_duration_seconds" || ts.Metric.Labels["status"] != "success" {
+		t.Errorf("metric = %+v", ts.Metric)
+	}
+	if ts.Resource.Type != "global" || ts.Resource.Labels["project_id"] != "crypto-bot-prod" {
+		t.Errorf("resource = %+v", ts.Resource)
+	}
+	if len(ts.Points) != 1 || ts.Points[0].Value.DoubleValue != 1.5 || ts.Points[0].Interval.EndTime != "2024-01-15T14:30:00Z" {
+		t.Errorf("points = %+v", ts.Points)
+	}
+}
+
+func TestCloudMonitoringPushErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"Permission denied"}}`, http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	c := &CloudMonitoring{
+		Project:  "p",
+		Token:    func(context.Context) (string, error) { return "t", nil },
+		Endpoint: server.URL,
+	}
+	err := c.Push(context.Background(), "m", nil, 1, time.Now())
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "Permission denied") {
+		t.Errorf("err = %v, want the status and message", err)
+	}
+
+	c.Token = func(context.Context) (string, error) { return "", errors.New("no credentials") }
+	if err := c.Push(context.Background(), "m", nil, 1, time.Now()); err == nil || !strings.Contains(err.Error(), "no credentials") {
+		t.Errorf("err = %v, want the credentials error", err)
+	}
+}
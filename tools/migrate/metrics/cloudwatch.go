@@ -0,0 +1,123 @@
+//go:build aws
+
+package metrics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// CloudWatch writes metrics to Amazon CloudWatch with the PutMetricData
+// call of its query API, signed with the SDK's SigV4 signer, rather than
+// pulling in the CloudWatch service client for one call.
+type CloudWatch struct {
+	Namespace string
+
+	// Endpoint overrides the regional endpoint, for tests.
+	Endpoint string
+
+	cfg    aws.Config
+	signer *v4.Signer
+	client aws.HTTPClient
+}
+
+// NewCloudWatch returns a CloudWatch client for namespace that
+// authenticates with cfg, as loaded by secrets.LoadAWSConfig.
+func NewCloudWatch(cfg aws.Config, namespace string) (*CloudWatch, error) {
+	if cfg.Region == "" {
+		return nil, errors.New("no AWS region for CloudWatch; set AWS_REGION")
+	}
+	c := &CloudWatch{Namespace: namespace, cfg: cfg, signer: v4.NewSigner(), client: cfg.HTTPClient}
+	if c.client == nil {
+		c.client = client
+	}
+	return c, nil
+}
+
+// Push writes value, measured at at, as a data point of the metric name
+// with labels as its dimensions. Metrics named *_seconds are sent with the
+// Seconds unit.
+func (c *CloudWatch) Push(ctx context.Context, name string, labels map[string]string, value float64, at time.Time) error {
+	form := url.Values{
+		"Action":                         {"PutMetricData"},
+		"Version":                        {"2010-08-01"},
+		"Namespace":                      {c.Namespace},
+		"MetricData.member.1.MetricName": {name},
+		"MetricData.member.1.Value":      {strconv.FormatFloat(value, 'g', -1, 64)},
+		"MetricData.member.1.Timestamp":  {at.UTC().Format(time.RFC3339)},
+	}
+	if strings.HasSuffix(name, "_seconds") {
+		form.Set("MetricData.member.1.Unit", "Seconds")
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		prefix := fmt.Sprintf("MetricData.member.1.Dimensions.member.%d.", i+1)
+		form.Set(prefix+"Name", key)
+		form.Set(prefix+"Value", labels[key])
+	}
+	body := form.Encode()
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://monitoring.%s.amazonaws.com/", c.cfg.Region)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	creds, err := c.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("load aws credentials: %w", err)
+	}
+	sum := sha256.Sum256([]byte(body))
+	if err := c.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), "monitoring", c.cfg.Region, time.Now()); err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return cloudWatchError(resp)
+	}
+	return nil
+}
+
+// cloudWatchErrorResponse is the body of a failed query API call.
+type cloudWatchErrorResponse struct {
+	Error struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Error"`
+}
+
+func cloudWatchError(resp *http.Response) error {
+	var e cloudWatchErrorResponse
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(body, &e) == nil && e.Error.Code != "" {
+		return fmt.Errorf("cloudwatch: %s: %s (HTTP %d)", e.Error.Code, e.Error.Message, resp.StatusCode)
+	}
+	return fmt.Errorf("cloudwatch returned %s", resp.Status)
+}
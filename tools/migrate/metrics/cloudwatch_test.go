@@ -0,0 +1,89 @@
+//go:build aws
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestCloudWatchPush(t *testing.T) {
+	var form url.Values
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		r.ParseForm()
+		form = r.PostForm
+		fmt.Fprint(w, "<PutMetricDataResponse/>")
+	}))
+	defer server.Close()
+
+	c, err := NewCloudWatch(aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	}, "CryptoBot/Migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Endpoint = server.URL
+	at := time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC)
+	labels := map[string]string{"status": "success", "command": "up"}
+	if err := c.Push(context.Background(), "migration_duration_seconds", labels, 1.5, at); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20") || !strings.Contains(auth, "/eu-west-1/monitoring/aws4_request") {
+		t.Errorf("Authorization = %q, want a SigV4 signature for monitoring in eu-west-1", auth)
+	}
+	want := map[string]string{
+		"Action":                                        "PutMetricData",
+		"Namespace":                                     "CryptoBot/Migrate",
+		"MetricData.member.1.MetricName":                "migration_duration_seconds",
+		"MetricData.member.1.Value":                     "1.5",
+		"MetricData.member.1.Unit":                      "Seconds",
+		"MetricData.member.1.Timestamp":                 "2024-01-15T14:30:00Z",
+		"MetricData.member.1.Dimensions.member.1.Name":  "command",
+		"MetricData.member.1.Dimensions.member.1.Value": "up",
+		"MetricData.member.1.Dimensions.member.2.Name":  "status",
+		"MetricData.member.1.Dimensions.member.2.Value": "success",
+	}
+	for key, value := range want {
+		if got := form.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestCloudWatchPushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "<ErrorResponse><Error><Code>AccessDenied</Code><Message>not allowed</Message></Error></ErrorResponse>")
+	}))
+	defer server.Close()
+
+	c, err := NewCloudWatch(aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	}, "CryptoBot/Migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Endpoint = server.URL
+	err = c.Push(context.Background(), "m", nil, 1, time.Now())
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied: not allowed") {
+		t.Errorf("err = %v, want the CloudWatch error", err)
+	}
+
+	if _, err := NewCloudWatch(aws.Config{}, "ns"); err == nil {
+		t.Error("NewCloudWatch without a region succeeded")
+	}
+}
@@ -0,0 +1,93 @@
+// Package metrics pushes run metrics to a Prometheus Pushgateway in the text
+// exposition format, without depending on the Prometheus client library.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pushTimeout bounds a push so that an unreachable gateway cannot hold up
+// a deploy.
+const pushTimeout = 5 * time.Second
+
+var client = &http.Client{Timeout: pushTimeout}
+
+// Push POSTs metrics to url, which names a Pushgateway grouping (see
+// GroupURL). Each key is a series as returned by Series, and every metric is
+// reported as a gauge.
+func Push(url string, metrics map[string]float64) error {
+	resp, err := client.Post(url, "text/plain; version=0.0.4", strings.NewReader(format(metrics)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pushgateway returned %s", resp.Status)
+	}
+	return nil
+}
+
+// GroupURL returns the URL that pushes to the group identified by job and
+// the given label name/value pairs on the gateway at base.
+func GroupURL(base, job string, labels ...string) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(base, "/"))
+	b.WriteString("/metrics/job/" + url.PathEscape(job))
+	for i := 0; i+1 < len(labels); i += 2 {
+		b.WriteString("/" + url.PathEscape(labels[i]) + "/" + url.PathEscape(labels[i+1]))
+	}
+	return b.String()
+}
+
+// Series returns the series name for a metric with labels, such as
+// migration_duration_seconds{command="up",status="success"}.
+func Series(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + `="` + escapeLabel(labels[key]) + `"`
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// format renders metrics in the text exposition format, with the series of
+// each metric grouped under a single TYPE line.
+func format(metrics map[string]float64) string {
+	series := make([]string, 0, len(metrics))
+	for s := range metrics {
+		series = append(series, s)
+	}
+	sort.Strings(series)
+
+	var b strings.Builder
+	typed := make(map[string]bool)
+	for _, s := range series {
+		name, _, _ := strings.Cut(s, "{")
+		if !typed[name] {
+			typed[name] = true
+			b.WriteString("# TYPE " + name + " gauge\n")
+		}
+		b.WriteString(s + " " + strconv.FormatFloat(metrics[s], 'g', -1, 64) + "\n")
+	}
+	return b.String()
+}
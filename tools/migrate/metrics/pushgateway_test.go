@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPush(t *testing.T) {
+	var body, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		if got := r.Header.Get("Content-Type"); got != "text/plain; version=0.0.4" {
+			t.Errorf("Content-Type = %q", got)
+		}
+		path = r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	err := Push(GroupURL(server.URL+"/", "migrate", "instance", "bot-1"), map[string]float64{
+		Series("migration_duration_seconds", map[string]string{"status": "success", "command": "up"}):   1.5,
+		Series("migration_duration_seconds", map[string]string{"status": "failure", "command": "down"}): 0.25,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "/metrics/job/migrate/instance/bot-1"; path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+	want := "# TYPE migration_duration_seconds gauge\n" +
+		`migration_duration_seconds{command="down",status="failure"} 0.25` + "\n" +
+		`migration_duration_seconds{command="up",status="success"} 1.5` + "\n"
+	if body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestPushRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad metric", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	if err := Push(GroupURL(server.URL, "migrate"), map[string]float64{"up": 1}); err == nil {
+		t.Error("Push succeeded, want an error for a 400 response")
+	}
+}
+
+func TestSeriesEscapesLabels(t *testing.T) {
+	got := Series("m", map[string]string{"host": `a"b\c`})
+	if want := `m{host="a\"b\\c"}`; got != want {
+		t.Errorf("Series = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,360 @@
+// Package migratetest gives Go integration tests a PostgreSQL database
+// migrated with migrate up, in a throwaway container started with
+// testcontainers-go.
+//
+// Each SetupTestDB or NewDB call starts its own container unless the test
+// binary's TestMain runs its tests through Main, in which case every call
+// gets a fresh database in one container shared by the whole binary:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(migratetest.Main(m))
+//	}
+package migratetest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/engine"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"github.com/crypto-bot/tools/migrate/runner"
+	"github.com/crypto-bot/tools/migrate/testrunner"
+)
+
+// setupTimeout bounds starting a container and migrating a database. The
+// cargo engine may have to build the migration crate first.
+const setupTimeout = 10 * time.Minute
+
+var (
+	// shared is the container Main started for the test binary. It is
+	// started by the first call that needs it.
+	shared struct {
+		sync.Mutex
+		enabled   bool
+		container *postgres.PostgresContainer
+		databases int
+	}
+
+	// databases maps each *sql.DB SetupTestDB returned to its database,
+	// for DatabaseURL, RunMigration and RollbackMigration.
+	databases sync.Map
+)
+
+// database is what SetupTestDB set a connection up with.
+type database struct {
+	url, migrationDir string
+}
+
+// Main runs the tests of m with one container shared by every SetupTestDB
+// and NewDB call, terminates the container and returns the exit code for
+// os.Exit. Call it from TestMain.
+func Main(m *testing.M) int {
+	shared.Lock()
+	shared.enabled = true
+	shared.Unlock()
+
+	code := m.Run()
+
+	shared.Lock()
+	defer shared.Unlock()
+	if shared.container != nil {
+		if err := shared.container.Terminate(context.Background()); err != nil {
+			fmt.Fprintf(os.Stderr, "migratetest: terminate container: %v\n", err)
+			if code == 0 {
+				code = 1
+			}
+		}
+		shared.container = nil
+	}
+	return code
+}
+
+// SetupTestDB returns a connection to a new database that migrate up has
+// migrated with the migrations of migrationDir, the directory migrate takes
+// with --migration-dir: the migration crate, whose migrations directory
+// holds the .sql files of the file-based engines. The engine is the one
+// MIGRATE_ENGINE names, the cargo engine by default, run from
+// MIGRATE_ENGINE_BIN (or CARGO_BIN) or PATH. The database and, unless Main
+// shares it, the container are removed when the test finishes. It skips
+// the test if docker is not available or the engine's CLI is not
+// installed.
+func SetupTestDB(t testing.TB, migrationDir string) *sql.DB {
+	t.Helper()
+
+	name, bin := migrationEngine()
+	cli := bin
+	if cli == "" {
+		cli = defaultBin(name)
+	}
+	if _, err := exec.LookPath(cli); err != nil {
+		t.Skipf("migratetest: %s not found in PATH", cli)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	databaseURL := newDatabase(ctx, t)
+	var output bytes.Buffer
+	r, err := runner.New(runner.Options{
+		MigrationDir: migrationDir,
+		DatabaseURL:  databaseURL,
+		Engine:       name,
+		Bin:          bin,
+		Stdout:       &output,
+		Stderr:       &output,
+	})
+	if err != nil {
+		t.Fatalf("migratetest: %v", err)
+	}
+	if err := r.Up(ctx); err != nil {
+		t.Fatalf("migratetest: migrate up: %v\n%s", err, output.Bytes())
+	}
+
+	conn := open(ctx, t, databaseURL)
+	databases.Store(conn, database{url: databaseURL, migrationDir: migrationDir})
+	t.Cleanup(func() { databases.Delete(conn) })
+	return conn
+}
+
+// DatabaseURL returns the URL of the database of conn, which SetupTestDB
+// returned, for running the migrate command or a runner against it.
+func DatabaseURL(t testing.TB, conn *sql.DB) string {
+	t.Helper()
+	return load(t, conn).url
+}
+
+// NewDB returns a connection to a new, empty database, for tests of code
+// that creates its own tables. It is removed like a SetupTestDB database,
+// and the test is skipped if docker is not available.
+func NewDB(t testing.TB) *sql.DB {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+	return open(ctx, t, newDatabase(ctx, t))
+}
+
+// RunMigration applies the up file of the migration called name, such as
+// 000003_create_orders or create_orders, from the migrations directory of
+// the crate conn was set up with, and records it as applied, as migrate up
+// --only does.
+func RunMigration(t testing.TB, conn *sql.DB, name string) {
+	t.Helper()
+	file := lookup(t, conn, name)
+	if file.UpPath == "" {
+		t.Fatalf("migratetest: migration %s has no up file", name)
+	}
+	data, err := os.ReadFile(file.UpPath)
+	if err != nil {
+		t.Fatalf("migratetest: %v", err)
+	}
+	if err := db.ApplyUp(context.Background(), conn, int64(file.Sequence), file.Name, data); err != nil {
+		t.Fatalf("migratetest: apply %s: %v", filepath.Base(file.UpPath), err)
+	}
+}
+
+// RollbackMigration applies the down file of the migration called name and
+// removes its record.
+func RollbackMigration(t testing.TB, conn *sql.DB, name string) {
+	t.Helper()
+	file := lookup(t, conn, name)
+	if file.DownPath == "" {
+		t.Fatalf("migratetest: migration %s has no down file", name)
+	}
+	data, err := os.ReadFile(file.DownPath)
+	if err != nil {
+		t.Fatalf("migratetest: %v", err)
+	}
+	if err := db.ApplyDown(context.Background(), conn, int64(file.Sequence), data); err != nil {
+		t.Fatalf("migratetest: roll back %s: %v", name, err)
+	}
+}
+
+// migrationEngine returns the engine migrate up would run and its CLI, as
+// the migrate command reads them from the environment. An empty bin means
+// the engine's CLI from PATH.
+func migrationEngine() (name, bin string) {
+	name = os.Getenv("MIGRATE_ENGINE")
+	if name == "" {
+		name = engine.Names[0]
+	}
+	bin = os.Getenv("MIGRATE_ENGINE_BIN")
+	if name == "cargo" {
+		bin = os.Getenv("CARGO_BIN")
+	}
+	return name, bin
+}
+
+// defaultBin is the CLI engine.New runs for the engine called name when
+// given none.
+func defaultBin(name string) string {
+	switch name {
+	case "golang-migrate":
+		return "migrate"
+	case "flyway":
+		return "flyway"
+	default:
+		return "cargo"
+	}
+}
+
+// open connects to the database at databaseURL, closing the connection when
+// the test finishes.
+func open(ctx context.Context, t testing.TB, databaseURL string) *sql.DB {
+	t.Helper()
+	conn, err := pg.Open(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("migratetest: connect to database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// newDatabase returns the URL of an empty database, in the shared container
+// when Main runs the tests and in a new container otherwise.
+func newDatabase(ctx context.Context, t testing.TB) string {
+	t.Helper()
+
+	shared.Lock()
+	defer shared.Unlock()
+	if !shared.enabled {
+		return connectionString(ctx, t, startContainer(ctx, t, true))
+	}
+	if shared.container == nil {
+		shared.container = startContainer(ctx, t, false)
+	}
+
+	shared.databases++
+	name := fmt.Sprintf("migratetest_%d", shared.databases)
+	adminURL := connectionString(ctx, t, shared.container)
+	if err := execStatement(ctx, adminURL, "CREATE DATABASE "+name); err != nil {
+		t.Fatalf("migratetest: create database: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := execStatement(ctx, adminURL, "DROP DATABASE "+name+" WITH (FORCE)"); err != nil {
+			t.Errorf("migratetest: drop database: %v", err)
+		}
+	})
+
+	u, err := url.Parse(adminURL)
+	if err != nil {
+		t.Fatalf("migratetest: %v", err)
+	}
+	u.Path = "/" + name
+	return u.String()
+}
+
+// startContainer starts a PostgreSQL container and waits for it to accept
+// connections, terminating it when the test finishes if remove is set.
+func startContainer(ctx context.Context, t testing.TB, remove bool) *postgres.PostgresContainer {
+	t.Helper()
+
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err == nil {
+		err = provider.Health(ctx)
+		provider.Close()
+	}
+	if err != nil {
+		t.Skipf("migratetest: docker is not available: %v", err)
+	}
+
+	c, err := postgres.RunContainer(ctx,
+		testcontainers.WithImage(testrunner.DefaultImage),
+		postgres.WithDatabase("migrate_test"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		// The image's entrypoint runs a temporary server while it
+		// initialises the database, so the second time the server is
+		// ready is the one that counts.
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").WithOccurrence(2)),
+	)
+	if err != nil {
+		t.Fatalf("migratetest: start container: %v", err)
+	}
+	if remove {
+		t.Cleanup(func() {
+			if err := c.Terminate(context.Background()); err != nil {
+				t.Errorf("migratetest: terminate container: %v", err)
+			}
+		})
+	}
+	return c
+}
+
+// connectionString returns the URL of c's database.
+func connectionString(ctx context.Context, t testing.TB, c *postgres.PostgresContainer) string {
+	t.Helper()
+	databaseURL, err := c.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("migratetest: %v", err)
+	}
+	return databaseURL
+}
+
+// lookup finds the migration called name in the migrations directory of
+// the crate conn was set up with.
+func lookup(t testing.TB, conn *sql.DB, name string) migrations.File {
+	t.Helper()
+
+	sqlDir := filepath.Join(load(t, conn).migrationDir, "migrations")
+	files, err := migrations.Scan(sqlDir)
+	if err != nil {
+		t.Fatalf("migratetest: %v", err)
+	}
+	file, ok := find(files, name)
+	if !ok {
+		t.Fatalf("migratetest: no migration %s in %s", name, sqlDir)
+	}
+	return file
+}
+
+// load returns what SetupTestDB set conn up with.
+func load(t testing.TB, conn *sql.DB) database {
+	t.Helper()
+	d, ok := databases.Load(conn)
+	if !ok {
+		t.Fatal("migratetest: the connection was not returned by SetupTestDB")
+	}
+	return d.(database)
+}
+
+// find returns the migration in files whose file name stem or name is name.
+func find(files []migrations.File, name string) (migrations.File, bool) {
+	var namer migrations.MigrationNamer
+	for _, file := range files {
+		if namer.Base(file.Sequence, file.Name) == name || file.Name == name {
+			return file, true
+		}
+	}
+	return migrations.File{}, false
+}
+
+// execStatement runs statement, which cannot run in a transaction, on the
+// database at databaseURL.
+func execStatement(ctx context.Context, databaseURL, statement string) error {
+	conn, err := pg.Open(ctx, databaseURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, statement)
+	return err
+}
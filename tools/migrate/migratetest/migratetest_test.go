@@ -0,0 +1,149 @@
+package migratetest
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"github.com/crypto-bot/tools/migrate/runner"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(Main(m))
+}
+
+func TestFind(t *testing.T) {
+	files := []migrations.File{
+		{Sequence: 1, Name: "create_users"},
+		{Sequence: 2, Name: "create_orders"},
+	}
+	for _, name := range []string{"000002_create_orders", "create_orders"} {
+		file, ok := find(files, name)
+		if !ok || file.Sequence != 2 {
+			t.Errorf("find(%q) = %+v, %v; want migration 2", name, file, ok)
+		}
+	}
+	if file, ok := find(files, "000003_create_orders"); ok {
+		t.Errorf("find(000003_create_orders) = %+v; want no migration", file)
+	}
+}
+
+// copyCrate copies the repository's migration crate to a new directory,
+// with a target directory shared between runs so that cargo builds it once.
+func copyCrate(t *testing.T) string {
+	t.Helper()
+	src := filepath.Join("..", "..", "..", "migration")
+	dir := t.TempDir()
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == "target" {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() {
+			return os.MkdirAll(filepath.Join(dir, rel), 0o755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, rel), data, 0o644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CARGO_TARGET_DIR", filepath.Join(os.TempDir(), "migratetest-target"))
+	return dir
+}
+
+func TestSetupRunRollback(t *testing.T) {
+	dir := copyCrate(t)
+	write := func(name, sql string) {
+		if err := os.WriteFile(filepath.Join(dir, "migrations", name), []byte(sql), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "migrations"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write("000001_create_users.up.sql", "CREATE TABLE users (id BIGINT PRIMARY KEY);")
+	write("000001_create_users.down.sql", "DROP TABLE users;")
+
+	conn := SetupTestDB(t, dir)
+
+	tableExists := func(table string) bool {
+		t.Helper()
+		var exists bool
+		if err := conn.QueryRow("SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+			t.Fatal(err)
+		}
+		return exists
+	}
+	if !tableExists("wallets") {
+		t.Fatal("SetupTestDB did not run migrate up")
+	}
+
+	RunMigration(t, conn, "create_users")
+	if !tableExists("users") {
+		t.Fatal("users does not exist after RunMigration")
+	}
+	RollbackMigration(t, conn, "000001_create_users")
+	if tableExists("users") {
+		t.Fatal("users still exists after RollbackMigration")
+	}
+
+	t.Run("separate database", func(t *testing.T) {
+		other := NewDB(t)
+		var exists bool
+		if err := other.QueryRow("SELECT to_regclass('wallets') IS NOT NULL").Scan(&exists); err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Error("a NewDB database shares tables with a SetupTestDB one")
+		}
+	})
+}
+
+// TestStatusAfterRunMigration checks that a migration recorded here, as up
+// --only and repair record one, does not break the SeaORM migrator, which
+// fails on a row of seaql_migrations it has no Rust migration for.
+func TestStatusAfterRunMigration(t *testing.T) {
+	dir := copyCrate(t)
+	if err := os.Mkdir(filepath.Join(dir, "migrations"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "migrations", "000001_create_users.up.sql"), []byte("CREATE TABLE users (id BIGINT PRIMARY KEY);"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := SetupTestDB(t, dir)
+	RunMigration(t, conn, "000001_create_users")
+
+	var output bytes.Buffer
+	r, err := runner.New(runner.Options{
+		MigrationDir: dir,
+		DatabaseURL:  DatabaseURL(t, conn),
+		Stderr:       &output,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := r.Status(context.Background())
+	if err != nil {
+		t.Fatalf("status after RunMigration: %v\n%s", err, output.Bytes())
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("%s is pending after SetupTestDB", status.Name)
+		}
+	}
+}
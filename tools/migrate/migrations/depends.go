@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var dependsHeader = regexp.MustCompile(`(?i)^--\s*depends:\s*(.*)$`)
+
+// Dependencies returns the migrations named in the `-- depends: <name>`
+// comments at the top of the file at path, as file name stems such as
+// 000001_create_users. A comment may name several migrations separated by
+// commas or spaces, and the names may also be given as file names. Only the
+// leading block of comments and blank lines is read.
+func Dependencies(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var namer MigrationNamer
+	var deps []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		match := dependsHeader.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		for _, name := range strings.FieldsFunc(match[1], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			if !strings.HasSuffix(name, ".sql") {
+				name += ".sql"
+			}
+			if sequence, stem, ok := namer.Parse(name); ok {
+				name = namer.Base(sequence, stem)
+			} else {
+				name = strings.TrimSuffix(name, ".sql")
+			}
+			deps = append(deps, name)
+		}
+	}
+	return deps, scanner.Err()
+}
@@ -0,0 +1,115 @@
+// Package migrations names and discovers the .sql migration files.
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MigrationNamer builds and parses migration file names of the form
+// <sequence>_<name>.up.sql / <sequence>_<name>.down.sql, where the sequence
+// number is zero-padded to six digits.
+type MigrationNamer struct{}
+
+var (
+	filePattern    = regexp.MustCompile(`^(\d+)_(.+?)(\.up|\.down)?\.sql$`)
+	nameSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Normalize lower-cases name and collapses anything that is not a letter or
+// digit into single underscores, so "Add Users-Table" becomes "add_users_table".
+func (MigrationNamer) Normalize(name string) (string, error) {
+	normalized := nameSeparators.ReplaceAllString(strings.ToLower(name), "_")
+	normalized = strings.Trim(normalized, "_")
+	if normalized == "" {
+		return "", fmt.Errorf("invalid migration name %q", name)
+	}
+	return normalized, nil
+}
+
+// Base returns the file name stem shared by the up and down files.
+func (MigrationNamer) Base(sequence int, name string) string {
+	return fmt.Sprintf("%06d_%s", sequence, name)
+}
+
+// Parse splits a migration file name into its sequence number and name.
+func (n MigrationNamer) Parse(filename string) (sequence int, name string, ok bool) {
+	sequence, name, _, ok = n.parse(filename)
+	return sequence, name, ok
+}
+
+// parse is Parse that also returns the direction: "up", "down" or "" for a
+// file without one.
+func (MigrationNamer) parse(filename string) (sequence int, name, direction string, ok bool) {
+	match := filePattern.FindStringSubmatch(filename)
+	if match == nil {
+		return 0, "", "", false
+	}
+	sequence, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, "", "", false
+	}
+	return sequence, match[2], strings.TrimPrefix(match[3], "."), true
+}
+
+// File is a migration discovered on disk. The up and down files of a
+// migration share one entry.
+type File struct {
+	Sequence int
+	Name     string
+	// UpPath is the path of the file applied when migrating up: the .up.sql
+	// file, or the plain .sql file of a migration without a down file. It
+	// is empty if the migration only has a down file.
+	UpPath string
+	// DownPath is the path of the .down.sql file, or empty if the migration
+	// has none.
+	DownPath string
+}
+
+// Scan lists the migrations in dir ordered by sequence number. A missing
+// directory is treated as containing no migrations.
+func Scan(dir string) ([]File, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var namer MigrationNamer
+	index := make(map[string]int)
+	var files []File
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		sequence, name, direction, ok := namer.parse(entry.Name())
+		if !ok {
+			continue
+		}
+
+		base := namer.Base(sequence, name)
+		i, seen := index[base]
+		if !seen {
+			i = len(files)
+			index[base] = i
+			files = append(files, File{Sequence: sequence, Name: name})
+		}
+		if direction == "down" {
+			files[i].DownPath = filepath.Join(dir, entry.Name())
+		} else {
+			files[i].UpPath = filepath.Join(dir, entry.Name())
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Sequence < files[j].Sequence
+	})
+	return files, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// s3Timeout bounds downloading the migration directory from S3.
+const s3Timeout = 5 * time.Minute
+
+// fetchMigrationDir downloads the migration directory MIGRATE_S3_DIR names,
+// when it is set, into a temporary directory and points cfg.MigrationDir
+// at it, in place of --migration-dir. The returned function removes the
+// directory again.
+func fetchMigrationDir(cfg *Config) (cleanup func(), err error) {
+	uri := getenv("MIGRATE_S3_DIR")
+	if uri == "" {
+		return func() {}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+
+	source, err := newS3MigrationSource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "migrate-s3-")
+	if err != nil {
+		return nil, err
+	}
+	if err := source.Download(ctx, dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("fetch migration directory from %s: %w", uri, err)
+	}
+	slog.Debug("downloaded migration directory", "from", uri, "to", dir)
+
+	cfg.MigrationDir = dir
+	return func() { os.RemoveAll(dir) }, nil
+}
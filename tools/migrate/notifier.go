@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// notifyTimeout bounds webhook delivery so a slow endpoint cannot hold up a
+// deploy.
+const notifyTimeout = 10 * time.Second
+
+// Notification describes the outcome of a migration run.
+type Notification struct {
+	Command    string `json:"command"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms"`
+	Hostname   string `json:"hostname"`
+}
+
+// Notifier delivers migration outcomes to an external system.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// HTTPNotifier POSTs notifications as JSON to a webhook URL. The payload
+// carries a Slack-compatible "text" summary next to the structured fields.
+type HTTPNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+type httpNotification struct {
+	Text string `json:"text"`
+	Notification
+}
+
+func (n *HTTPNotifier) Notify(ctx context.Context, notification Notification) error {
+	outcome := "succeeded"
+	if notification.ExitCode != 0 {
+		outcome = fmt.Sprintf("failed with exit code %d", notification.ExitCode)
+	}
+
+	body, err := json.Marshal(httpNotification{
+		Text: fmt.Sprintf("migrate %s on %s %s after %s", notification.Command, notification.Hostname, outcome,
+			time.Duration(notification.DurationMS)*time.Millisecond),
+		Notification: notification,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// notifyWebhook reports the outcome of a migration run to
+// MIGRATE_WEBHOOK_URL. It does nothing when the variable is unset, and
+// delivery failures only produce a warning.
+func notifyWebhook(command string, exitCode int, duration time.Duration) {
+	url := getenv("MIGRATE_WEBHOOK_URL")
+	if url == "" {
+		return
+	}
+
+	hostname, _ := os.Hostname()
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	var notifier Notifier = &HTTPNotifier{URL: url}
+	err := notifier.Notify(ctx, Notification{
+		Command:    command,
+		ExitCode:   exitCode,
+		DurationMS: duration.Milliseconds(),
+		Hostname:   hostname,
+	})
+	if err != nil {
+		printer.Warn("Warning: failed to send webhook notification: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// onlyWarning is printed before up --only applies a migration out of order.
+const onlyWarning = "WARNING: applying out-of-order migration; ensure your migration is idempotent"
+
+// runOnlyUp applies the up file of the migration --only names and records
+// it as applied, whatever else is applied or pending. The migrator can
+// only apply migrations in sequence, so, as with --parallelism, the file is
+// run here in one transaction and recorded in the migrator's tracking table
+// as it would record it, which is why it needs the cargo engine. A
+// migration already recorded, for example as failed, has its record
+// replaced.
+func runOnlyUp(cfg Config) int {
+	var namer migrations.MigrationNamer
+
+	file, err := findMigration(cfg, cfg.Only)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	name := namer.Base(file.Sequence, file.Name)
+	if file.UpPath == "" {
+		printer.Error("Error: migration %s has no up file", name)
+		return 1
+	}
+
+	ctx, cancel := cfg.commandContext()
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	if err := ensureTrackingTable(ctx, conn); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	printer.Info("Applying %s", name)
+	if err := applySQLMigration(ctx, conn, file); err != nil {
+		printer.Error("Migration failed: %s: %v", name, err)
+		return 1
+	}
+	printer.Success("✓ Applied %s", name)
+	return 0
+}
@@ -0,0 +1,94 @@
+// Package output post-processes what the migration binary prints.
+package output
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errorPatterns recognise the lines of Cargo's stderr that carry the actual
+// database error, most specific first. When a pattern has a group, the group
+// is the meaningful part of the line.
+var errorPatterns = []*regexp.Regexp{
+	// The Debug form of sqlx's PgDatabaseError inside a panic message.
+	regexp.MustCompile(`PgDatabaseError \{.*?message: ("(?:[^"\\]|\\.)*")`),
+	regexp.MustCompile(`error returned from database: (.+)`),
+	regexp.MustCompile(`\bERROR:\s*(.+)`),
+	regexp.MustCompile(`syntax error at or near .+`),
+	regexp.MustCompile(`relation "[^"]+" (?:already exists|does not exist)`),
+	regexp.MustCompile(`(?:column|type|function|schema|index|constraint) "[^"]+" (?:of relation "[^"]+" )?(?:already exists|does not exist)`),
+	regexp.MustCompile(`duplicate key value violates unique constraint .+`),
+}
+
+// StderrFilter passes the stream it wraps through unchanged while picking
+// out recognised error lines, which Errors returns once the stream has been
+// read. The caller can then repeat them after the raw output, where they
+// would otherwise be buried in a panic message or stack trace.
+type StderrFilter struct {
+	r      io.Reader
+	line   []byte
+	errors []string
+}
+
+// NewStderrFilter returns a StderrFilter reading from r.
+func NewStderrFilter(r io.Reader) *StderrFilter {
+	return &StderrFilter{r: r}
+}
+
+func (f *StderrFilter) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	f.line = append(f.line, p[:n]...)
+	for {
+		i := bytes.IndexByte(f.line, '\n')
+		if i < 0 {
+			break
+		}
+		f.scan(string(f.line[:i]))
+		f.line = f.line[i+1:]
+	}
+	if err == io.EOF && len(f.line) > 0 {
+		f.scan(string(f.line))
+		f.line = nil
+	}
+	return n, err
+}
+
+// Errors returns the error messages found so far, without duplicates, in
+// the order they first appeared.
+func (f *StderrFilter) Errors() []string {
+	return append([]string(nil), f.errors...)
+}
+
+func (f *StderrFilter) scan(line string) {
+	message, ok := extractError(strings.TrimRight(line, "\r"))
+	if !ok {
+		return
+	}
+	for _, seen := range f.errors {
+		if seen == message {
+			return
+		}
+	}
+	f.errors = append(f.errors, message)
+}
+
+func extractError(line string) (string, bool) {
+	for _, pattern := range errorPatterns {
+		m := pattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if len(m) < 2 {
+			return strings.TrimSpace(m[0]), true
+		}
+		message := m[1]
+		if unquoted, err := strconv.Unquote(message); err == nil {
+			message = unquoted
+		}
+		return strings.TrimSpace(message), true
+	}
+	return "", false
+}
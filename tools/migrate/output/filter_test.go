@@ -0,0 +1,48 @@
+package output
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStderrFilter(t *testing.T) {
+	stderr := strings.Join([]string{
+		"   Compiling migration v0.1.0 (/app/migration)",
+		"    Finished dev [unoptimized + debuginfo] target(s) in 2.31s",
+		"     Running `target/debug/migration up`",
+		`thread 'main' panicked at 'called ` + "`Result::unwrap()`" + ` on an ` + "`Err`" +
+			` value: Exec(SqlxError(Database(PgDatabaseError { severity: Error, code: "42P07", message: "relation \"orders\" already exists", detail: None })))', src/main.rs:6:5`,
+		"note: run with `RUST_BACKTRACE=1` environment variable to display a backtrace",
+		"Execution Error: error returned from database: syntax error at or near \"TABEL\"",
+		"psql:up.sql:3: ERROR:  column \"price\" does not exist",
+		"Execution Error: error returned from database: syntax error at or near \"TABEL\"",
+	}, "\n")
+
+	filter := NewStderrFilter(strings.NewReader(stderr))
+	passed, err := io.ReadAll(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(passed) != stderr {
+		t.Errorf("filter changed the stream:\n%s", passed)
+	}
+
+	want := []string{
+		`relation "orders" already exists`,
+		`syntax error at or near "TABEL"`,
+		`column "price" does not exist`,
+	}
+	if got := filter.Errors(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Errors() = %q, want %q", got, want)
+	}
+}
+
+func TestStderrFilterNoErrors(t *testing.T) {
+	filter := NewStderrFilter(strings.NewReader("Applying all pending migrations\nNo pending migrations\n"))
+	io.Copy(io.Discard, filter)
+	if got := filter.Errors(); len(got) != 0 {
+		t.Errorf("Errors() = %q, want none", got)
+	}
+}
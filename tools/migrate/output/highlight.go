@@ -0,0 +1,148 @@
+package output
+
+import (
+	"strings"
+)
+
+const (
+	boldRed    = "\x1b[1;31m"
+	boldYellow = "\x1b[1;33m"
+	cyan       = "\x1b[36m"
+	dim        = "\x1b[2m"
+	reset      = "\x1b[0m"
+)
+
+// riskyKeywords destroy or rewrite data already in the database and are
+// drawn in bold red, so they stand out in a migration being reviewed.
+var riskyKeywords = map[string]bool{
+	"DROP": true, "DELETE": true, "TRUNCATE": true,
+}
+
+// changeKeywords alter existing tables or rows and are drawn in bold
+// yellow.
+var changeKeywords = map[string]bool{
+	"ALTER": true, "UPDATE": true, "RENAME": true,
+}
+
+// keywords are the rest of the SQL keywords that are colored.
+var keywords = map[string]bool{
+	"ADD": true, "AND": true, "AS": true, "BEGIN": true, "BY": true,
+	"CASCADE": true, "COLUMN": true, "COMMIT": true, "CONSTRAINT": true,
+	"CREATE": true, "DEFAULT": true, "EXISTS": true, "FOREIGN": true,
+	"FROM": true, "FUNCTION": true, "IF": true, "IN": true, "INDEX": true,
+	"INSERT": true, "INTO": true, "IS": true, "KEY": true, "NOT": true,
+	"NULL": true, "ON": true, "OR": true, "PRIMARY": true, "REFERENCES": true,
+	"RESTRICT": true, "SCHEMA": true, "SELECT": true, "SEQUENCE": true,
+	"SET": true, "TABLE": true, "TO": true, "TRIGGER": true, "TYPE": true,
+	"UNIQUE": true, "USING": true, "VALUES": true, "VIEW": true, "WHERE": true,
+}
+
+// HighlightSQL returns sql with ANSI colors added for display on a
+// terminal: DROP, DELETE and TRUNCATE in bold red, ALTER, UPDATE and
+// RENAME in bold yellow, other keywords in cyan and comments dimmed.
+// String literals, quoted identifiers and dollar-quoted bodies are left
+// alone, so a word inside them is not mistaken for a statement.
+func HighlightSQL(sql string) string {
+	var b strings.Builder
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			b.WriteString(dim + sql[i:i+end] + reset)
+			i += end
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql) - i
+			} else {
+				end += 4
+			}
+			b.WriteString(dim + sql[i:i+end] + reset)
+			i += end
+		case c == '\'' || c == '"':
+			n := quotedLen(sql[i:], c)
+			b.WriteString(sql[i : i+n])
+			i += n
+		case c == '$':
+			n := dollarQuotedLen(sql[i:])
+			b.WriteString(sql[i : i+n])
+			i += n
+		case isWordStart(c):
+			n := 1
+			for i+n < len(sql) && isWordPart(sql[i+n]) {
+				n++
+			}
+			b.WriteString(colorWord(sql[i : i+n]))
+			i += n
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func colorWord(word string) string {
+	upper := strings.ToUpper(word)
+	switch {
+	case riskyKeywords[upper]:
+		return boldRed + word + reset
+	case changeKeywords[upper]:
+		return boldYellow + word + reset
+	case keywords[upper]:
+		return cyan + word + reset
+	}
+	return word
+}
+
+// quotedLen returns the length of the literal quoted with q at the start
+// of s, where a doubled quote stands for itself. An unterminated literal
+// runs to the end of s.
+func quotedLen(s string, q byte) int {
+	for i := 1; i < len(s); i++ {
+		if s[i] != q {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == q {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(s)
+}
+
+// dollarQuotedLen returns the length of the $tag$...$tag$ string at the
+// start of s, or 1 if the $ does not open one, as in a $1 parameter.
+func dollarQuotedLen(s string) int {
+	end := strings.IndexByte(s[1:], '$')
+	if end < 0 {
+		return 1
+	}
+	tag := s[:end+2]
+	for _, c := range []byte(tag[1 : len(tag)-1]) {
+		if !isWordPart(c) {
+			return 1
+		}
+	}
+	if len(tag) > 2 && !isWordStart(tag[1]) {
+		return 1
+	}
+	closing := strings.Index(s[len(tag):], tag)
+	if closing < 0 {
+		return len(s)
+	}
+	return 2*len(tag) + closing
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z'
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || '0' <= c && c <= '9'
+}
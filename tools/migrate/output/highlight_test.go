@@ -0,0 +1,40 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+// markColors replaces the escapes HighlightSQL adds with readable markers.
+var markColors = strings.NewReplacer(boldRed, "<red>", boldYellow, "<yellow>", cyan, "<kw>", dim, "<dim>", reset, "</>")
+
+func TestHighlightSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{"risky statements", "DROP TABLE orders;\ntruncate trades;\nDELETE FROM wallets;",
+			"<red>DROP</> <kw>TABLE</> orders;\n<red>truncate</> trades;\n<red>DELETE</> <kw>FROM</> wallets;"},
+		{"changes", "ALTER TABLE orders DROP COLUMN fee;\nUPDATE orders SET fee = 0;",
+			"<yellow>ALTER</> <kw>TABLE</> orders <red>DROP</> <kw>COLUMN</> fee;\n<yellow>UPDATE</> orders <kw>SET</> fee = 0;"},
+		{"identifiers containing keywords", "SELECT drop_date, updated FROM t",
+			"<kw>SELECT</> drop_date, updated <kw>FROM</> t"},
+		{"string literal", "UPDATE t SET note = 'drop it'", "<yellow>UPDATE</> t <kw>SET</> note = 'drop it'"},
+		{"doubled quote", "SELECT 'it''s DROP'", "<kw>SELECT</> 'it''s DROP'"},
+		{"quoted identifier", `DROP TABLE "delete"`, `<red>DROP</> <kw>TABLE</> "delete"`},
+		{"line comment", "-- drop the table\nDROP TABLE t;", "<dim>-- drop the table</>\n<red>DROP</> <kw>TABLE</> t;"},
+		{"block comment", "/* DELETE */ DROP TABLE t", "<dim>/* DELETE */</> <red>DROP</> <kw>TABLE</> t"},
+		{"dollar quoted", "CREATE FUNCTION f() AS $fn$ DELETE FROM t $fn$;",
+			"<kw>CREATE</> <kw>FUNCTION</> f() <kw>AS</> $fn$ DELETE FROM t $fn$;"},
+		{"parameter", "DELETE FROM t WHERE id = $1", "<red>DELETE</> <kw>FROM</> t <kw>WHERE</> id = $1"},
+		{"unterminated literal", "SELECT 'DROP", "<kw>SELECT</> 'DROP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markColors.Replace(HighlightSQL(tt.sql)); got != tt.want {
+				t.Errorf("HighlightSQL(%q) =\n%s\nwant\n%s", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Mask replaces each redacted value.
+const Mask = "***"
+
+// RedactingWriter replaces every occurrence of a set of sensitive values in
+// the stream written to it with Mask before passing it on. A value split
+// across two writes is still found: the last len(longest value)-1 bytes of
+// each write are held back until the next one, or until Flush.
+type RedactingWriter struct {
+	w       io.Writer
+	secrets [][]byte
+	hold    int
+	buf     []byte
+}
+
+// NewRedactingWriter returns a RedactingWriter that writes to w with the
+// values in secrets masked. Empty values are ignored.
+func NewRedactingWriter(w io.Writer, secrets []string) *RedactingWriter {
+	r := &RedactingWriter{w: w}
+	for _, s := range sortSecrets(secrets) {
+		r.secrets = append(r.secrets, []byte(s))
+	}
+	if len(r.secrets) > 0 {
+		r.hold = len(r.secrets[0]) - 1
+	}
+	return r
+}
+
+func (r *RedactingWriter) Write(p []byte) (int, error) {
+	r.buf = append(r.buf, p...)
+	if err := r.emit(len(r.buf) - r.hold); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes the bytes held back for the next write, then flushes the
+// underlying writer if it has a Flush method. It is safe to call more than
+// once.
+func (r *RedactingWriter) Flush() {
+	r.emit(len(r.buf))
+	if f, ok := r.w.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+// emit writes the buffer up to limit, with secrets masked, and keeps the
+// rest. A secret that starts before limit is masked and written whole.
+func (r *RedactingWriter) emit(limit int) error {
+	var out bytes.Buffer
+	i := 0
+	for i < limit {
+		if n := r.match(r.buf[i:]); n > 0 {
+			out.WriteString(Mask)
+			i += n
+			continue
+		}
+		out.WriteByte(r.buf[i])
+		i++
+	}
+	r.buf = append(r.buf[:0], r.buf[i:]...)
+	if out.Len() == 0 {
+		return nil
+	}
+	_, err := r.w.Write(out.Bytes())
+	return err
+}
+
+// match returns the length of the longest secret b starts with, or 0.
+func (r *RedactingWriter) match(b []byte) int {
+	for _, s := range r.secrets {
+		if bytes.HasPrefix(b, s) {
+			return len(s)
+		}
+	}
+	return 0
+}
+
+// Redact returns s with every occurrence of the values in secrets masked,
+// longest values first.
+func Redact(s string, secrets []string) string {
+	for _, secret := range sortSecrets(secrets) {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+	return s
+}
+
+// sortSecrets returns the non-empty secrets without duplicates, longest
+// first, so that a value containing another is masked whole.
+func sortSecrets(secrets []string) []string {
+	seen := make(map[string]bool, len(secrets))
+	var sorted []string
+	for _, s := range secrets {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			sorted = append(sorted, s)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	return sorted
+}
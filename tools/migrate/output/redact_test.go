@@ -0,0 +1,59 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactingWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []string
+		writes  []string
+		want    string
+	}{
+		{"single write", []string{"s3cret"}, []string{"password=s3cret ok\n"}, "password=*** ok\n"},
+		{"split across writes", []string{"s3cret"}, []string{"password=s3", "cr", "et ok\n"}, "password=*** ok\n"},
+		{"at the end of the stream", []string{"s3cret"}, []string{"password=s3c", "ret"}, "password=***"},
+		{"partial match at the end", []string{"s3cret"}, []string{"password=s3c"}, "password=s3c"},
+		{"longest first", []string{"pass", "pass%21"}, []string{"url=u:pass%21@db pass\n"}, "url=u:***@db ***\n"},
+		{"repeated", []string{"ab"}, []string{"abab", "a", "b"}, "*********"},
+		{"no secrets", nil, []string{"password=s3cret"}, "password=s3cret"},
+		{"empty secret ignored", []string{""}, []string{"text"}, "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			w := NewRedactingWriter(&b, tt.secrets)
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+					t.Fatalf("Write(%q) = %d, %v", s, n, err)
+				}
+			}
+			w.Flush()
+			w.Flush()
+			if b.String() != tt.want {
+				t.Errorf("output = %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactingWriterByteAtATime(t *testing.T) {
+	input := "connecting to postgres://bot:hunter2@db/app with hunter2\n"
+	var b strings.Builder
+	w := NewRedactingWriter(&b, []string{"hunter2"})
+	for i := 0; i < len(input); i++ {
+		w.Write([]byte{input[i]})
+	}
+	w.Flush()
+	if want := "connecting to postgres://bot:***@db/app with ***\n"; b.String() != want {
+		t.Errorf("output = %q, want %q", b.String(), want)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	if got, want := Redact("dial bot:hunter2@db failed; hunter2", []string{"hunter2"}), "dial bot:***@db failed; ***"; got != want {
+		t.Errorf("Redact() = %q, want %q", got, want)
+	}
+}
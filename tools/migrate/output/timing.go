@@ -0,0 +1,69 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// timingLine matches the line the migration binary prints once a migration
+// has run, e.g. "Migration m20240101_000001_create_wallets_table applied in
+// 1.25s". The name may be quoted, as in the migrator's status lines.
+var timingLine = regexp.MustCompile(`Migration '?([^'\s]+)'? applied in (\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h))\b`)
+
+// TimingParser passes the stream it wraps through unchanged while picking
+// out how long each migration took to apply, which Timings returns once the
+// stream has been read.
+type TimingParser struct {
+	r       io.Reader
+	line    []byte
+	timings map[string]time.Duration
+}
+
+// NewTimingParser returns a TimingParser reading from r.
+func NewTimingParser(r io.Reader) *TimingParser {
+	return &TimingParser{r: r, timings: make(map[string]time.Duration)}
+}
+
+func (p *TimingParser) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.line = append(p.line, b[:n]...)
+	for {
+		i := bytes.IndexByte(p.line, '\n')
+		if i < 0 {
+			break
+		}
+		p.scan(string(p.line[:i]))
+		p.line = p.line[i+1:]
+	}
+	if err == io.EOF && len(p.line) > 0 {
+		p.scan(string(p.line))
+		p.line = nil
+	}
+	return n, err
+}
+
+// Timings returns the execution time of each migration found so far, by
+// name. A migration reported more than once, for example because it was
+// retried, is given the sum of its times.
+func (p *TimingParser) Timings() map[string]time.Duration {
+	timings := make(map[string]time.Duration, len(p.timings))
+	for name, d := range p.timings {
+		timings[name] = d
+	}
+	return timings
+}
+
+func (p *TimingParser) scan(line string) {
+	m := timingLine.FindStringSubmatch(strings.TrimRight(line, "\r"))
+	if m == nil {
+		return
+	}
+	d, err := time.ParseDuration(m[2])
+	if err != nil {
+		return
+	}
+	p.timings[m[1]] += d
+}
@@ -0,0 +1,51 @@
+package output
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimingParser(t *testing.T) {
+	input := strings.Join([]string{
+		"Applying migration 'm20240101_000001_create_wallets_table'",
+		"Migration m20240101_000001_create_wallets_table applied in 1.25s",
+		"Migration 'm20240102_000001_create_orders_table' applied in 350ms",
+		"Migration m20240101_000001_create_wallets_table applied in 0.75s",
+		"Migration m20240103_000001_broken applied in soon",
+		"Migration m20240104_000001_last applied in 2.00s",
+	}, "\n")
+
+	p := NewTimingParser(strings.NewReader(input))
+	out, err := io.ReadAll(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != input {
+		t.Error("the stream was not passed through unchanged")
+	}
+
+	want := map[string]time.Duration{
+		"m20240101_000001_create_wallets_table": 2 * time.Second,
+		"m20240102_000001_create_orders_table":  350 * time.Millisecond,
+		"m20240104_000001_last":                 2 * time.Second,
+	}
+	got := p.Timings()
+	if len(got) != len(want) {
+		t.Fatalf("Timings() = %v, want %v", got, want)
+	}
+	for name, d := range want {
+		if got[name] != d {
+			t.Errorf("Timings()[%s] = %s, want %s", name, got[name], d)
+		}
+	}
+}
+
+func TestTimingParserWithoutTimings(t *testing.T) {
+	p := NewTimingParser(strings.NewReader("Migration 'm1'... Applied\n"))
+	io.Copy(io.Discard, p)
+	if got := p.Timings(); len(got) != 0 {
+		t.Errorf("Timings() = %v, want none", got)
+	}
+}
@@ -0,0 +1,123 @@
+// Package parallel runs the nodes of a dependency graph concurrently.
+package parallel
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/graph"
+)
+
+// Result reports how running one node went.
+type Result[T comparable] struct {
+	Node     T
+	Err      error
+	Duration time.Duration
+}
+
+// Scheduler runs every node of a graph once all the nodes it depends on have
+// finished, with at most Parallelism running at once.
+type Scheduler[T comparable] struct {
+	// Parallelism is the maximum number of nodes run at once. Values below
+	// 1 run them one at a time.
+	Parallelism int
+	// OnDone, if set, is called as each node finishes. Calls are never
+	// concurrent.
+	OnDone func(Result[T])
+}
+
+// Run calls run for every node in g and returns the first error. When a
+// node fails, the context passed to the nodes still running is cancelled and
+// no further nodes are started. Run returns once every started node has
+// finished. A cycle in g is reported before anything runs.
+func (s *Scheduler[T]) Run(ctx context.Context, g *graph.DAG[T], run func(context.Context, T) error) error {
+	if _, err := g.TopologicalSort(); err != nil {
+		return err
+	}
+
+	parallelism := s.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	nodes := g.Nodes()
+	waiting := make(map[T]int, len(nodes))
+	for _, n := range nodes {
+		for _, next := range g.Successors(n) {
+			waiting[next]++
+		}
+	}
+	var ready []T
+	for _, n := range nodes {
+		if waiting[n] == 0 {
+			ready = append(ready, n)
+		}
+	}
+
+	var (
+		wg        sync.WaitGroup
+		semaphore = make(chan struct{}, parallelism)
+		results   = make(chan Result[T], len(nodes))
+		firstErr  error
+		started   int
+		finished  int
+	)
+
+	for {
+		// Start the ready nodes, waiting for a free slot before each.
+		for len(ready) > 0 && ctx.Err() == nil {
+			semaphore <- struct{}{}
+			if ctx.Err() != nil {
+				<-semaphore
+				break
+			}
+
+			n := ready[0]
+			ready = ready[1:]
+			started++
+			wg.Add(1)
+			go func(n T) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+
+				start := time.Now()
+				err := run(ctx, n)
+				if err != nil {
+					cancel()
+				}
+				results <- Result[T]{Node: n, Err: err, Duration: time.Since(start)}
+			}(n)
+		}
+		if finished == started {
+			break
+		}
+
+		result := <-results
+		finished++
+		if s.OnDone != nil {
+			s.OnDone(result)
+		}
+		if result.Err != nil {
+			if firstErr == nil {
+				firstErr = result.Err
+			}
+			continue
+		}
+		for _, next := range g.Successors(result.Node) {
+			waiting[next]--
+			if waiting[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+
+	wg.Wait()
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
@@ -0,0 +1,105 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/graph"
+)
+
+func TestSchedulerRespectsDependencies(t *testing.T) {
+	g := graph.New[string]()
+	for _, n := range []string{"users", "prices", "orders", "indexes"} {
+		g.AddNode(n)
+	}
+	g.AddEdge("users", "orders")
+	g.AddEdge("prices", "orders")
+	g.AddEdge("orders", "indexes")
+
+	var mu sync.Mutex
+	finished := make(map[string]bool)
+	var running, maxRunning int32
+
+	s := &Scheduler[string]{Parallelism: 2}
+	err := s.Run(context.Background(), g, func(_ context.Context, n string) error {
+		if r := atomic.AddInt32(&running, 1); r > atomic.LoadInt32(&maxRunning) {
+			atomic.StoreInt32(&maxRunning, r)
+		}
+		defer atomic.AddInt32(&running, -1)
+
+		mu.Lock()
+		switch n {
+		case "orders":
+			if !finished["users"] || !finished["prices"] {
+				t.Errorf("orders started before its dependencies finished")
+			}
+		case "indexes":
+			if !finished["orders"] {
+				t.Errorf("indexes started before orders finished")
+			}
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		finished[n] = true
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(finished) != 4 {
+		t.Errorf("ran %d nodes, want 4", len(finished))
+	}
+	if maxRunning > 2 {
+		t.Errorf("%d nodes ran at once, want at most 2", maxRunning)
+	}
+}
+
+func TestSchedulerStopsAfterFailure(t *testing.T) {
+	g := graph.New[int]()
+	g.AddEdge(1, 2)
+	g.AddNode(3)
+
+	errBoom := errors.New("boom")
+	var ran []int
+	var mu sync.Mutex
+	s := &Scheduler[int]{Parallelism: 1}
+	err := s.Run(context.Background(), g, func(_ context.Context, n int) error {
+		mu.Lock()
+		ran = append(ran, n)
+		mu.Unlock()
+		if n == 1 {
+			return errBoom
+		}
+		return nil
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Run() = %v, want %v", err, errBoom)
+	}
+	if len(ran) != 1 {
+		t.Errorf("ran %v, want only the failing node", ran)
+	}
+}
+
+func TestSchedulerRejectsCycle(t *testing.T) {
+	g := graph.New[string]()
+	g.AddEdge("a", "b")
+	g.AddEdge("b", "a")
+
+	s := &Scheduler[string]{Parallelism: 2}
+	err := s.Run(context.Background(), g, func(context.Context, string) error {
+		t.Error("a node ran despite the cycle")
+		return nil
+	})
+	var cycle *graph.CycleError[string]
+	if !errors.As(err, &cycle) {
+		t.Errorf("Run() = %v, want a CycleError", err)
+	}
+}
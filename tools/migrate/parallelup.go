@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/graph"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"github.com/crypto-bot/tools/migrate/parallel"
+)
+
+// runParallelUp is a Go-side applier: it runs the pending .sql files itself,
+// up to --parallelism at once, without Cargo or the Rust migrator, which
+// can only apply migrations in sequence, not a single named one. Only the
+// `-- depends:` headers order them, so migrations without dependencies
+// between them may run in any order. Each is applied in its own
+// transaction and recorded in db.RecordsTable, as up --only records one.
+// The migrations written in Rust are not run. It needs the cargo engine,
+// whose tracking table layout it writes; see migrationEngine.
+func runParallelUp(cfg Config) int {
+	var namer migrations.MigrationNamer
+
+	files, err := migrations.Scan(cfg.SQLDir())
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	ctx, cancel := cfg.commandContext()
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+	conn.SetMaxOpenConns(cfg.Parallelism)
+
+	state := make([]db.Migration, len(files))
+	for i, file := range files {
+		state[i] = db.Migration{Version: int64(file.Sequence), Name: file.Name}
+	}
+	state, err = db.NewPostgresRepository(conn).Status(ctx, state)
+	if err != nil {
+		printer.Error("Error: read migration state: %v", err)
+		return 1
+	}
+
+	applied := make(map[string]bool, len(files))
+	pending := make(map[string]migrations.File, len(files))
+	g := graph.New[string]()
+	for i, file := range files {
+		name := namer.Base(file.Sequence, file.Name)
+		if state[i].Applied {
+			applied[name] = true
+			continue
+		}
+		if file.UpPath == "" {
+			printer.Error("Error: pending migration %s has no up file", name)
+			return 1
+		}
+		pending[name] = file
+		g.AddNode(name)
+	}
+	if len(pending) == 0 {
+		printer.Success("No pending migrations")
+		return 0
+	}
+
+	for _, name := range g.Nodes() {
+		deps, err := migrations.Dependencies(pending[name].UpPath)
+		if err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		for _, dep := range deps {
+			switch {
+			case applied[dep]:
+			case pending[dep].UpPath != "":
+				g.AddEdge(dep, name)
+			default:
+				printer.Error("Error: %s depends on unknown migration %s", name, dep)
+				return 1
+			}
+		}
+	}
+
+	if err := ensureTrackingTable(ctx, conn); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	printer.Info("Applying %d migration(s), up to %d at once", len(pending), cfg.Parallelism)
+	var mu sync.Mutex
+	done := 0
+	scheduler := &parallel.Scheduler[string]{
+		Parallelism: cfg.Parallelism,
+		OnDone: func(result parallel.Result[string]) {
+			mu.Lock()
+			defer mu.Unlock()
+			duration := result.Duration.Round(time.Millisecond)
+			if result.Err != nil {
+				printer.Error("✗ %s: %v", result.Node, result.Err)
+				return
+			}
+			done++
+			printer.Success("✓ %s (%s) [%d/%d]", result.Node, duration, done, len(pending))
+		},
+	}
+	err = scheduler.Run(ctx, g, func(ctx context.Context, name string) error {
+		return applySQLMigration(ctx, conn, pending[name])
+	})
+	if err != nil {
+		printer.Error("Migration failed: %v", err)
+		return 1
+	}
+
+	printer.Success("Migration completed successfully")
+	return 0
+}
+
+// ensureTrackingTable creates the tracking table up front, since concurrent
+// transactions cannot both create it.
+func ensureTrackingTable(ctx context.Context, conn *sql.DB) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := db.EnsureTable(ctx, tx); err != nil {
+		return fmt.Errorf("create %s: %w", db.RecordsTable(), err)
+	}
+	return tx.Commit()
+}
+
+// applySQLMigration runs the up file of file and records it as applied, in
+// one transaction.
+func applySQLMigration(ctx context.Context, conn *sql.DB, file migrations.File) error {
+	data, err := os.ReadFile(file.UpPath)
+	if err != nil {
+		return err
+	}
+	return db.ApplyUp(ctx, conn, int64(file.Sequence), file.Name, data)
+}
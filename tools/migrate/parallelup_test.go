@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/graph"
+	"github.com/crypto-bot/tools/migrate/migratetest"
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"github.com/crypto-bot/tools/migrate/parallel"
+)
+
+func TestParallelismNeedsCargoEngine(t *testing.T) {
+	for _, name := range []string{"golang-migrate", "flyway"} {
+		t.Setenv("MIGRATE_ENGINE", name)
+		if _, err := migrationEngine(Config{Command: "up", Parallelism: 4}); err == nil {
+			t.Errorf("MIGRATE_ENGINE=%s: up --parallelism 4 was accepted", name)
+		}
+		if _, err := migrationEngine(Config{Command: "up", Parallelism: 4, ShardsFile: "shards.yaml"}); err != nil {
+			t.Errorf("MIGRATE_ENGINE=%s: up --shards --parallelism 4 = %v, want it accepted", name, err)
+		}
+	}
+}
+
+// TestParallelApplyRecordsEveryMigration applies independent migrations
+// concurrently, as runParallelUp does, and checks that each one is recorded
+// once, under its own name.
+func TestParallelApplyRecordsEveryMigration(t *testing.T) {
+	conn := migratetest.NewDB(t)
+	ctx := context.Background()
+
+	var namer migrations.MigrationNamer
+	dir := t.TempDir()
+	files := make(map[string]migrations.File)
+	g := graph.New[string]()
+	for i := 1; i <= 8; i++ {
+		file := migrations.File{Sequence: i, Name: fmt.Sprintf("create_table_%d", i)}
+		file.UpPath = filepath.Join(dir, namer.Base(file.Sequence, file.Name)+".up.sql")
+		if err := os.WriteFile(file.UpPath, []byte(fmt.Sprintf("CREATE TABLE table_%d (id BIGINT PRIMARY KEY);", i)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		name := namer.Base(file.Sequence, file.Name)
+		files[name] = file
+		g.AddNode(name)
+	}
+
+	conn.SetMaxOpenConns(4)
+	if err := ensureTrackingTable(ctx, conn); err != nil {
+		t.Fatal(err)
+	}
+	scheduler := &parallel.Scheduler[string]{Parallelism: 4}
+	if err := scheduler.Run(ctx, g, func(ctx context.Context, name string) error {
+		return applySQLMigration(ctx, conn, files[name])
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := db.Applied(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(files) {
+		t.Fatalf("%d migration(s) recorded, want %d: %+v", len(applied), len(files), applied)
+	}
+	for i, m := range applied {
+		if want := fmt.Sprintf("create_table_%d", i+1); m.Version != int64(i+1) || m.Name != want {
+			t.Errorf("record %d = %06d_%s, want %06d_%s", i, m.Version, m.Name, i+1, want)
+		}
+	}
+}
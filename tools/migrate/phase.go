@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"github.com/crypto-bot/tools/migrate/phases"
+)
+
+// phaseSteps works out how many pending migrations `up --phase` applies:
+// the leading run of pending migrations that belong to the phase. The
+// migrator applies migrations strictly in order, so a pending migration of
+// the other phase holds back everything after it; those are returned in
+// held so the caller can say so.
+func phaseSteps(cfg Config) (steps int, held []string, err error) {
+	var namer migrations.MigrationNamer
+
+	files, state, err := migrationState(cfg)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	blocked := false
+	for i, m := range state {
+		if m.Applied {
+			continue
+		}
+		phase, err := phases.Parse(files[i].UpPath)
+		if err != nil {
+			return 0, nil, err
+		}
+		switch {
+		case !blocked && phase == cfg.Phase:
+			steps++
+		case phase == cfg.Phase:
+			held = append(held, namer.Base(files[i].Sequence, files[i].Name))
+		default:
+			blocked = true
+		}
+	}
+	return steps, held, nil
+}
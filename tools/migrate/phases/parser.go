@@ -0,0 +1,68 @@
+// Package phases sorts migrations into the two halves of a blue-green
+// deploy: additive changes applied before traffic switches to the new
+// release, and destructive ones applied after.
+package phases
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Phase is the deploy phase a migration belongs to.
+type Phase string
+
+const (
+	// Additive migrations, such as new tables or nullable columns, are safe
+	// for the old release and run before the traffic switch.
+	Additive Phase = "additive"
+	// Destructive migrations, such as dropping columns the old release
+	// still reads, run after it.
+	Destructive Phase = "destructive"
+)
+
+var phaseHeader = regexp.MustCompile(`(?i)^--\s*phase:\s*(\S*)\s*$`)
+
+// ParsePhase returns the Phase called name.
+func ParsePhase(name string) (Phase, error) {
+	switch p := Phase(strings.ToLower(name)); p {
+	case Additive, Destructive:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown phase %q (expected additive or destructive)", name)
+}
+
+// Parse returns the phase declared by a `-- phase: <additive|destructive>`
+// comment at the top of the migration file at path. A migration without one
+// is additive. Only the leading block of comments and blank lines is read.
+func Parse(path string) (Phase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		if match := phaseHeader.FindStringSubmatch(line); match != nil {
+			phase, err := ParsePhase(match[1])
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", path, err)
+			}
+			return phase, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return Additive, nil
+}
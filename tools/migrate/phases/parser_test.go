@@ -0,0 +1,37 @@
+package phases
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Phase
+		wantErr bool
+	}{
+		{"no header", "CREATE TABLE a (id int);\n", Additive, false},
+		{"destructive", "-- drop the old column\n-- phase: destructive\nALTER TABLE a DROP COLUMN b;\n", Destructive, false},
+		{"case insensitive", "-- Phase: Additive\n", Additive, false},
+		{"after statements", "SELECT 1;\n-- phase: destructive\n", Additive, false},
+		{"unknown", "-- phase: sometimes\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "000001_a.up.sql")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Parse(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
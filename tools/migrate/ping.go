@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/retry"
+)
+
+// pingRetryDelay and pingRetryCap bound the pause between failed connection
+// attempts. By default it is jittered, so that callers retrying after the
+// same outage, such as a database restart, spread out.
+const (
+	pingRetryDelay = 500 * time.Millisecond
+	pingRetryCap   = 30 * time.Second
+)
+
+// pingDatabase connects to databaseURL and returns the server version.
+func pingDatabase(databaseURL string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	db, err := pg.Open(ctx, databaseURL)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	var version string
+	if err := db.QueryRowContext(ctx, "SHOW server_version").Scan(&version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// runPing checks database connectivity entirely in Go, so it works where the
+// Cargo toolchain is not installed.
+func runPing(cfg Config) int {
+	backoff, _ := retry.ParseBackoff(cfg.RetryBackoff, cfg.RetryDelay, pingRetryCap)
+
+	var err error
+	retrier := &retry.Retrier{
+		Retries:   cfg.Retries,
+		Backoff:   backoff,
+		Retryable: func(int) bool { return true },
+		OnRetry: func(attempt, _ int, delay time.Duration) {
+			printer.Warn("Attempt %d failed: %v; retrying in %s", attempt, err, delay.Round(time.Millisecond))
+		},
+	}
+	exitCode := retrier.Run(func(int) int {
+		var version string
+		version, err = pingDatabase(cfg.DatabaseURL, cfg.Timeout)
+		if err != nil {
+			return 1
+		}
+		printer.Success("OK (PostgreSQL %s)", version)
+		return 0
+	})
+
+	if exitCode != 0 {
+		printer.Error("Error: database is not reachable: %v", err)
+	}
+	return exitCode
+}
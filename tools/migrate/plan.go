@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/crypto-bot/tools/migrate/graph"
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"github.com/crypto-bot/tools/migrate/plan"
+)
+
+// runPlan prints the up files of the pending migrations, between --from and
+// --to when given, in the order their `-- depends:` headers allow, so that
+// the SQL can be reviewed before up applies it. It reads the files and the
+// tracking table itself, so Cargo is not needed.
+func runPlan(cfg Config) int {
+	var namer migrations.MigrationNamer
+
+	first, last := 0, -1
+	if cfg.PlanFrom != "" {
+		file, err := findMigration(cfg, cfg.PlanFrom)
+		if err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		first = file.Sequence
+	}
+	if cfg.PlanTo != "" {
+		file, err := findMigration(cfg, cfg.PlanTo)
+		if err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		last = file.Sequence
+	}
+	if last >= 0 && last < first {
+		printer.Error("Error: --to %s comes before --from %s", cfg.PlanTo, cfg.PlanFrom)
+		return 1
+	}
+
+	files, state, err := migrationState(cfg)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	applied := make(map[string]bool, len(files))
+	pending := make(map[string]migrations.File, len(files))
+	g := graph.New[string]()
+	for i, file := range files {
+		name := namer.Base(file.Sequence, file.Name)
+		if state[i].Applied {
+			applied[name] = true
+			continue
+		}
+		if file.Sequence < first || (last >= 0 && file.Sequence > last) {
+			continue
+		}
+		if file.UpPath == "" {
+			printer.Error("Error: pending migration %s has no up file", name)
+			return 1
+		}
+		pending[name] = file
+		g.AddNode(name)
+	}
+	if len(pending) == 0 {
+		printer.Success("No pending migrations")
+		return 0
+	}
+
+	for _, name := range g.Nodes() {
+		deps, err := migrations.Dependencies(pending[name].UpPath)
+		if err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		for _, dep := range deps {
+			switch {
+			case applied[dep]:
+			case pending[dep].UpPath != "":
+				g.AddEdge(dep, name)
+			default:
+				printer.Error("Error: %s depends on %s, which is neither applied nor in the plan", name, dep)
+				return 1
+			}
+		}
+	}
+	order, err := g.TopologicalSort()
+	if err != nil {
+		var cycle *graph.CycleError[string]
+		if errors.As(err, &cycle) {
+			printer.Error("Error: migrations depend on each other in a cycle:")
+			for _, member := range cycle.Members {
+				printer.Error("  - %s", member)
+			}
+			return 1
+		}
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	steps := make([]plan.Step, len(order))
+	for i, name := range order {
+		data, err := os.ReadFile(pending[name].UpPath)
+		if err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+		steps[i] = plan.Step{Name: name, SQL: string(data)}
+	}
+
+	if cfg.Format != "unified-diff" {
+		err = plan.WriteSQL(os.Stdout, steps)
+	} else {
+		err = writePlanDiff(cfg, steps)
+	}
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// writePlanDiff prints steps as diffs against the schema snapshot writes,
+// or against an empty schema when there is no snapshot.
+func writePlanDiff(cfg Config, steps []plan.Step) error {
+	path := filepath.Join(cfg.ProjectRoot, "schema.sql")
+	base, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		printer.Warn("Warning: no schema snapshot at %s, diffing against an empty schema; run migrate snapshot to create one", path)
+	} else if err != nil {
+		return fmt.Errorf("read schema snapshot: %w", err)
+	}
+	return plan.WriteUnifiedDiff(os.Stdout, filepath.Base(path), string(base), steps)
+}
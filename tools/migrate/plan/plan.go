@@ -0,0 +1,83 @@
+// Package plan prints the SQL of the migrations an up run would apply, in
+// the order it would apply them, for review before it runs.
+package plan
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Step is one migration in a plan: its file name stem and the contents of
+// its up file.
+type Step struct {
+	Name string
+	SQL  string
+}
+
+// Formats are the accepted values of plan --format.
+var Formats = []string{"sql", "unified-diff"}
+
+// WriteSQL writes the SQL of each step, preceded by a `-- [N/M] name`
+// header, so that the output can be read or run as one script.
+func WriteSQL(w io.Writer, steps []Step) error {
+	for i, step := range steps {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "-- [%d/%d] %s\n%s", i+1, len(steps), step.Name, withNewline(step.SQL)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteUnifiedDiff writes each step as a unified diff of the schema file
+// called path: the first step against base, the schema of the current
+// database, and each later step against base with the earlier steps
+// applied. Migrations are appended to the schema, so each diff adds the
+// step's SQL after the last line of the one before it.
+func WriteUnifiedDiff(w io.Writer, path, base string, steps []Step) error {
+	lines := countLines(base)
+	for i, step := range steps {
+		added := strings.Split(strings.TrimSuffix(withNewline(step.SQL), "\n"), "\n")
+		if _, err := fmt.Fprintf(w, "-- [%d/%d] %s\n--- a/%s\n+++ b/%s\n@@ -%s +%s @@\n",
+			i+1, len(steps), step.Name, path, path, hunkRange(lines, 0), hunkRange(lines+1, len(added))); err != nil {
+			return err
+		}
+		for _, line := range added {
+			if _, err := fmt.Fprintf(w, "+%s\n", line); err != nil {
+				return err
+			}
+		}
+		lines += len(added)
+	}
+	return nil
+}
+
+// hunkRange formats the start,count range of a hunk. An empty range starts
+// at the line it follows, as diff writes it.
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// countLines returns the number of lines in s, counting a final line
+// without a newline.
+func countLines(s string) int {
+	if s == "" {
+		return 0
+	}
+	return strings.Count(withNewline(s), "\n")
+}
+
+func withNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
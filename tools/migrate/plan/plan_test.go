@@ -0,0 +1,64 @@
+package plan
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteSQL(t *testing.T) {
+	steps := []Step{
+		{Name: "000002_create_orders", SQL: "CREATE TABLE orders (id BIGINT);\n"},
+		{Name: "000003_index_orders", SQL: "CREATE INDEX orders_id ON orders (id);"},
+	}
+	var b bytes.Buffer
+	if err := WriteSQL(&b, steps); err != nil {
+		t.Fatal(err)
+	}
+	want := `-- [1/2] 000002_create_orders
+CREATE TABLE orders (id BIGINT);
+
+-- [2/2] 000003_index_orders
+CREATE INDEX orders_id ON orders (id);
+`
+	if b.String() != want {
+		t.Errorf("WriteSQL wrote\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestWriteUnifiedDiff(t *testing.T) {
+	steps := []Step{
+		{Name: "000002_create_orders", SQL: "CREATE TABLE orders (\n    id BIGINT\n);\n"},
+		{Name: "000003_index_orders", SQL: "CREATE INDEX orders_id ON orders (id);\n"},
+	}
+	var b bytes.Buffer
+	if err := WriteUnifiedDiff(&b, "schema.sql", "CREATE TABLE users (id BIGINT);\n", steps); err != nil {
+		t.Fatal(err)
+	}
+	want := `-- [1/2] 000002_create_orders
+--- a/schema.sql
++++ b/schema.sql
+@@ -1,0 +2,3 @@
++CREATE TABLE orders (
++    id BIGINT
++);
+-- [2/2] 000003_index_orders
+--- a/schema.sql
++++ b/schema.sql
+@@ -4,0 +5 @@
++CREATE INDEX orders_id ON orders (id);
+`
+	if b.String() != want {
+		t.Errorf("WriteUnifiedDiff wrote\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestWriteUnifiedDiffWithoutBase(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteUnifiedDiff(&b, "schema.sql", "", []Step{{Name: "000001_init", SQL: "SELECT 1;"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := "-- [1/1] 000001_init\n--- a/schema.sql\n+++ b/schema.sql\n@@ -0,0 +1 @@\n+SELECT 1;\n"
+	if b.String() != want {
+		t.Errorf("WriteUnifiedDiff wrote %q, want %q", b.String(), want)
+	}
+}
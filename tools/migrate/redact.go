@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/crypto-bot/tools/migrate/config"
+	"github.com/crypto-bot/tools/migrate/internal/terminal"
+	"github.com/crypto-bot/tools/migrate/output"
+)
+
+// redactions returns the values --redact-logs masks: the passwords in
+// databaseURL and MIGRATE_HISTORY_DB_URLS, as written in the URL and
+// decoded, and the values of the tool's and the .env files' variables whose
+// names mark them as credentials. It returns nil without --redact-logs.
+func (c Config) redactions(databaseURL string) []string {
+	if !c.RedactLogs {
+		return nil
+	}
+	values := databasePasswords(databaseURL)
+	for _, u := range strings.Split(getenv("MIGRATE_HISTORY_DB_URLS"), ",") {
+		values = append(values, databasePasswords(strings.TrimSpace(u))...)
+	}
+	for _, key := range append(environment.Keys(), toolVariables...) {
+		if config.IsSensitive(key) {
+			values = append(values, getenv(key))
+		}
+	}
+	return values
+}
+
+// databasePasswords returns the password in a database URL decoded and as
+// written, which differ when it contains escaped characters.
+func databasePasswords(raw string) []string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return nil
+	}
+	password, ok := u.User.Password()
+	if !ok {
+		return nil
+	}
+	_, written, _ := strings.Cut(u.User.String(), ":")
+	return []string{password, written}
+}
+
+// enableRedaction makes every record logged from now on, including
+// printer's messages, mask the values redactions returns. They are worked
+// out for each record, since DATABASE_URL may only be resolved from a
+// secret store later in the run.
+func enableRedaction(cfg Config) {
+	logger := slog.New(&redactingHandler{
+		Handler: slog.Default().Handler(),
+		secrets: func() []string { return cfg.redactions(getenv("DATABASE_URL")) },
+	})
+	slog.SetDefault(logger)
+	printer = terminal.NewPrinter(logger)
+}
+
+// redactingHandler masks secrets in the message and string attributes of
+// each record before passing it to the handler it wraps.
+type redactingHandler struct {
+	slog.Handler
+	secrets func() []string
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	secrets := h.secrets()
+	redacted := slog.NewRecord(r.Time, r.Level, output.Redact(r.Message, secrets), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(a, secrets))
+		return true
+	})
+	return h.Handler.Handle(ctx, redacted)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	secrets := h.secrets()
+	for i, a := range attrs {
+		attrs[i] = redactAttr(a, secrets)
+	}
+	return &redactingHandler{Handler: h.Handler.WithAttrs(attrs), secrets: h.secrets}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{Handler: h.Handler.WithGroup(name), secrets: h.secrets}
+}
+
+func redactAttr(a slog.Attr, secrets []string) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]any, len(group))
+		for i, member := range group {
+			redacted[i] = redactAttr(member, secrets)
+		}
+		return slog.Group(a.Key, redacted...)
+	case slog.KindString, slog.KindAny:
+		return slog.String(a.Key, output.Redact(a.Value.String(), secrets))
+	default:
+		return a
+	}
+}
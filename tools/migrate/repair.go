@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"github.com/crypto-bot/tools/migrate/repair"
+)
+
+// runRepair records a migration as applied, or removes its record, in
+// the tracking table without running it, after the user confirms. The change
+// is written to the audit log like any other migration run.
+func runRepair(cfg Config) int {
+	var namer migrations.MigrationNamer
+
+	name := cfg.MarkApplied
+	if name == "" {
+		name = cfg.MarkRolledBack
+	}
+	file, err := findMigration(cfg, name)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	base := namer.Base(file.Sequence, file.Name)
+
+	action := fmt.Sprintf("mark %s as applied", base)
+	if cfg.MarkRolledBack != "" {
+		action = fmt.Sprintf("mark %s as rolled back", base)
+	}
+	if !cfg.Yes {
+		if err := confirmRepairInteractive(action); err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	repairer := repair.NewRepairer(conn)
+	if cfg.MarkApplied != "" {
+		err = repairer.MarkApplied(ctx, file)
+	} else {
+		err = repairer.MarkRolledBack(ctx, int64(file.Sequence))
+	}
+
+	exitCode := 0
+	if err != nil {
+		printer.Error("Error: %v", err)
+		exitCode = 1
+	}
+	recordAudit(cfg, start, exitCode, time.Since(start))
+	if exitCode == 0 {
+		printer.Success("Repaired: %s", action)
+	}
+	return exitCode
+}
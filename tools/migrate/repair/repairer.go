@@ -0,0 +1,90 @@
+// Package repair edits the migration tracking table by hand, for when a
+// migration was finished or undone outside the migrator.
+package repair
+
+import (
+	"context"
+	"crypto/sha512"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// ErrNoTrackingTable is returned when the database has no tracking table
+// to repair.
+var ErrNoTrackingTable = errors.New("database has no migration tracking table")
+
+// Repairer marks migrations as applied or rolled back in the tracking table
+// without running them. Each change is made in its own transaction.
+type Repairer struct {
+	db *sql.DB
+}
+
+// NewRepairer returns a Repairer for db.
+func NewRepairer(db *sql.DB) *Repairer {
+	return &Repairer{db: db}
+}
+
+// MarkApplied records file as successfully applied now, as the migrator
+// would, with the SHA-384 checksum of its up file where the tracking table
+// keeps one. A row left behind by a failed attempt is overwritten.
+func (r *Repairer) MarkApplied(ctx context.Context, file migrations.File) error {
+	if file.UpPath == "" {
+		return fmt.Errorf("migration %06d_%s has no up file", file.Sequence, file.Name)
+	}
+	data, err := os.ReadFile(file.UpPath)
+	if err != nil {
+		return err
+	}
+	checksum := sha512.Sum384(data)
+
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		return db.RecordApplied(ctx, tx, int64(file.Sequence), file.Name, checksum[:], 0)
+	})
+}
+
+// MarkRolledBack deletes the record of the migration with version, failing
+// if there is none.
+func (r *Repairer) MarkRolledBack(ctx context.Context, version int64) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		removed, err := db.RecordRolledBack(ctx, tx, version)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("migration %06d is not recorded as applied", version)
+		}
+		return nil
+	})
+}
+
+func (r *Repairer) inTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// A database the SeaORM migrator has run against may have no
+	// db.RecordsTable yet.
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL OR to_regclass($2) IS NOT NULL",
+		db.QuotedMigrationsTable(), db.QuotedRecordsTable()).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNoTrackingTable
+	}
+	if err := db.EnsureTable(ctx, tx); err != nil {
+		return fmt.Errorf("create %s: %w", db.RecordsTable(), err)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
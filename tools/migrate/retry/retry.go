@@ -0,0 +1,133 @@
+// Package retry re-runs operations that fail for transient reasons.
+package retry
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// BackoffStrategy decides how long to wait between attempts.
+type BackoffStrategy interface {
+	// Delay returns the wait after the given failed attempt, counting
+	// from 1.
+	Delay(attempt int) time.Duration
+}
+
+// Fixed waits Base before every retry.
+type Fixed struct {
+	Base time.Duration
+}
+
+func (f Fixed) Delay(int) time.Duration { return f.Base }
+
+// Linear waits Base times the retry number.
+type Linear struct {
+	Base time.Duration
+}
+
+func (l Linear) Delay(attempt int) time.Duration { return l.Base * time.Duration(attempt) }
+
+// Exponential doubles the delay after every retry, waiting at most Max
+// when it is set.
+type Exponential struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func (e Exponential) Delay(attempt int) time.Duration {
+	return doubled(e.Base, attempt, e.Max)
+}
+
+// ExponentialJitter waits a random time between zero and the delay
+// Exponential would, the "full jitter" of
+// https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/,
+// so that many callers failing at once do not all retry together.
+type ExponentialJitter struct {
+	Base time.Duration
+	Max  time.Duration
+	// Int63n returns a random number in [0, n); nil means rand.Int63n.
+	Int63n func(n int64) int64
+}
+
+func (e ExponentialJitter) Delay(attempt int) time.Duration {
+	ceiling := doubled(e.Base, attempt, e.Max)
+	if ceiling <= 0 {
+		return 0
+	}
+	int63n := e.Int63n
+	if int63n == nil {
+		int63n = rand.Int63n
+	}
+	return time.Duration(int63n(int64(ceiling)))
+}
+
+// doubled returns base doubled for every attempt after the first, limited
+// to limit when it is positive and kept from overflowing.
+func doubled(base time.Duration, attempt int, limit time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt && d > 0 && d <= math.MaxInt64/2 && (limit <= 0 || d < limit); i++ {
+		d *= 2
+	}
+	if limit > 0 && d > limit {
+		return limit
+	}
+	return d
+}
+
+// ParseBackoff returns the strategy named s, waiting base before the first
+// retry and, for exponential and jitter, at most limit when it is set.
+func ParseBackoff(s string, base, limit time.Duration) (BackoffStrategy, error) {
+	switch s {
+	case "fixed":
+		return Fixed{Base: base}, nil
+	case "linear":
+		return Linear{Base: base}, nil
+	case "exponential":
+		return Exponential{Base: base, Max: limit}, nil
+	case "jitter":
+		return ExponentialJitter{Base: base, Max: limit}, nil
+	}
+	return nil, fmt.Errorf("unknown backoff %q (expected fixed, linear, exponential or jitter)", s)
+}
+
+// Retrier re-runs an operation that reports its outcome as an exit code.
+type Retrier struct {
+	// Retries is the number of times the operation is re-run after the
+	// first attempt.
+	Retries int
+	// Backoff decides the wait before each retry; nil means no wait.
+	Backoff BackoffStrategy
+	// Retryable reports whether an exit code signals a transient failure.
+	// Other non-zero codes are returned straight away.
+	Retryable func(exitCode int) bool
+	// OnRetry, if set, is called before waiting for each retry.
+	OnRetry func(attempt int, exitCode int, delay time.Duration)
+}
+
+// Run calls op until it returns zero, returns a code that is not
+// retryable, or the retries are used up, and returns its last exit code.
+// op receives the attempt number starting at 1.
+func (r *Retrier) Run(op func(attempt int) int) int {
+	for attempt := 1; ; attempt++ {
+		exitCode := op(attempt)
+		if exitCode == 0 || attempt > r.Retries || r.Retryable == nil || !r.Retryable(exitCode) {
+			return exitCode
+		}
+
+		delay := r.DelayFor(attempt)
+		if r.OnRetry != nil {
+			r.OnRetry(attempt, exitCode, delay)
+		}
+		time.Sleep(delay)
+	}
+}
+
+// DelayFor returns the wait after the given failed attempt.
+func (r *Retrier) DelayFor(attempt int) time.Duration {
+	if r.Backoff == nil {
+		return 0
+	}
+	return r.Backoff.Delay(attempt)
+}
@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelays(t *testing.T) {
+	tests := []struct {
+		backoff BackoffStrategy
+		want    []time.Duration
+	}{
+		{Fixed{Base: time.Second}, []time.Duration{time.Second, time.Second, time.Second}},
+		{Linear{Base: time.Second}, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}},
+		{Exponential{Base: time.Second}, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}},
+		{Exponential{Base: time.Second, Max: 3 * time.Second}, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}},
+	}
+	for _, tt := range tests {
+		for i, want := range tt.want {
+			if got := tt.backoff.Delay(i + 1); got != want {
+				t.Errorf("%#v.Delay(%d) = %s, want %s", tt.backoff, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestExponentialJitter(t *testing.T) {
+	var ceilings []int64
+	jitter := ExponentialJitter{
+		Base: 500 * time.Millisecond,
+		Max:  30 * time.Second,
+		Int63n: func(n int64) int64 {
+			ceilings = append(ceilings, n)
+			return n / 2
+		},
+	}
+	if got := jitter.Delay(3); got != time.Second {
+		t.Errorf("Delay(3) = %s, want half of 2s", got)
+	}
+	jitter.Delay(100)
+	if want := []int64{int64(2 * time.Second), int64(30 * time.Second)}; ceilings[0] != want[0] || ceilings[1] != want[1] {
+		t.Errorf("random ceilings = %v, want %v", ceilings, want)
+	}
+
+	jitter.Int63n = nil
+	for attempt := 1; attempt <= 10; attempt++ {
+		if d := jitter.Delay(attempt); d < 0 || d >= 30*time.Second {
+			t.Errorf("Delay(%d) = %s, want within [0, 30s)", attempt, d)
+		}
+	}
+}
+
+func TestParseBackoff(t *testing.T) {
+	b, err := ParseBackoff("jitter", time.Second, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j, ok := b.(ExponentialJitter); !ok || j.Base != time.Second || j.Max != time.Minute {
+		t.Errorf("ParseBackoff(jitter) = %#v", b)
+	}
+	if _, err := ParseBackoff("random", time.Second, 0); err == nil {
+		t.Error("ParseBackoff(random) succeeded")
+	}
+}
+
+func TestRetrierStopsOnUnretryableCode(t *testing.T) {
+	calls := 0
+	r := &Retrier{Retries: 3, Retryable: func(code int) bool { return code == 101 }}
+	code := r.Run(func(int) int {
+		calls++
+		if calls == 1 {
+			return 101
+		}
+		return 2
+	})
+	if code != 2 || calls != 2 {
+		t.Errorf("Run() = %d after %d calls, want 2 after 2", code, calls)
+	}
+}
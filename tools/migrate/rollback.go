@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/interactive"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/internal/terminal"
+	"github.com/crypto-bot/tools/migrate/output"
+	"golang.org/x/term"
+)
+
+// rollbackDateLayouts are the accepted formats for --to-date. Times without
+// a zone are taken as UTC.
+var rollbackDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseRollbackDate parses the value of --to-date.
+func parseRollbackDate(value string) (time.Time, error) {
+	for _, layout := range rollbackDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q; accepted formats: "+
+		"2024-01-15T14:30:00Z, 2024-01-15T14:30:00+02:00, 2024-01-15T14:30:00, 2024-01-15 14:30:00, 2024-01-15 (UTC unless a zone is given)", value)
+}
+
+// RollbackPlanner works out which migrations to roll back to return the
+// database to the state it was in at a point in time.
+type RollbackPlanner struct {
+	db    *sql.DB
+	since time.Time
+}
+
+// NewRollbackPlanner returns a planner for rolling back everything db
+// applied after since.
+func NewRollbackPlanner(db *sql.DB, since time.Time) *RollbackPlanner {
+	return &RollbackPlanner{db: db, since: since}
+}
+
+// Plan returns the number of down steps needed and the names of the
+// migrations they roll back, newest first. down always rolls back the
+// highest applied version, so the plan fails if a migration applied after
+// the date is older than one applied before it.
+func (p *RollbackPlanner) Plan(ctx context.Context) (steps int, names []string, err error) {
+	applied, err := db.Applied(ctx, p.db)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	reachedOlder := false
+	for i := len(applied) - 1; i >= 0; i-- {
+		m := applied[i]
+		name := fmt.Sprintf("%06d_%s", m.Version, m.Name)
+		if !m.AppliedAt.After(p.since) {
+			reachedOlder = true
+			continue
+		}
+		if reachedOlder {
+			return 0, nil, fmt.Errorf("%s was applied after %s but is older than migrations applied before it; roll back with down --steps instead",
+				name, p.since.Format(time.RFC3339))
+		}
+		names = append(names, name)
+	}
+	return len(names), names, nil
+}
+
+// runRollback rolls back every migration applied after --to-date, or after
+// the migration --to-tag labels, with a single down invocation, once the
+// user has confirmed the plan.
+func runRollback(cfg Config) int {
+	if cfg.Interactive || cfg.InteractiveDiff {
+		return runInteractiveRollback(cfg)
+	}
+
+	var names []string
+	var after string
+	var err error
+	if cfg.ToTag != "" {
+		after = "tag " + cfg.ToTag
+		names, err = appliedAfterTag(cfg)
+	} else {
+		after = cfg.ToDate.Format(time.RFC3339)
+		names, err = appliedAfterDate(cfg)
+	}
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	if len(names) == 0 {
+		printer.Success("No migrations were applied after %s, nothing to roll back", after)
+		return 0
+	}
+
+	printer.Info("Rolling back %d migration(s) applied after %s:", len(names), after)
+	for _, name := range names {
+		printer.Info("  - %s", name)
+	}
+
+	if !cfg.Yes {
+		if err := confirmRollbackInteractive(); err != nil {
+			printer.Error("Error: %v", err)
+			return 1
+		}
+	}
+
+	cfg.Command = "down"
+	cfg.Steps = len(names)
+	return migrateDatabase(context.Background(), cfg)
+}
+
+// appliedAfterDate returns the migrations applied after --to-date, newest
+// first.
+func appliedAfterDate(cfg Config) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+	defer conn.Close()
+	_, names, err := NewRollbackPlanner(conn, cfg.ToDate).Plan(ctx)
+	return names, err
+}
+
+// appliedAfterTag returns the applied migrations numbered above the one
+// --to-tag labels, newest first. down rolls back the highest applied
+// version, so these are exactly the migrations it reaches first.
+func appliedAfterTag(cfg Config) ([]string, error) {
+	version, err := taggedVersion(cfg, cfg.ToTag)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+	defer conn.Close()
+	applied, err := appliedNewestFirst(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, m := range applied {
+		if m.Version > version {
+			names = append(names, m.String())
+		}
+	}
+	return names, nil
+}
+
+// runInteractiveRollback asks about each applied migration, newest first,
+// and runs down one step for each the user agrees to, reading the tracking
+// table again after every step. With --interactive-diff each question is
+// preceded by the migration's down SQL.
+func runInteractiveRollback(cfg Config) int {
+	flag := "--interactive"
+	if cfg.InteractiveDiff {
+		flag = "--interactive-diff"
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		printer.Error("Error: rollback %s needs a terminal to ask on; use --to-date with --yes instead", flag)
+		return 1
+	}
+
+	conn, err := pg.Open(context.Background(), cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	exitCode := 0
+	down := cfg
+	down.Command = "down"
+	down.Steps = 1
+	r := &interactive.Rollback{
+		Applied: func(ctx context.Context) ([]interactive.Migration, error) {
+			ctx, cancel := context.WithTimeout(ctx, listTimeout)
+			defer cancel()
+			return appliedNewestFirst(ctx, conn)
+		},
+		Down: func(context.Context) error {
+			if exitCode = migrateDatabase(context.Background(), down); exitCode != 0 {
+				return fmt.Errorf("down exited with status %d", exitCode)
+			}
+			return nil
+		},
+		In:  os.Stdin,
+		Out: os.Stdout,
+	}
+	if cfg.InteractiveDiff {
+		color := !cfg.NoColor && os.Getenv("NO_COLOR") == "" && terminal.IsTerminal(os.Stdout)
+		r.Show = func(w io.Writer, m interactive.Migration) error {
+			return showDownSQL(w, cfg, m, color)
+		}
+	}
+	rolledBack, err := r.Run(context.Background())
+	if err != nil {
+		printer.Error("Error: %v", err)
+		if exitCode == 0 {
+			exitCode = 1
+		}
+		return exitCode
+	}
+	printer.Success("Rolled back %d migration(s)", len(rolledBack))
+	return 0
+}
+
+// showDownSQL writes the .down.sql file of m to w, with its keywords
+// highlighted if color is set. A migration without one cannot be reviewed,
+// so that is an error rather than a rollback run blind.
+func showDownSQL(w io.Writer, cfg Config, m interactive.Migration, color bool) error {
+	file, err := findMigration(cfg, m.String())
+	if err != nil {
+		return err
+	}
+	if file.DownPath == "" {
+		return fmt.Errorf("%s has no .down.sql file in %s to show", m, cfg.SQLDir())
+	}
+	sql, err := os.ReadFile(file.DownPath)
+	if err != nil {
+		return err
+	}
+
+	text := strings.TrimRight(string(sql), "\n")
+	if color {
+		text = output.HighlightSQL(text)
+	}
+	fmt.Fprintf(w, "\n--- %s\n%s\n---\n", file.DownPath, text)
+	return nil
+}
+
+// appliedNewestFirst returns the successfully applied migrations recorded
+// in the tracking table, newest first.
+func appliedNewestFirst(ctx context.Context, conn *sql.DB) ([]interactive.Migration, error) {
+	applied, err := db.Applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]interactive.Migration, 0, len(applied))
+	for i := len(applied) - 1; i >= 0; i-- {
+		list = append(list, interactive.Migration{Version: applied[i].Version, Name: applied[i].Name})
+	}
+	return list, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/internal/terminal"
+)
+
+const (
+	// shutdownGracePeriod is how long a migration may keep running after
+	// the tool receives SIGINT or SIGTERM before it is killed.
+	shutdownGracePeriod = 30 * time.Second
+	// timeoutGracePeriod is how long a migration may keep running after
+	// --timeout expires and it has been sent SIGTERM.
+	timeoutGracePeriod = 10 * time.Second
+)
+
+// spinnerDisabled suppresses the progress spinner, e.g. while several shards
+// are migrated at once and their spinners would overwrite each other.
+var spinnerDisabled bool
+
+// flusher is implemented by the writers that relay a child's output line by
+// line and may hold back a final, unterminated line.
+type flusher interface {
+	Flush()
+}
+
+// errTimedOut reports that the migration was stopped because it ran longer
+// than --timeout.
+var errTimedOut = errors.New("timed out")
+
+// interruptedError reports that the migration was stopped because the tool
+// received a signal.
+type interruptedError struct {
+	signal os.Signal
+}
+
+func (e *interruptedError) Error() string {
+	return fmt.Sprintf("interrupted by %s", e.signal)
+}
+
+// exitCode follows the shell convention of 128 + signal number, e.g. 130 for
+// SIGINT and 143 for SIGTERM.
+func (e *interruptedError) exitCode() int {
+	if sig, ok := e.signal.(syscall.Signal); ok {
+		return 128 + int(sig)
+	}
+	return 1
+}
+
+// runCommand starts cmd and waits for it to finish. The child runs in its own
+// process group so a terminal Ctrl-C reaches only this process; SIGINT and
+// SIGTERM are then forwarded to the child, which is given
+// shutdownGracePeriod to exit cleanly before being killed. A second signal
+// kills it immediately. When ctx expires the child is sent SIGTERM and killed
+// after timeoutGracePeriod. While the child runs, a spinner with the elapsed
+// time is shown on interactive terminals.
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	isolateProcessGroup(cmd)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	spinner := terminal.NewSpinner("Running migration")
+	if !spinnerDisabled {
+		spinner.Start()
+		defer spinner.Stop("")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		for _, w := range []io.Writer{cmd.Stdout, cmd.Stderr} {
+			if f, ok := w.(flusher); ok {
+				f.Flush()
+			}
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			spinner.Stop(fmt.Sprintf("Migration exited after %s", spinner.Elapsed().Round(time.Millisecond)))
+		} else {
+			spinner.Stop(fmt.Sprintf("Migration finished in %s", spinner.Elapsed().Round(time.Millisecond)))
+		}
+		return err
+	case sig := <-signals:
+		spinner.Stop("")
+		printer.Warn("Waiting for migration to complete...")
+		stopProcess(cmd, sig, shutdownGracePeriod, done, signals)
+		return &interruptedError{signal: sig}
+	case <-ctx.Done():
+		spinner.Stop("")
+		printer.Warn("Migration timed out, stopping it...")
+		stopProcess(cmd, syscall.SIGTERM, timeoutGracePeriod, done, signals)
+		return errTimedOut
+	}
+}
+
+// stopProcess sends sig to the child and waits up to grace for it to exit,
+// killing it once grace elapses or another signal arrives.
+func stopProcess(cmd *exec.Cmd, sig os.Signal, grace time.Duration, done <-chan error, signals <-chan os.Signal) {
+	signalProcess(cmd, sig)
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-signals:
+		killProcess(cmd)
+		<-done
+	case <-timer.C:
+		printer.Warn("Migration did not exit within %s, killing it", grace)
+		killProcess(cmd)
+		<-done
+	}
+}
@@ -0,0 +1,203 @@
+// Package runner runs migrations from Go programs, with the same engines as
+// the migrate command but without it: no flags, .env files, prompts or
+// signal handling, only the migration itself.
+//
+//	r, err := runner.New(runner.Options{
+//		MigrationDir: "migration",
+//		DatabaseURL:  os.Getenv("DATABASE_URL"),
+//		Stdout:       os.Stdout,
+//		Stderr:       os.Stderr,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	return r.Up(ctx)
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/crypto-bot/tools/migrate/engine"
+	"github.com/crypto-bot/tools/migrate/output"
+)
+
+// Exec runs a prepared command whose standard streams are already set,
+// returning once it has exited. It lets a caller such as the migrate
+// command add a progress display or signal forwarding.
+type Exec func(ctx context.Context, cmd *exec.Cmd) error
+
+// Options configure a MigrationRunner.
+type Options struct {
+	// MigrationDir is the migration crate; SQLDir, the directory of .sql
+	// files in it that file-based engines read, defaults to its
+	// migrations directory.
+	MigrationDir string
+	SQLDir       string
+
+	DatabaseURL string
+
+	// Engine is the migration tool to run, one of engine.Names; empty
+	// means cargo. Bin is its CLI; empty takes it from PATH.
+	Engine string
+	Bin    string
+
+	// MigrationsTable is the tracking table the migrator is told to use;
+	// empty leaves it at the migrator's default.
+	MigrationsTable string
+	DryRun          bool
+	// ExtraArgs are passed to the engine's CLI verbatim.
+	ExtraArgs []string
+	// Env is the environment the engine's CLI runs in; nil means the
+	// process environment.
+	Env []string
+
+	// Stdin, Stdout and Stderr are the CLI's standard streams; nil
+	// connects them to the null device.
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+
+	// Exec runs the CLI; nil runs it as a plain child process that is
+	// killed when the context is done.
+	Exec Exec
+}
+
+// MigrationRunner performs migrations with the engine its Options name.
+// Each method runs the engine's CLI to completion.
+type MigrationRunner struct {
+	opts   Options
+	engine engine.Runner
+}
+
+// New returns a MigrationRunner for opts. Nothing is run until one of its
+// methods is called.
+func New(opts Options) (*MigrationRunner, error) {
+	if opts.MigrationDir == "" {
+		return nil, errors.New("runner: no migration directory")
+	}
+	if opts.DatabaseURL == "" {
+		return nil, errors.New("runner: no database URL")
+	}
+	if opts.Engine == "" {
+		opts.Engine = engine.Names[0]
+	}
+	if opts.SQLDir == "" {
+		opts.SQLDir = filepath.Join(opts.MigrationDir, "migrations")
+	}
+	if opts.Exec == nil {
+		opts.Exec = run
+	}
+
+	r := &MigrationRunner{opts: opts}
+	var err error
+	if r.engine, err = engine.New(opts.Engine, opts.Bin, r.exec(opts.Stdout)); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Up applies every pending migration.
+func (r *MigrationRunner) Up(ctx context.Context) error {
+	return r.engine.Up(ctx, r.engineOptions(0))
+}
+
+// Down rolls back the last steps migrations, or the last one if steps is 0.
+func (r *MigrationRunner) Down(ctx context.Context, steps int) error {
+	if steps < 0 {
+		return fmt.Errorf("runner: negative steps %d", steps)
+	}
+	return r.engine.Down(ctx, r.engineOptions(steps))
+}
+
+// Fresh drops everything in the database and applies every migration.
+func (r *MigrationRunner) Fresh(ctx context.Context) error {
+	return r.engine.Fresh(ctx, r.engineOptions(0))
+}
+
+// Run performs the engine operation called command, one of up, down,
+// status, fresh and reset, limited to steps migrations where the engine
+// supports it, with the CLI's output going to Stdout. It covers what the
+// other methods do not, such as up with a step limit.
+func (r *MigrationRunner) Run(ctx context.Context, command string, steps int) error {
+	return engine.Run(ctx, r.engine, command, r.engineOptions(steps))
+}
+
+// Status returns the state of every migration, parsed from the output of
+// the SeaORM migrator's status command, with the execution times it
+// reports. It needs the cargo engine. On failure the captured output is
+// written to Stderr, since it is not passed on otherwise.
+func (r *MigrationRunner) Status(ctx context.Context) ([]MigrationRecord, error) {
+	if r.opts.Engine != "cargo" {
+		return nil, fmt.Errorf("runner: status is read from the SeaORM migrator's output and needs the cargo engine, not %s", r.opts.Engine)
+	}
+
+	var stdout bytes.Buffer
+	status, err := engine.New(r.opts.Engine, r.opts.Bin, r.exec(&stdout))
+	if err != nil {
+		return nil, err
+	}
+	if err := status.Status(ctx, r.engineOptions(0)); err != nil {
+		if r.opts.Stderr != nil {
+			r.opts.Stderr.Write(stdout.Bytes())
+		}
+		return nil, err
+	}
+
+	timings := output.NewTimingParser(&stdout)
+	statuses, err := parseStatus(timings)
+	if err != nil {
+		return nil, err
+	}
+	durations := timings.Timings()
+	for i := range statuses {
+		statuses[i].Duration = durations[statuses[i].Name]
+	}
+	return statuses, nil
+}
+
+func (r *MigrationRunner) engineOptions(steps int) engine.Options {
+	return engine.Options{
+		Dir:             r.opts.MigrationDir,
+		SQLDir:          r.opts.SQLDir,
+		DatabaseURL:     r.opts.DatabaseURL,
+		Steps:           steps,
+		DryRun:          r.opts.DryRun,
+		MigrationsTable: r.opts.MigrationsTable,
+		ExtraArgs:       r.opts.ExtraArgs,
+		Env:             r.opts.Env,
+	}
+}
+
+// exec returns the engine.Exec that connects a command's streams, with its
+// standard output going to stdout, and runs it with Options.Exec.
+func (r *MigrationRunner) exec(stdout io.Writer) engine.Exec {
+	return func(ctx context.Context, cmd *exec.Cmd) error {
+		cmd.Stdin = r.opts.Stdin
+		cmd.Stdout = stdout
+		cmd.Stderr = r.opts.Stderr
+		return r.opts.Exec(ctx, cmd)
+	}
+}
+
+// run is the default Exec: it starts cmd and kills it if ctx is done first.
+func run(ctx context.Context, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		<-done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,203 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testURL = "postgres://app:secret@db:5432/wallets?sslmode=disable"
+
+// fakeCLI is an Exec that records the commands it is given and writes
+// output to their standard output instead of running them.
+type fakeCLI struct {
+	cmds   []*exec.Cmd
+	output string
+	err    error
+}
+
+func (f *fakeCLI) exec(_ context.Context, cmd *exec.Cmd) error {
+	f.cmds = append(f.cmds, cmd)
+	if cmd.Stdout != nil {
+		io.WriteString(cmd.Stdout, f.output)
+	}
+	return f.err
+}
+
+func newTestRunner(t *testing.T, cli *fakeCLI, stdout, stderr io.Writer) *MigrationRunner {
+	t.Helper()
+	r, err := New(Options{
+		MigrationDir: "/repo/migration",
+		DatabaseURL:  testURL,
+		Bin:          "/usr/bin/cargo",
+		Stdout:       stdout,
+		Stderr:       stderr,
+		Exec:         cli.exec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestMigrationRunnerCommands(t *testing.T) {
+	cli := &fakeCLI{output: "done\n"}
+	var stdout bytes.Buffer
+	r := newTestRunner(t, cli, &stdout, nil)
+	ctx := context.Background()
+
+	for _, call := range []func() error{
+		func() error { return r.Up(ctx) },
+		func() error { return r.Down(ctx, 2) },
+		func() error { return r.Fresh(ctx) },
+		func() error { return r.Run(ctx, "up", 3) },
+	} {
+		if err := call(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := [][]string{
+		{"run", "--", "up"},
+		{"run", "--", "down", "--num", "2"},
+		{"run", "--", "fresh"},
+		{"run", "--", "up", "--num", "3"},
+	}
+	for i, cmd := range cli.cmds {
+		if !slices.Equal(cmd.Args[1:], want[i]) {
+			t.Errorf("command %d args = %q, want %q", i, cmd.Args[1:], want[i])
+		}
+		if cmd.Dir != "/repo/migration" || !slices.Contains(cmd.Env, "DATABASE_URL="+testURL) {
+			t.Errorf("command %d: Dir = %q, DATABASE_URL not in env", i, cmd.Dir)
+		}
+	}
+	if got := stdout.String(); got != strings.Repeat("done\n", 4) {
+		t.Errorf("stdout = %q", got)
+	}
+}
+
+func TestMigrationRunnerStatus(t *testing.T) {
+	cli := &fakeCLI{output: strings.Join([]string{
+		"Checking migration status",
+		"Migration 'm20240101_000001_create_wallets_table' applied in 1.5s",
+		"Migration 'm20240101_000001_create_wallets_table'... Applied",
+		"Migration 'm20240102_000001_create_transactions_table'... Pending",
+		"",
+	}, "\n")}
+	var stdout bytes.Buffer
+	r := newTestRunner(t, cli, &stdout, nil)
+
+	statuses, err := r.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MigrationRecord{
+		{Name: "m20240101_000001_create_wallets_table", Applied: true, Duration: 1500 * time.Millisecond},
+		{Name: "m20240102_000001_create_transactions_table"},
+	}
+	if !slices.Equal(statuses, want) {
+		t.Errorf("Status = %+v, want %+v", statuses, want)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Status passed the output on to Stdout: %q", stdout.String())
+	}
+}
+
+func TestMigrationRunnerStatusFailure(t *testing.T) {
+	cli := &fakeCLI{output: "error: connection refused\n", err: errors.New("exit status 1")}
+	var stderr bytes.Buffer
+	r := newTestRunner(t, cli, nil, &stderr)
+
+	if _, err := r.Status(context.Background()); err == nil {
+		t.Fatal("Status succeeded")
+	}
+	if !strings.Contains(stderr.String(), "connection refused") {
+		t.Errorf("stderr = %q, want the captured output", stderr.String())
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    []MigrationRecord
+		wantErr bool
+	}{
+		{
+			name:   "applied",
+			output: "Migration 'm20240101_000001_create_wallets_table'... Applied\n",
+			want:   []MigrationRecord{{Name: "m20240101_000001_create_wallets_table", Applied: true}},
+		},
+		{
+			name:   "pending",
+			output: "Migration 'm20240102_000001_create_transactions_table'... Pending\n",
+			want:   []MigrationRecord{{Name: "m20240102_000001_create_transactions_table"}},
+		},
+		{
+			name: "mixed with other output",
+			output: "Checking migration status\n" +
+				"Migration 'm1'... Applied\n" +
+				"Migration 'm1' applied in 2s\n" +
+				"Migration 'm2'... Pending\n",
+			want: []MigrationRecord{{Name: "m1", Applied: true}, {Name: "m2"}},
+		},
+		{name: "malformed status", output: "Migration 'm1'... Skipped\n", wantErr: true},
+		{name: "malformed line among valid ones", output: "Migration 'm1'... Applied\nMigration 'm2' is weird\n", wantErr: true},
+		{name: "no statuses", output: "Checking migration status\n", wantErr: true},
+		{name: "empty", output: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStatus(strings.NewReader(tt.output))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStatusRejectsUnknownOutput(t *testing.T) {
+	if _, err := parseStatus(strings.NewReader("Migration 'm1' is weird\n")); err == nil {
+		t.Error("parseStatus accepted an unrecognized status line")
+	}
+	if _, err := parseStatus(strings.NewReader("nothing here\n")); err == nil {
+		t.Error("parseStatus accepted output without statuses")
+	}
+}
+
+func TestNewValidatesOptions(t *testing.T) {
+	for _, opts := range []Options{
+		{DatabaseURL: testURL},
+		{MigrationDir: "m"},
+		{MigrationDir: "m", DatabaseURL: testURL, Engine: "liquibase"},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("New(%+v) succeeded", opts)
+		}
+	}
+}
+
+func TestDefaultExecKillsOnCancel(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not installed")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := run(ctx, exec.Command("sleep", "10")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("run = %v, want the context's error", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("run did not kill the command")
+	}
+}
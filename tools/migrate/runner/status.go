@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MigrationRecord describes the state of a single migration as reported by
+// the migration binary. The status formats are written against it.
+type MigrationRecord struct {
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+	// AppliedAt is when the migration was applied, which the binary does not
+	// report; callers fill it in from the tracking table.
+	AppliedAt *time.Time `json:"applied_at"`
+	// Duration is how long the migration took to apply, when the binary
+	// reported it.
+	Duration time.Duration `json:"-"`
+}
+
+// statusLine matches the per-migration lines printed by the SeaORM migration
+// CLI, e.g. "Migration 'm20240101_000001_create_wallets_table'... Applied".
+var statusLine = regexp.MustCompile(`Migration '([^']+)'\.\.\. (Applied|Pending)`)
+
+// parseStatus extracts migration statuses from the output of the migration
+// binary's status command. It fails rather than returning an empty result
+// when the output does not look like status output, so a change in the
+// upstream format is noticed instead of producing empty reports.
+func parseStatus(r io.Reader) ([]MigrationRecord, error) {
+	var statuses []MigrationRecord
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		match := statusLine.FindStringSubmatch(line)
+		if match == nil {
+			// Lines reporting a migration's execution time are picked up by
+			// output.TimingParser instead.
+			if strings.Contains(line, "Migration '") && !strings.Contains(line, " applied in ") {
+				return nil, fmt.Errorf("unrecognized status line: %q", line)
+			}
+			continue
+		}
+		statuses = append(statuses, MigrationRecord{
+			Name:    match[1],
+			Applied: match[2] == "Applied",
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(statuses) == 0 {
+		return nil, fmt.Errorf("no migration statuses found in output; has the migration binary's output format changed?")
+	}
+
+	return statuses, nil
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+func isolateProcessGroup(cmd *exec.Cmd) {}
+
+func signalProcess(cmd *exec.Cmd, sig os.Signal) {
+	cmd.Process.Signal(sig)
+}
+
+func killProcess(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,30 @@
+//go:build aws
+
+package main
+
+import (
+	"context"
+
+	"github.com/crypto-bot/tools/migrate/secrets"
+	"github.com/crypto-bot/tools/migrate/storage"
+)
+
+// newS3MigrationSource returns the source for MIGRATE_S3_DIR, in
+// AWS_REGION, caching objects in the user's cache directory. As with the
+// AWS CLI, AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL point it at an
+// S3-compatible service instead.
+func newS3MigrationSource(ctx context.Context, uri string) (storage.MigrationSource, error) {
+	awsCfg, err := secrets.LoadAWSConfig(ctx, getenv("AWS_REGION"), getenv)
+	if err != nil {
+		return nil, err
+	}
+	var cache *storage.ETagCache
+	if dir, err := storage.DefaultCacheDir(); err == nil {
+		cache = &storage.ETagCache{Dir: dir}
+	}
+	endpoint := getenv("AWS_ENDPOINT_URL_S3")
+	if endpoint == "" {
+		endpoint = getenv("AWS_ENDPOINT_URL")
+	}
+	return storage.NewS3MigrationSource(uri, endpoint, awsCfg, cache)
+}
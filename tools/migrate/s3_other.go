@@ -0,0 +1,16 @@
+//go:build !aws
+
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/crypto-bot/tools/migrate/storage"
+)
+
+// newS3MigrationSource refuses MIGRATE_S3_DIR in builds without the aws
+// tag, as resolveSSMParameter refuses AWS_PARAMETER_NAME.
+func newS3MigrationSource(context.Context, string) (storage.MigrationSource, error) {
+	return nil, errors.New("MIGRATE_S3_DIR is set but this binary was built without AWS support (build with -tags aws)")
+}
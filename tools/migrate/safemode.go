@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/internal/lock"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/safety"
+)
+
+// safeModeTimeout bounds the pg_stat_activity check of up --safe-mode.
+const safeModeTimeout = 30 * time.Second
+
+// maxQueryWidth is how much of each blocking query --safe-mode prints.
+const maxQueryWidth = 120
+
+// checkConnections implements up --safe-mode: it fails, listing them, if
+// more than --max-connections sessions other than the tool's own are busy
+// on the database. migrateDatabase runs it once the migration lock is held,
+// so the lock's session is not counted.
+func checkConnections(ctx context.Context, cfg Config) int {
+	ctx, cancel := context.WithTimeout(ctx, safeModeTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	blocking, err := safety.NewConnectionChecker(conn, cfg.MaxConnections, lock.MigrationKey).Blocking(ctx)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if len(blocking) == 0 {
+		return 0
+	}
+
+	printer.Error("Error: %d active connection(s) on the database, more than --max-connections %d; not migrating:", len(blocking), cfg.MaxConnections)
+	for _, c := range blocking {
+		running := ""
+		if c.Since != nil {
+			running = " for " + time.Since(*c.Since).Round(time.Second).String()
+		}
+		printer.Error("  - PID %d (%s, %s)%s: %s", c.PID, c.User, c.State, running, oneLine(c.Query, maxQueryWidth))
+	}
+	printer.Error("Retry when the database is quieter, or raise --max-connections.")
+	return 1
+}
+
+// oneLine collapses the whitespace in query and cuts it to width runes.
+func oneLine(query string, width int) string {
+	query = strings.Join(strings.Fields(query), " ")
+	if r := []rune(query); len(r) > width {
+		return string(r[:width-1]) + "…"
+	}
+	return query
+}
@@ -0,0 +1,90 @@
+// Package safety checks that the database is quiet enough to migrate, so a
+// migration that locks tables does not stall a busy application.
+package safety
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Connection is a session of another client that is running a query or
+// holding a transaction open.
+type Connection struct {
+	PID         int
+	User        string
+	Application string
+	State       string
+	// Since is when the current query started, or nil if it is unknown.
+	Since *time.Time
+	Query string
+}
+
+// ConnectionChecker counts the other clients' busy sessions on a database.
+// Sessions that hold the advisory lock LockKey, the tool's own, are not
+// counted.
+type ConnectionChecker struct {
+	db        *sql.DB
+	threshold int
+	lockKey   int64
+}
+
+// NewConnectionChecker returns a checker for db that tolerates up to
+// threshold busy sessions besides those holding the advisory lock lockKey.
+func NewConnectionChecker(db *sql.DB, threshold int, lockKey int64) *ConnectionChecker {
+	return &ConnectionChecker{db: db, threshold: threshold, lockKey: lockKey}
+}
+
+// activeQuery lists the client sessions on the current database, other than
+// this one and the holders of the advisory lock $1, that are not idle:
+// running a query or idle in a transaction, which can hold locks as well.
+// A bigint advisory lock is recorded in pg_locks as its high 32 bits in
+// classid and its low 32 bits in objid.
+const activeQuery = `
+SELECT a.pid, coalesce(a.usename, ''), a.application_name, a.state, a.query_start, a.query
+FROM pg_stat_activity a
+WHERE a.datname = current_database()
+  AND a.backend_type = 'client backend'
+  AND a.pid <> pg_backend_pid()
+  AND a.state IS NOT NULL AND a.state <> 'idle'
+  AND a.pid NOT IN (
+    SELECT l.pid FROM pg_locks l
+    WHERE l.locktype = 'advisory' AND l.granted AND l.objsubid = 1
+      AND (l.classid::bigint << 32 | l.objid::bigint) = $1
+  )
+ORDER BY a.query_start NULLS LAST, a.pid`
+
+// Active returns every busy session that counts against the threshold,
+// longest running first.
+func (c *ConnectionChecker) Active(ctx context.Context) ([]Connection, error) {
+	rows, err := c.db.QueryContext(ctx, activeQuery, c.lockKey)
+	if err != nil {
+		return nil, fmt.Errorf("read pg_stat_activity: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Connection
+	for rows.Next() {
+		var conn Connection
+		var since sql.NullTime
+		if err := rows.Scan(&conn.PID, &conn.User, &conn.Application, &conn.State, &since, &conn.Query); err != nil {
+			return nil, err
+		}
+		if since.Valid {
+			conn.Since = &since.Time
+		}
+		list = append(list, conn)
+	}
+	return list, rows.Err()
+}
+
+// Blocking returns the busy sessions if there are more than the threshold,
+// and nil otherwise. What to do about them is left to the caller.
+func (c *ConnectionChecker) Blocking(ctx context.Context) ([]Connection, error) {
+	active, err := c.Active(ctx)
+	if err != nil || len(active) <= c.threshold {
+		return nil, err
+	}
+	return active, nil
+}
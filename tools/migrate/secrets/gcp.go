@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const secretManagerEndpoint = "https://secretmanager.googleapis.com"
+
+// GCPSecretManagerClient reads secrets from the Google Cloud Secret Manager
+// REST API, authenticating with Application Default Credentials as
+// GCPCredentials does.
+type GCPSecretManagerClient struct {
+	Client *http.Client
+
+	// Endpoint and MetadataURL override the API and metadata server
+	// locations, for tests.
+	Endpoint    string
+	MetadataURL string
+
+	// Getenv looks up GOOGLE_APPLICATION_CREDENTIALS and CLOUDSDK_CONFIG;
+	// nil means os.Getenv.
+	Getenv func(key string) string
+}
+
+type accessResponse struct {
+	Payload struct {
+		Data       string `json:"data"`
+		DataCrc32c string `json:"dataCrc32c"`
+	} `json:"payload"`
+}
+
+// AccessLatest returns the payload of the latest version of secret in
+// project.
+func (c *GCPSecretManagerClient) AccessLatest(ctx context.Context, project, secret string) (string, error) {
+	token, err := c.token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get gcp credentials: %w", err)
+	}
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = secretManagerEndpoint
+	}
+	u := strings.TrimRight(endpoint, "/") + "/v1/projects/" + url.PathEscape(project) +
+		"/secrets/" + url.PathEscape(secret) + "/versions/latest:access"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secret manager returned %s for %s/%s", resp.Status, project, secret)
+	}
+
+	var body accessResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode secret manager response: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(body.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("decode secret payload: %w", err)
+	}
+	if body.Payload.DataCrc32c != "" {
+		want, err := strconv.ParseUint(body.Payload.DataCrc32c, 10, 32)
+		if err != nil || crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)) != uint32(want) {
+			return "", fmt.Errorf("secret %s/%s failed its checksum", project, secret)
+		}
+	}
+	return string(data), nil
+}
+
+func (c *GCPSecretManagerClient) httpClient() *http.Client {
+	if c.Client == nil {
+		return http.DefaultClient
+	}
+	return c.Client
+}
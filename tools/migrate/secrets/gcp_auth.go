@@ -0,0 +1,226 @@
+package secrets
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	googleTokenURL   = "https://oauth2.googleapis.com/token"
+	cloudPlatform    = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// credentialsFile is the subset of a Google credentials JSON file needed
+// for service account keys and gcloud user credentials.
+type credentialsFile struct {
+	Type string `json:"type"`
+
+	// service_account
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+
+	// authorized_user
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// GCPCredentials obtains OAuth2 access tokens for the Google Cloud APIs
+// from the first Application Default Credentials source available: the
+// credentials file named by GOOGLE_APPLICATION_CREDENTIALS, then the one
+// written by `gcloud auth application-default login`, then the metadata
+// server of the instance it runs on.
+type GCPCredentials struct {
+	Client *http.Client
+
+	// MetadataURL overrides the metadata server's token endpoint, for
+	// tests.
+	MetadataURL string
+
+	// Getenv looks up GOOGLE_APPLICATION_CREDENTIALS and CLOUDSDK_CONFIG;
+	// nil means os.Getenv.
+	Getenv func(key string) string
+}
+
+// Token returns an access token with the cloud-platform scope.
+func (c *GCPCredentials) Token(ctx context.Context) (string, error) {
+	getenv := c.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	if path := getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return c.tokenFromFile(ctx, path)
+	}
+	if path := wellKnownCredentialsFile(getenv); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			return c.tokenFromFile(ctx, path)
+		}
+	}
+	return c.metadataToken(ctx)
+}
+
+// token returns an access token for the Secret Manager client.
+func (c *GCPSecretManagerClient) token(ctx context.Context) (string, error) {
+	creds := &GCPCredentials{Client: c.httpClient(), MetadataURL: c.MetadataURL, Getenv: c.Getenv}
+	return creds.Token(ctx)
+}
+
+// wellKnownCredentialsFile is where `gcloud auth application-default login`
+// stores user credentials.
+func wellKnownCredentialsFile(getenv func(string) string) string {
+	if dir := getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return filepath.Join(dir, "application_default_credentials.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
+
+func (c *GCPCredentials) tokenFromFile(ctx context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var creds credentialsFile
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return "", fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	switch creds.Type {
+	case "service_account":
+		tokenURL := creds.TokenURI
+		if tokenURL == "" {
+			tokenURL = googleTokenURL
+		}
+		assertion, err := signJWT(creds, tokenURL, time.Now())
+		if err != nil {
+			return "", fmt.Errorf("sign token request: %w", err)
+		}
+		return c.exchange(ctx, tokenURL, url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		})
+	case "authorized_user":
+		return c.exchange(ctx, googleTokenURL, url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {creds.ClientID},
+			"client_secret": {creds.ClientSecret},
+			"refresh_token": {creds.RefreshToken},
+		})
+	default:
+		return "", fmt.Errorf("%s: unsupported credentials type %q", path, creds.Type)
+	}
+}
+
+// signJWT builds the RS256-signed assertion a service account exchanges for
+// an access token.
+func signJWT(creds credentialsFile, audience string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", errors.New("private_key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return "", err
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("private_key is not an RSA key")
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": creds.PrivateKeyID})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   creds.ClientEmail,
+		"scope": cloudPlatform,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(signature), nil
+}
+
+func (c *GCPCredentials) exchange(ctx context.Context, tokenURL string, form url.Values) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.doToken(req)
+}
+
+func (c *GCPCredentials) metadataToken(ctx context.Context) (string, error) {
+	u := c.MetadataURL
+	if u == "" {
+		u = metadataTokenURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	return c.doToken(req)
+}
+
+func (c *GCPCredentials) doToken(req *http.Request) (string, error) {
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("token endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var body tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", errors.New("token response has no access_token")
+	}
+	return body.AccessToken, nil
+}
+
+func (c *GCPCredentials) client() *http.Client {
+	if c.Client == nil {
+		return http.DefaultClient
+	}
+	return c.Client
+}
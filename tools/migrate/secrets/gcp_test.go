@@ -0,0 +1,68 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestGCPSecretManagerClientAccessLatest(t *testing.T) {
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("CLOUDSDK_CONFIG", t.TempDir())
+
+	payload := []byte("postgres://u:p@db/app")
+	checksum := crc32.Checksum(payload, crc32.MakeTable(crc32.Castagnoli))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if got := r.Header.Get("Metadata-Flavor"); got != "Google" {
+				t.Errorf("Metadata-Flavor = %q, want Google", got)
+			}
+			fmt.Fprint(w, `{"access_token":"ya29.token","expires_in":3599,"token_type":"Bearer"}`)
+		case "/v1/projects/bots/secrets/db-url/versions/latest:access":
+			if got := r.Header.Get("Authorization"); got != "Bearer ya29.token" {
+				t.Errorf("Authorization = %q, want Bearer ya29.token", got)
+			}
+			fmt.Fprintf(w, `{"name":"projects/1/secrets/db-url/versions/4","payload":{"data":%q,"dataCrc32c":"%d"}}`,
+				base64.StdEncoding.EncodeToString(payload), checksum)
+		default:
+			t.Errorf("unexpected request for %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := &GCPSecretManagerClient{Endpoint: server.URL, MetadataURL: server.URL + "/token"}
+	got, err := client.AccessLatest(context.Background(), "bots", "db-url")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(payload) {
+		t.Errorf("AccessLatest = %q, want %q", got, payload)
+	}
+}
+
+func TestGCPSecretManagerClientChecksumMismatch(t *testing.T) {
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("CLOUDSDK_CONFIG", filepath.Join(t.TempDir(), "missing"))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"access_token":"ya29.token"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"payload":{"data":%q,"dataCrc32c":"1"}}`, base64.StdEncoding.EncodeToString([]byte("x")))
+	}))
+	defer server.Close()
+
+	client := &GCPSecretManagerClient{Endpoint: server.URL, MetadataURL: server.URL + "/token"}
+	if _, err := client.AccessLatest(context.Background(), "bots", "db-url"); err == nil {
+		t.Error("AccessLatest succeeded, want a checksum error")
+	}
+}
@@ -0,0 +1,63 @@
+//go:build aws
+
+package secrets
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// SSMClient reads parameters from AWS Systems Manager Parameter Store using
+// the standard AWS credential chain.
+type SSMClient struct {
+	api *ssm.Client
+}
+
+// NewSSMClient returns an SSMClient configured by LoadAWSConfig.
+func NewSSMClient(ctx context.Context, region string, getenv func(key string) string) (*SSMClient, error) {
+	cfg, err := LoadAWSConfig(ctx, region, getenv)
+	if err != nil {
+		return nil, err
+	}
+	return &SSMClient{api: ssm.NewFromConfig(cfg)}, nil
+}
+
+// LoadAWSConfig loads the AWS configuration for region, or for the region
+// the shared AWS configuration names when region is empty. Static keys in
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are looked up with getenv,
+// so that keys from a .env file are used as if they were in the process
+// environment; a nil getenv leaves the credential chain to the SDK.
+func LoadAWSConfig(ctx context.Context, region string, getenv func(key string) string) (aws.Config, error) {
+	var opts []func(*config.LoadOptions) error
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	if getenv != nil {
+		if id, secret := getenv("AWS_ACCESS_KEY_ID"), getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+			opts = append(opts, config.WithCredentialsProvider(
+				credentials.NewStaticCredentialsProvider(id, secret, getenv("AWS_SESSION_TOKEN"))))
+		}
+	}
+	return config.LoadDefaultConfig(ctx, opts...)
+}
+
+// Parameter returns the value of the parameter called name, decrypting it
+// if it is a SecureString.
+func (c *SSMClient) Parameter(ctx context.Context, name string) (string, error) {
+	out, err := c.api.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	if out.Parameter == nil || out.Parameter.Value == nil {
+		return "", errors.New("parameter " + name + " has no value")
+	}
+	return *out.Parameter.Value, nil
+}
@@ -0,0 +1,110 @@
+// Package secrets fetches credentials from external secret stores.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// VaultClient reads secrets from the HashiCorp Vault HTTP API. The token is
+// only ever held in memory.
+type VaultClient struct {
+	Addr   string
+	Token  string
+	Client *http.Client
+}
+
+// vaultResponse covers both KV engine versions: version 1 returns the
+// secret's fields in data, version 2 nests them in data.data.
+type vaultResponse struct {
+	Data map[string]json.RawMessage `json:"data"`
+}
+
+// Read fetches the secret at path, such as "secret/data/crypto-bot", and
+// returns its string fields.
+func (c *VaultClient) Read(ctx context.Context, path string) (map[string]string, error) {
+	url := strings.TrimRight(c.Addr, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", c.Token)
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned %s for %s", resp.Status, path)
+	}
+
+	var body vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode vault response: %w", err)
+	}
+
+	data := body.Data
+	if nested, ok := data["data"]; ok {
+		var inner map[string]json.RawMessage
+		if err := json.Unmarshal(nested, &inner); err == nil {
+			data = inner
+		}
+	}
+
+	fields := make(map[string]string, len(data))
+	for key, raw := range data {
+		var value string
+		if json.Unmarshal(raw, &value) == nil {
+			fields[key] = value
+		}
+	}
+	return fields, nil
+}
+
+// DatabaseURL fetches the DATABASE_URL field of the secret at path.
+func (c *VaultClient) DatabaseURL(ctx context.Context, path string) (string, error) {
+	return c.Field(ctx, path, "DATABASE_URL")
+}
+
+// Field fetches the field called field of the secret at path.
+func (c *VaultClient) Field(ctx context.Context, path, field string) (string, error) {
+	fields, err := c.Read(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	value := fields[field]
+	if value == "" {
+		return "", errors.New("vault secret " + path + " has no " + field + " field")
+	}
+	return value, nil
+}
+
+// ParseVaultRef splits a reference of the form vault://<path>#<field>, as
+// given with the !secret tag in migrate.yaml, into the secret's path and the
+// field to read, which defaults to DATABASE_URL.
+func ParseVaultRef(ref string) (path, field string, err error) {
+	rest, ok := strings.CutPrefix(ref, "vault://")
+	if !ok {
+		return "", "", fmt.Errorf("%q is not a vault:// reference", ref)
+	}
+	path, field, _ = strings.Cut(rest, "#")
+	if path == "" {
+		return "", "", fmt.Errorf("%q has no secret path", ref)
+	}
+	if field == "" {
+		field = "DATABASE_URL"
+	}
+	return path, field, nil
+}
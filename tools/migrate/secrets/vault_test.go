@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVaultClientDatabaseURL(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"kv v1", `{"data":{"DATABASE_URL":"postgres://u:p@db/app"}}`},
+		{"kv v2", `{"data":{"data":{"DATABASE_URL":"postgres://u:p@db/app"},"metadata":{"version":3}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					t.Errorf("method = %s, want GET", r.Method)
+				}
+				if r.URL.Path != "/v1/secret/data/crypto-bot" {
+					t.Errorf("path = %s, want /v1/secret/data/crypto-bot", r.URL.Path)
+				}
+				if got := r.Header.Get("X-Vault-Token"); got != "s.token" {
+					t.Errorf("X-Vault-Token = %q, want s.token", got)
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := &VaultClient{Addr: server.URL + "/", Token: "s.token"}
+			got, err := client.DatabaseURL(context.Background(), "secret/data/crypto-bot")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != "postgres://u:p@db/app" {
+				t.Errorf("DatabaseURL = %q", got)
+			}
+		})
+	}
+}
+
+func TestVaultClientErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"forbidden", http.StatusForbidden, `{"errors":["permission denied"]}`, "403"},
+		{"missing field", http.StatusOK, `{"data":{"OTHER":"x"}}`, "no DATABASE_URL"},
+		{"malformed", http.StatusOK, `not json`, "decode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := &VaultClient{Addr: server.URL, Token: "s.token"}
+			_, err := client.DatabaseURL(context.Background(), "secret/app")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseVaultRef(t *testing.T) {
+	tests := []struct {
+		ref         string
+		path, field string
+		wantErr     bool
+	}{
+		{ref: "vault://secret/data/crypto-bot", path: "secret/data/crypto-bot", field: "DATABASE_URL"},
+		{ref: "vault://secret/data/staging#url", path: "secret/data/staging", field: "url"},
+		{ref: "vault://#url", wantErr: true},
+		{ref: "ssm://db-url", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		path, field, err := ParseVaultRef(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseVaultRef(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if path != tt.path || field != tt.field {
+			t.Errorf("ParseVaultRef(%q) = %q, %q, want %q, %q", tt.ref, path, field, tt.path, tt.field)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/seed"
+)
+
+// runSeed loads the fixtures in --seed-dir into the database, talking to it
+// directly so that Cargo is not needed.
+func runSeed(cfg Config) int {
+	if info, err := os.Stat(cfg.SeedDir); err != nil || !info.IsDir() {
+		printer.Error("Error: seed directory %s does not exist (use --seed-dir to override)", cfg.SeedDir)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	failed := false
+	seeder := &seed.Seeder{
+		DB:              conn,
+		ContinueOnError: cfg.ContinueOnError,
+		OnApply: func(name string) {
+			printer.Info("Seeding %s", name)
+		},
+		OnError: func(name string, err error) {
+			failed = true
+			printer.Error("Error: %v", err)
+		},
+	}
+	if err := seeder.Run(ctx, cfg.SeedDir); err != nil {
+		// Failures of individual files have been reported already.
+		if !failed {
+			printer.Error("Error: %v", err)
+		}
+		return 1
+	}
+
+	printer.Success("Seeding completed successfully")
+	return 0
+}
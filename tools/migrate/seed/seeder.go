@@ -0,0 +1,84 @@
+// Package seed loads SQL fixture files into a database.
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Seeder applies every .sql file in a directory, in alphabetical order,
+// each in its own transaction so that a failing file leaves nothing behind.
+type Seeder struct {
+	DB *sql.DB
+	// ContinueOnError applies the remaining files after one fails instead
+	// of stopping.
+	ContinueOnError bool
+	// OnApply, if set, is called with each file name before it is applied.
+	OnApply func(name string)
+	// OnError, if set, is called for each file that fails.
+	OnError func(name string, err error)
+}
+
+// Run applies the seed files in dir. With ContinueOnError it returns every
+// failure joined together once all files have been tried; otherwise it
+// stops at the first one.
+func (s *Seeder) Run(ctx context.Context, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if s.OnApply != nil {
+			s.OnApply(name)
+		}
+
+		err := s.apply(ctx, filepath.Join(dir, name))
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("%s: %w", name, err)
+		if s.OnError != nil {
+			s.OnError(name, err)
+		}
+		if !s.ContinueOnError {
+			return err
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Seeder) apply(ctx context.Context, path string) error {
+	query, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Without arguments the statement goes over the simple query protocol,
+	// which lets a file hold several statements.
+	if _, err := tx.ExecContext(ctx, string(query)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
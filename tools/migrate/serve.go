@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/server"
+)
+
+// serveShutdownTimeout bounds how long serve waits for a running migration
+// to finish after it is told to stop.
+const serveShutdownTimeout = 5 * time.Minute
+
+// runServe serves POST /migrate on --port until interrupted.
+func runServe(cfg Config) int {
+	exe, err := os.Executable()
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	srv := &http.Server{
+		Addr:              net.JoinHostPort("", strconv.Itoa(cfg.Port)),
+		Handler:           server.New(cfg.Token, serveRunner(cfg, exe)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+	printer.Info("Listening on %s", srv.Addr)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-errs:
+		printer.Error("Error: %v", err)
+		return 1
+	case <-signals:
+	}
+
+	printer.Info("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// serveRunner returns a server.RunFunc that runs each migration in a child
+// process of this binary, so that its output, which normally goes to the
+// terminal, can be streamed to the client. The child is deliberately not
+// tied to the request: a client that disconnects does not abort a
+// migration halfway.
+func serveRunner(cfg Config, exe string) server.RunFunc {
+	return func(command string, out io.Writer) int {
+		printer.Info("Running %s for a remote request", command)
+
+		cmd := exec.Command(exe, serveArgs(cfg, command)...)
+		cmd.Stdout = out
+		cmd.Stderr = out
+		cmd.Env = append(environment.Environ(), "DATABASE_URL="+cfg.DatabaseURL, "MIGRATE_ENGINE="+cfg.Engine)
+		if table := getenv("MIGRATE_TABLE_NAME"); table != "" {
+			cmd.Env = append(cmd.Env, "MIGRATE_TABLE_NAME="+table)
+		}
+
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			printer.Success("Remote %s finished", command)
+			return 0
+		case errors.As(err, &exitErr) && exitErr.ExitCode() > 0:
+			printer.Error("Remote %s exited with %d", command, exitErr.ExitCode())
+			return exitErr.ExitCode()
+		default:
+			printer.Error("Remote %s failed: %v", command, err)
+			return 1
+		}
+	}
+}
+
+// serveArgs returns the command line the child running command for serve
+// is given: the global flags serve was started with, the lock and
+// migration timeouts, and --no-color, since the output goes to a client
+// rather than a terminal. The child reads the same configuration file and
+// environment, so that the file's database_url does not replace the one
+// the --env environment gave serve.
+func serveArgs(cfg Config, command string) []string {
+	args := []string{command, "--migration-dir", cfg.MigrationDir, "--no-color",
+		"--lock-timeout", cfg.LockTimeout.String()}
+	for _, file := range cfg.EnvFiles {
+		args = append(args, "--env-file", file)
+	}
+	if cfg.SkipValidation {
+		args = append(args, "--skip-validation")
+	}
+	if cfg.RedactLogs {
+		args = append(args, "--redact-logs")
+	}
+	if cfg.CargoBin != "" {
+		args = append(args, "--cargo-bin", cfg.CargoBin)
+	}
+	if cfg.File.Path != "" {
+		args = append(args, "--config", cfg.File.Path)
+	}
+	if cfg.Env != "" {
+		args = append(args, "--env", cfg.Env)
+	}
+	if cfg.Confirm {
+		args = append(args, "--confirm")
+	}
+	if cfg.OtelEndpoint != "" {
+		args = append(args, "--otel-endpoint", cfg.OtelEndpoint)
+	}
+	if cfg.VaultTimeout != 0 {
+		args = append(args, "--vault-timeout", cfg.VaultTimeout.String())
+	}
+	if cfg.Timeout > 0 {
+		args = append(args, "--timeout", cfg.Timeout.String())
+	}
+	return args
+}
@@ -0,0 +1,116 @@
+// Package server exposes migrations over HTTP so that a deployment platform
+// can trigger them with a webhook.
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Commands are the migration commands a request may ask for. fresh is left
+// out on purpose: dropping every table should not be one POST away.
+var Commands = []string{"up", "down", "status"}
+
+// maxBodyBytes bounds the request body, which only ever holds a command.
+const maxBodyBytes = 1 << 10
+
+// RunFunc runs command, writing its output to out, and returns its exit
+// code.
+type RunFunc func(command string, out io.Writer) int
+
+// Server is an http.Handler that runs a migration for each authorised
+// `POST /migrate` request whose JSON body names one of Commands, such as
+// {"command":"up"}, and streams its output back as text/plain. Requests are
+// served one at a time; later ones wait for the running migration to
+// finish. The exit code follows the output and is also sent in the
+// Migrate-Exit-Code trailer.
+type Server struct {
+	token string
+	run   RunFunc
+
+	mu sync.Mutex
+}
+
+// New returns a Server that accepts requests bearing token and hands them
+// to run.
+func New(token string, run RunFunc) *Server {
+	return &Server{token: token, run: run}
+}
+
+type request struct {
+	Command string `json:"command"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/migrate" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(Commands, req.Command) {
+		http.Error(w, fmt.Sprintf("unknown command %q; expected one of %s", req.Command, strings.Join(Commands, ", ")),
+			http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Trailer", "Migrate-Exit-Code")
+	w.WriteHeader(http.StatusOK)
+
+	out := &flushWriter{w: w}
+	if f, ok := w.(http.Flusher); ok {
+		out.f = f
+	}
+	code := s.run(req.Command, out)
+	fmt.Fprintf(out, "exit code: %d\n", code)
+	w.Header().Set("Migrate-Exit-Code", fmt.Sprint(code))
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// flushWriter flushes after every write so that output reaches the client
+// as it is produced rather than when the migration ends. The runner may
+// write from more than one goroutine.
+type flushWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+	f  http.Flusher
+}
+
+func (fw *flushWriter) Write(b []byte) (int, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	n, err := fw.w.Write(b)
+	if fw.f != nil {
+		fw.f.Flush()
+	}
+	return n, err
+}
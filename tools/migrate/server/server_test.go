@@ -0,0 +1,58 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		token      string
+		body       string
+		wantStatus int
+		wantRun    string
+	}{
+		{"runs command", http.MethodPost, "secret", `{"command":"up"}`, http.StatusOK, "up"},
+		{"wrong token", http.MethodPost, "guess", `{"command":"up"}`, http.StatusUnauthorized, ""},
+		{"missing token", http.MethodPost, "", `{"command":"up"}`, http.StatusUnauthorized, ""},
+		{"unknown command", http.MethodPost, "secret", `{"command":"fresh"}`, http.StatusBadRequest, ""},
+		{"invalid body", http.MethodPost, "secret", `up`, http.StatusBadRequest, ""},
+		{"wrong method", http.MethodGet, "secret", "", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran string
+			srv := New("secret", func(command string, out io.Writer) int {
+				ran = command
+				fmt.Fprintln(out, "applied 1 migration")
+				return 0
+			})
+
+			req := httptest.NewRequest(tt.method, "/migrate", strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if ran != tt.wantRun {
+				t.Errorf("ran %q, want %q", ran, tt.wantRun)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if want := "applied 1 migration\nexit code: 0\n"; rec.Body.String() != want {
+					t.Errorf("body = %q, want %q", rec.Body.String(), want)
+				}
+			}
+		})
+	}
+}
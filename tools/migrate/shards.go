@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/shards"
+)
+
+// runShards applies the configured command to every shard listed in
+// --shards and prints a per-shard summary.
+func runShards(cfg Config) int {
+	list, err := shards.Load(cfg.ShardsFile)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	parallelism := 1
+	if cfg.Parallel {
+		parallelism = cfg.Parallelism
+	}
+	spinnerDisabled = parallelism > 1
+
+	results := shards.Run(list, shards.Options{
+		Parallelism:     parallelism,
+		ContinueOnError: cfg.ContinueOnError,
+	}, func(shard shards.Shard) int {
+		printer.Info("Shard %s", shard.Name)
+		shardCfg := cfg
+		shardCfg.DatabaseURL = shard.DatabaseURL
+		return migrateDatabase(context.Background(), shardCfg)
+	})
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SHARD\tSTATUS\tEXIT CODE\tDURATION")
+	exitCode := 0
+	for _, result := range results {
+		duration := "-"
+		if result.Status != shards.StatusSkipped {
+			duration = result.Duration.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", result.Shard.Name, result.Status, result.ExitCode, duration)
+		if result.Status != shards.StatusOK {
+			exitCode = 1
+		}
+	}
+	w.Flush()
+
+	return exitCode
+}
@@ -0,0 +1,86 @@
+package shards
+
+import (
+	"sync"
+	"time"
+)
+
+// Status is the outcome of migrating one shard.
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+)
+
+// Result reports how migrating a shard went.
+type Result struct {
+	Shard    Shard
+	Status   Status
+	ExitCode int
+	Duration time.Duration
+}
+
+// Options controls how Run schedules shards.
+type Options struct {
+	// Parallelism is the maximum number of shards migrated at once. Values
+	// below 1 run the shards one at a time.
+	Parallelism int
+	// ContinueOnError keeps migrating the remaining shards after a failure
+	// instead of skipping them.
+	ContinueOnError bool
+}
+
+// Run calls migrate for every shard and returns one result per shard in the
+// order given. migrate returns the exit code of the migration; any non-zero
+// code counts as a failure. Unless opts.ContinueOnError is set, shards that
+// have not started when a failure occurs are skipped.
+func Run(shards []Shard, opts Options, migrate func(Shard) int) []Result {
+	parallelism := opts.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	results := make([]Result, len(shards))
+	semaphore := make(chan struct{}, parallelism)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+	)
+
+	for i, shard := range shards {
+		semaphore <- struct{}{}
+
+		mu.Lock()
+		skip := failed && !opts.ContinueOnError
+		mu.Unlock()
+		if skip {
+			<-semaphore
+			results[i] = Result{Shard: shard, Status: StatusSkipped}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, shard Shard) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			start := time.Now()
+			exitCode := migrate(shard)
+			result := Result{Shard: shard, Status: StatusOK, ExitCode: exitCode, Duration: time.Since(start)}
+			if exitCode != 0 {
+				result.Status = StatusFailed
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+			results[i] = result
+		}(i, shard)
+	}
+
+	wg.Wait()
+	return results
+}
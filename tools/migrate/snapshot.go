@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/crypto-bot/tools/migrate/backup"
+)
+
+// runSnapshot writes the schema of the database to --output using pg_dump,
+// so the cumulative effect of the migrations can be reviewed and checked in.
+// A missing pg_dump is only a warning, which keeps the step optional in CI.
+func runSnapshot(cfg Config) int {
+	pgDump, err := exec.LookPath("pg_dump")
+	if err != nil {
+		printer.Warn("Warning: pg_dump not found in PATH; skipping schema snapshot")
+		return 0
+	}
+
+	vars, err := backup.Env(cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	// Dump into a temporary file next to the output so that a failed dump
+	// never leaves a truncated schema behind.
+	tmp, err := os.CreateTemp(filepath.Dir(cfg.Output), ".schema-*.sql")
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	defer os.Remove(tmp.Name())
+
+	printer.Info("Dumping schema to %s", cfg.Output)
+
+	cmd := exec.Command(pgDump, "--schema-only")
+	cmd.Stdout = tmp
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(environment.Environ(), vars...)
+	err = cmd.Run()
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		printer.Error("Snapshot failed: %v", err)
+		return 1
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if err := os.Rename(tmp.Name(), cfg.Output); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	printer.Success("Schema written to %s", cfg.Output)
+	return 0
+}
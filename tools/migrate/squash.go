@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/internal/lock"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"github.com/crypto-bot/tools/migrate/squash"
+)
+
+// runSquash replaces every migration with a baseline holding the schema the
+// database has now: it archives the migration files, writes the baseline
+// from pg_dump --schema-only, and leaves only the baseline in
+// the tracking table. It prints the plan and stops unless --confirm is given,
+// and refuses to run while migrations are pending, since their changes
+// would be missing from the baseline.
+func runSquash(cfg Config) int {
+	var namer migrations.MigrationNamer
+
+	sequence, name, ok := namer.Parse(cfg.Output)
+	if !ok {
+		printer.Error("Error: %s is not a migration file name such as 000001_baseline.up.sql", cfg.Output)
+		return 1
+	}
+	upPath := filepath.Join(cfg.SQLDir(), cfg.Output)
+	archiveDir := filepath.Join(cfg.MigrationDir, "archive")
+
+	files, state, err := migrationState(cfg)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if len(files) == 0 {
+		printer.Error("Error: no migrations in %s to squash", cfg.SQLDir())
+		return 1
+	}
+	var pending []string
+	for _, m := range state {
+		if !m.Applied {
+			pending = append(pending, namer.Base(int(m.Version), m.Name))
+		}
+	}
+	if len(pending) > 0 {
+		printer.Error("Error: %d migration(s) are not applied: %s", len(pending), strings.Join(pending, ", "))
+		printer.Error("Run up first; the baseline is dumped from the database and would leave them out")
+		return 1
+	}
+
+	paths, err := squash.Files(cfg.SQLDir())
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	first, last := files[0], files[len(files)-1]
+
+	printer.Info("Squash plan for %s on %s:", databaseName(cfg.DatabaseURL), databaseHost(cfg.DatabaseURL))
+	printer.Info("  1. Dump the schema with pg_dump --schema-only into %s", upPath)
+	printer.Info("  2. Write %s: %s", squash.DownPath(upPath), strings.ReplaceAll(strings.TrimSpace(squash.DownSQL), "\n", " "))
+	printer.Info("  3. Move %d file(s) of %d migration(s), %s to %s, into %s",
+		len(paths), len(files), namer.Base(first.Sequence, first.Name), namer.Base(last.Sequence, last.Name), archiveDir)
+	printer.Info("  4. Replace every row of %s with %s", db.RecordsTable(), namer.Base(sequence, name))
+	if !cfg.Yes {
+		printer.Error("Error: squash rewrites the migration history; rerun with --confirm to carry out this plan")
+		return 1
+	}
+
+	release, err := acquireMigrationLock(context.Background(), cfg.DatabaseURL, cfg.LockTimeout)
+	if errors.Is(err, lock.ErrTimeout) {
+		printer.Error("Error: another migration is running (lock not acquired within %s)", cfg.LockTimeout)
+		return exitLockContention
+	}
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	defer release()
+
+	start := time.Now()
+	exitCode := 0
+	if err := squashMigrations(cfg, paths, archiveDir, upPath, int64(sequence), name); err != nil {
+		printer.Error("Squash failed: %v", err)
+		exitCode = 1
+	}
+	recordAudit(cfg, start, exitCode, time.Since(start))
+	if exitCode == 0 {
+		printer.Success("Squashed %d migration(s) into %s", len(files), upPath)
+	}
+	return exitCode
+}
+
+// squashMigrations carries out the plan printed by runSquash. The files are
+// put back if the tracking table cannot be updated, so a failure leaves the
+// project as it was.
+func squashMigrations(cfg Config, paths []string, archiveDir, upPath string, sequence int64, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	var schema bytes.Buffer
+	fmt.Fprintf(&schema, "-- Baseline of %d migration files squashed on %s.\n\n", len(paths), time.Now().UTC().Format(time.RFC3339))
+	if err := newDumper(cfg.DatabaseURL).Schema(ctx, &schema, db.MigrationsTable(), db.RecordsTable()); err != nil {
+		return err
+	}
+
+	restore, err := squash.Archive(paths, archiveDir)
+	if err != nil {
+		return fmt.Errorf("archive migrations: %w", err)
+	}
+	undo := func(err error) error {
+		os.Remove(upPath)
+		os.Remove(squash.DownPath(upPath))
+		if restoreErr := restore(); restoreErr != nil {
+			return fmt.Errorf("%w; moving the migrations back from %s also failed: %v", err, archiveDir, restoreErr)
+		}
+		return err
+	}
+	if err := squash.WriteBaseline(upPath, schema.Bytes()); err != nil {
+		return undo(fmt.Errorf("write baseline: %w", err))
+	}
+	printer.Success("✓ Archived %d file(s) to %s", len(paths), archiveDir)
+
+	data, err := os.ReadFile(upPath)
+	if err != nil {
+		return undo(err)
+	}
+	checksum := sha512.Sum384(data)
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return undo(fmt.Errorf("connect to database: %w", err))
+	}
+	defer conn.Close()
+	removed, err := squash.ResetTracking(ctx, conn, sequence, name, checksum[:])
+	if err != nil {
+		return undo(err)
+	}
+	printer.Success("✓ Replaced %d row(s) of %s with the baseline", removed, db.RecordsTable())
+	return nil
+}
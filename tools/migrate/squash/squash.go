@@ -0,0 +1,145 @@
+// Package squash replaces a long migration history with a single baseline
+// migration holding the current schema, so that fresh databases no longer
+// replay every migration.
+package squash
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// DownSQL is the down migration of a baseline: it empties the schema the
+// baseline created.
+const DownSQL = "DROP SCHEMA public CASCADE;\nCREATE SCHEMA public;\n"
+
+// resetSearchPath ends a baseline. pg_dump clears search_path for the
+// session, and the migrator records the baseline on the same connection
+// once it has run.
+const resetSearchPath = "\nRESET search_path;\n"
+
+// DownPath returns the down file that belongs with the up file upPath.
+func DownPath(upPath string) string {
+	return strings.TrimSuffix(upPath, ".up.sql") + ".down.sql"
+}
+
+// Files returns the paths of every migration file in dir, up and down, in
+// name order.
+func Files(dir string) ([]string, error) {
+	var namer migrations.MigrationNamer
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		if _, _, ok := namer.Parse(entry.Name()); ok && !entry.IsDir() {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// Archive moves paths into dir, creating it if needed. It refuses to
+// overwrite a file already archived by an earlier squash. On success it
+// returns a function that moves the files back.
+func Archive(paths []string, dir string) (restore func() error, err error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		target := filepath.Join(dir, filepath.Base(path))
+		if _, err := os.Lstat(target); err == nil {
+			return nil, fmt.Errorf("%s is already archived", target)
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	var moved []string
+	restore = func() error {
+		var errs []error
+		for _, path := range moved {
+			if err := os.Rename(filepath.Join(dir, filepath.Base(path)), path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+	for _, path := range paths {
+		if err := os.Rename(path, filepath.Join(dir, filepath.Base(path))); err != nil {
+			if restoreErr := restore(); restoreErr != nil {
+				err = errors.Join(err, restoreErr)
+			}
+			return nil, err
+		}
+		moved = append(moved, path)
+	}
+	return restore, nil
+}
+
+// Baseline returns the up migration of a baseline holding schema, a
+// plain-format pg_dump of the database.
+func Baseline(schema []byte) []byte {
+	return append(append([]byte(nil), schema...), resetSearchPath...)
+}
+
+// WriteBaseline writes the Baseline of schema to upPath and DownSQL to the
+// matching down file. Neither may exist yet.
+func WriteBaseline(upPath string, schema []byte) error {
+	if err := writeNew(upPath, Baseline(schema)); err != nil {
+		return err
+	}
+	if err := writeNew(DownPath(upPath), []byte(DownSQL)); err != nil {
+		os.Remove(upPath)
+		return err
+	}
+	return nil
+}
+
+func writeNew(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// ResetTracking replaces every row of db.RecordsTable with one recording the
+// baseline with version and name as applied, in a single transaction; the
+// rows of migrations written in Rust stay.
+// checksum is the SHA-384 of the baseline's up file. It returns the number
+// of rows removed.
+func ResetTracking(ctx context.Context, conn *sql.DB, version int64, name string, checksum []byte) (int64, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := db.EnsureTable(ctx, tx); err != nil {
+		return 0, fmt.Errorf("create %s: %w", db.RecordsTable(), err)
+	}
+	removed, err := db.ClearRecords(ctx, tx)
+	if err != nil {
+		return 0, fmt.Errorf("clear %s: %w", db.RecordsTable(), err)
+	}
+	if err := db.RecordApplied(ctx, tx, version, name, checksum, 0); err != nil {
+		return 0, fmt.Errorf("record baseline: %w", err)
+	}
+	return removed, tx.Commit()
+}
@@ -0,0 +1,85 @@
+package squash
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFilesAndArchive(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "000001_init.up.sql", "000001_init.down.sql", "000002_wallets.sql", "notes.txt")
+
+	paths, err := Files(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "000001_init.down.sql"),
+		filepath.Join(dir, "000001_init.up.sql"),
+		filepath.Join(dir, "000002_wallets.sql"),
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("Files() = %v, want %v", paths, want)
+	}
+
+	archive := filepath.Join(t.TempDir(), "archive")
+	restore, err := Archive(paths, archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left, _ := Files(dir); len(left) != 0 {
+		t.Errorf("files left after Archive: %v", left)
+	}
+	if archived, _ := Files(archive); len(archived) != 3 {
+		t.Errorf("archived %v, want 3 files", archived)
+	}
+
+	if err := restore(); err != nil {
+		t.Fatal(err)
+	}
+	if back, _ := Files(dir); !reflect.DeepEqual(back, want) {
+		t.Errorf("Files() after restore = %v, want %v", back, want)
+	}
+}
+
+func TestArchiveRefusesToOverwrite(t *testing.T) {
+	dir, archive := t.TempDir(), t.TempDir()
+	writeFiles(t, dir, "000001_init.up.sql", "000002_wallets.up.sql")
+	writeFiles(t, archive, "000002_wallets.up.sql")
+
+	paths, _ := Files(dir)
+	if _, err := Archive(paths, archive); err == nil {
+		t.Fatal("Archive() overwrote an archived file")
+	}
+	if left, _ := Files(dir); len(left) != 2 {
+		t.Errorf("Archive() moved files before failing: %v left", left)
+	}
+}
+
+func TestWriteBaseline(t *testing.T) {
+	up := filepath.Join(t.TempDir(), "000001_baseline.up.sql")
+	if err := WriteBaseline(up, []byte("CREATE TABLE wallets ();\n")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(up); string(data) != "CREATE TABLE wallets ();\n"+resetSearchPath {
+		t.Errorf("up file = %q", data)
+	}
+	if data, _ := os.ReadFile(DownPath(up)); string(data) != DownSQL {
+		t.Errorf("down file = %q, want %q", data, DownSQL)
+	}
+
+	if err := WriteBaseline(up, nil); err == nil {
+		t.Error("WriteBaseline() overwrote an existing baseline")
+	}
+}
@@ -0,0 +1,38 @@
+//go:build aws
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/secrets"
+)
+
+// ssmTimeout bounds loading AWS credentials and reading the parameter.
+const ssmTimeout = 10 * time.Second
+
+// resolveSSMParameter sets DATABASE_URL from the Parameter Store parameter
+// AWS_PARAMETER_NAME when it is set, in AWS_REGION. As with Vault, an
+// explicit DATABASE_URL wins.
+func resolveSSMParameter() error {
+	name := getenv("AWS_PARAMETER_NAME")
+	if name == "" || getenv("DATABASE_URL") != "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ssmTimeout)
+	defer cancel()
+
+	client, err := secrets.NewSSMClient(ctx, getenv("AWS_REGION"), getenv)
+	if err != nil {
+		return fmt.Errorf("load aws configuration: %w", err)
+	}
+	url, err := client.Parameter(ctx, name)
+	if err != nil {
+		return fmt.Errorf("read DATABASE_URL from parameter store: %w", err)
+	}
+	environment.Set("DATABASE_URL", url)
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !aws
+
+package main
+
+import (
+	"errors"
+)
+
+// resolveSSMParameter refuses AWS_PARAMETER_NAME in builds without the aws
+// tag, which leave out the AWS SDK, rather than silently ignoring it.
+func resolveSSMParameter() error {
+	if getenv("AWS_PARAMETER_NAME") == "" || getenv("DATABASE_URL") != "" {
+		return nil
+	}
+	return errors.New("AWS_PARAMETER_NAME is set but this binary was built without AWS support (build with -tags aws)")
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/exitcodes"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/runner"
+)
+
+// StatusFormatter renders parsed migration statuses in a specific format.
+type StatusFormatter interface {
+	Format(w io.Writer, statuses []runner.MigrationRecord) error
+}
+
+type jsonStatusFormatter struct{}
+
+func (jsonStatusFormatter) Format(w io.Writer, statuses []runner.MigrationRecord) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(statuses)
+}
+
+// tableStatusFormatter renders statuses as an aligned table, with a
+// DURATION column when any migration's execution time is known.
+type tableStatusFormatter struct{}
+
+func (tableStatusFormatter) Format(w io.Writer, statuses []runner.MigrationRecord) error {
+	timed := false
+	for _, status := range statuses {
+		timed = timed || status.Duration > 0
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if timed {
+		fmt.Fprintln(tw, "MIGRATION\tSTATUS\tDURATION")
+	} else {
+		fmt.Fprintln(tw, "MIGRATION\tSTATUS")
+	}
+	for _, status := range statuses {
+		state := "Pending"
+		if status.Applied {
+			state = "Applied"
+		}
+		if !timed {
+			fmt.Fprintf(tw, "%s\t%s\n", status.Name, state)
+			continue
+		}
+		duration := "-"
+		if status.Duration > 0 {
+			duration = status.Duration.String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", status.Name, state, duration)
+	}
+	return tw.Flush()
+}
+
+// statusFormatters lists the formats accepted by `status --format`. The
+// default text format is not listed because it passes Cargo output through
+// untouched.
+var statusFormatters = map[string]StatusFormatter{
+	"json":  jsonStatusFormatter{},
+	"table": tableStatusFormatter{},
+}
+
+// runStatusFormatted runs the status command with Cargo's stdout captured and
+// re-renders it in the requested format, along with any execution times the
+// binary reported. Diagnostics go to stderr so that
+// stdout only carries the formatted report.
+func runStatusFormatted(cfg Config) int {
+	ctx, cancel := cfg.commandContext()
+	defer cancel()
+
+	stderr := newLogWriter(slog.LevelError, "stderr")
+	migrator, err := newMigrationRunner(cfg, nil, stderr, runCommand)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	statuses, err := migrator.Status(ctx)
+	stderr.Flush()
+	if err != nil {
+		printer.Error("Migration failed: %v", err)
+		return commandExitCode(err)
+	}
+	if err := fillAppliedAt(ctx, cfg, statuses); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	if err := statusFormatters[cfg.Format].Format(os.Stdout, statuses); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	return 0
+}
+
+// fillAppliedAt sets the AppliedAt of each applied migration in statuses to
+// the time the tracking table records for it, which the binary's status
+// output leaves out.
+func fillAppliedAt(ctx context.Context, cfg Config, statuses []runner.MigrationRecord) error {
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("connect to database: %w", err)
+	}
+	defer conn.Close()
+
+	times, err := db.NewPostgresRepository(conn).AppliedTimes(ctx)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", db.MigrationsTable(), err)
+	}
+	for i := range statuses {
+		if at, ok := times[statuses[i].Name]; ok && statuses[i].Applied {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return nil
+}
+
+// runPendingCount prints the number of pending migrations, read from the
+// database and the migration files without Cargo, and fails if it is not
+// zero, so that `migrate status --pending-count` can gate a deploy, e.g. in
+// an init container.
+func runPendingCount(cfg Config) int {
+	_, state, err := migrationState(cfg)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	pending := countPending(state)
+	fmt.Println(pending)
+	if pending > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runStatusExitCode reports the state of the database, read without Cargo,
+// through its exit code: exitcodes.ExitAllApplied, ExitPending or, for a
+// database no migration has run against, ExitTableMissing. ExitError keeps
+// a failure to read the state apart from pending migrations.
+func runStatusExitCode(cfg Config) int {
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return exitcodes.ExitError
+	}
+	exists, err := db.NewPostgresRepository(conn).HasTrackingTable(ctx)
+	conn.Close()
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return exitcodes.ExitError
+	}
+	if !exists {
+		printer.Warn("The migration tracking table %s does not exist yet", db.MigrationsTable())
+		return exitcodes.ExitTableMissing
+	}
+
+	_, state, err := migrationState(cfg)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return exitcodes.ExitError
+	}
+	if pending := countPending(state); pending > 0 {
+		printer.Warn("%d of %d migrations pending", pending, len(state))
+		return exitcodes.ExitPending
+	}
+	printer.Success("All %d migrations applied", len(state))
+	return exitcodes.ExitAllApplied
+}
+
+// runStatusSince lists the migrations applied after --since, read from the
+// database without Cargo, which has no such filter. An empty window gets a
+// warning, since it is often a time zone mistake.
+func runStatusSince(cfg Config) int {
+	_, state, err := migrationState(cfg)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	var recent []db.Migration
+	for _, m := range state {
+		if m.Applied && m.AppliedAt != nil && m.AppliedAt.After(cfg.AppliedSince) {
+			recent = append(recent, m)
+		}
+	}
+	if len(recent) == 0 {
+		printer.Warn("Warning: no migrations were applied since %s (%s local time); check the time zone if you expected some",
+			cfg.AppliedSince.UTC().Format(time.RFC3339), cfg.AppliedSince.Local().Format("2006-01-02 15:04:05"))
+		return 0
+	}
+	writeMigrationTable(os.Stdout, recent, nil)
+	return 0
+}
+
+// parseSince parses the value of status --since: a duration such as 24h or
+// 1h30m before now, or a time in one of the --to-date formats.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, errors.New("must not be negative")
+		}
+		return now.Add(-d), nil
+	}
+	t, err := parseRollbackDate(value)
+	if err != nil {
+		return time.Time{}, errors.New("expected a duration such as 24h or a time such as 2024-01-15T14:00:00Z")
+	}
+	return t, nil
+}
+
+// reportRemaining prints how many migrations are still pending after up
+// --max, read from the database without Cargo, so that a staged rollout
+// knows whether another step is due. Failing to read it is only a warning,
+// since the migration itself succeeded.
+func reportRemaining(cfg Config) {
+	_, state, err := migrationState(cfg)
+	if err != nil {
+		printer.Warn("Warning: could not count the remaining migrations: %v", err)
+		return
+	}
+	printer.Info("%d migrations remaining", countPending(state))
+}
+
+// countPending returns the number of migrations in state not yet applied.
+func countPending(state []db.Migration) int {
+	pending := 0
+	for _, m := range state {
+		if !m.Applied {
+			pending++
+		}
+	}
+	return pending
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"golang.org/x/term"
+)
+
+// watchStatementTimeout is the statement_timeout of status --watch's
+// session, shorter than the default interval so that a slow database
+// cannot freeze the display.
+const watchStatementTimeout = 4 * time.Second
+
+// runStatusWatch redraws the status table every --interval, read directly
+// from the tracking table, until interrupted. On a terminal each table
+// replaces the previous one in place; otherwise the tables are appended.
+// A failed refresh keeps the last table and shows the error under it.
+func runStatusWatch(cfg Config) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	connectCtx, cancel := context.WithTimeout(ctx, listTimeout)
+	conn, err := pg.OpenWithStatementTimeout(connectCtx, cfg.DatabaseURL, watchStatementTimeout)
+	cancel()
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0
+		}
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	inPlace := term.IsTerminal(int(os.Stdout.Fd()))
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	var table []byte
+	drawn := 0
+	for {
+		state, err := watchedState(ctx, cfg, conn)
+		if ctx.Err() != nil {
+			return 0
+		}
+
+		var frame bytes.Buffer
+		if err == nil {
+			var t bytes.Buffer
+			writeMigrationTable(&t, state, nil)
+			table = t.Bytes()
+		}
+		frame.Write(table)
+		fmt.Fprintf(&frame, "\nUpdated %s, every %s (Ctrl-C to stop)\n", time.Now().Format("15:04:05"), cfg.Interval)
+		if err != nil {
+			fmt.Fprintf(&frame, "Error: %v\n", err)
+		}
+
+		if inPlace && drawn > 0 {
+			// Move to the start of the previous frame and clear from there.
+			fmt.Fprintf(os.Stdout, "\x1b[%dF\x1b[J", drawn)
+		} else if drawn > 0 {
+			fmt.Fprintln(os.Stdout)
+		}
+		os.Stdout.Write(frame.Bytes())
+		drawn = strings.Count(frame.String(), "\n")
+
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchedState reads the state of the migration files, scanned afresh so
+// that new files show up, from conn.
+func watchedState(ctx context.Context, cfg Config, conn *sql.DB) ([]db.Migration, error) {
+	files, err := migrations.Scan(cfg.SQLDir())
+	if err != nil {
+		return nil, err
+	}
+	state := make([]db.Migration, len(files))
+	for i, file := range files {
+		state[i] = db.Migration{Version: int64(file.Sequence), Name: file.Name}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, watchStatementTimeout+time.Second)
+	defer cancel()
+	state, err = db.NewPostgresRepository(conn).Status(ctx, state)
+	if err != nil {
+		return nil, fmt.Errorf("read migration state: %w", err)
+	}
+	return state, nil
+}
@@ -0,0 +1,260 @@
+//go:build aws
+
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty request body, sent with every
+// GET as S3 requires.
+var emptyPayloadHash = func() string {
+	sum := sha256.Sum256(nil)
+	return hex.EncodeToString(sum[:])
+}()
+
+// S3MigrationSource is a migration directory kept under Prefix in Bucket.
+// It speaks the two S3 REST calls it needs, ListObjectsV2 and GetObject,
+// signed with the SDK's SigV4 signer and its credential chain, rather than
+// pulling in the whole S3 service client for them.
+type S3MigrationSource struct {
+	Bucket string
+	Prefix string
+
+	// Cache, if not nil, keeps the downloaded objects by ETag.
+	Cache *ETagCache
+
+	cfg      aws.Config
+	endpoint *url.URL
+	signer   *v4.Signer
+	client   aws.HTTPClient
+}
+
+// NewS3MigrationSource returns a source for uri, s3://bucket/prefix, that
+// authenticates with cfg, as loaded by secrets.LoadAWSConfig. A non-empty
+// endpoint is the base URL of an S3-compatible service such as MinIO,
+// addressed with path-style URLs; otherwise the regional AWS endpoint is
+// used.
+func NewS3MigrationSource(uri, endpoint string, cfg aws.Config, cache *ETagCache) (*S3MigrationSource, error) {
+	bucket, prefix, err := ParseS3URI(uri)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("no AWS region for %s; set AWS_REGION", uri)
+	}
+	src := &S3MigrationSource{
+		Bucket: bucket,
+		Prefix: prefix,
+		Cache:  cache,
+		cfg:    cfg,
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			// S3 signs the path as sent, not escaped a second time.
+			o.DisableURIPathEscaping = true
+		}),
+		client: cfg.HTTPClient,
+	}
+	if src.client == nil {
+		src.client = http.DefaultClient
+	}
+	if endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+		}
+		src.endpoint = u
+	}
+	return src, nil
+}
+
+// Download writes every object under the prefix into destDir, taking those
+// whose ETag is cached from the cache.
+func (s *S3MigrationSource) Download(ctx context.Context, destDir string) error {
+	objects, err := s.list(ctx)
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		return fmt.Errorf("no objects under s3://%s/%s", s.Bucket, s.Prefix)
+	}
+
+	for _, obj := range objects {
+		path, err := localPath(destDir, s.Prefix, obj.Key)
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			continue
+		}
+		if err := s.download(ctx, obj, path); err != nil {
+			return fmt.Errorf("download s3://%s/%s: %w", s.Bucket, obj.Key, err)
+		}
+	}
+	return nil
+}
+
+func (s *S3MigrationSource) download(ctx context.Context, obj s3Object, path string) error {
+	if s.Cache == nil || obj.ETag == "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := s.get(ctx, obj.Key, f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	cached, ok := s.Cache.Lookup(obj.ETag)
+	if !ok {
+		var err error
+		cached, err = s.Cache.Store(obj.ETag, func(w io.Writer) error {
+			return s.get(ctx, obj.Key, w)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return copyFile(path, cached)
+}
+
+type s3Object struct {
+	Key  string `xml:"Key"`
+	ETag string `xml:"ETag"`
+}
+
+type listBucketResult struct {
+	Contents              []s3Object `xml:"Contents"`
+	IsTruncated           bool       `xml:"IsTruncated"`
+	NextContinuationToken string     `xml:"NextContinuationToken"`
+}
+
+// list returns every object under the prefix, following continuation
+// tokens.
+func (s *S3MigrationSource) list(ctx context.Context) ([]s3Object, error) {
+	var objects []s3Object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, "", query)
+		if err != nil {
+			return nil, fmt.Errorf("list s3://%s/%s: %w", s.Bucket, s.Prefix, err)
+		}
+		var page listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("list s3://%s/%s: %w", s.Bucket, s.Prefix, err)
+		}
+		objects = append(objects, page.Contents...)
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// get writes the content of the object key to w.
+func (s *S3MigrationSource) get(ctx context.Context, key string, w io.Writer) error {
+	resp, err := s.do(ctx, key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// do sends a signed GET for key, or for the bucket when key is empty, and
+// returns the response if it succeeded.
+func (s *S3MigrationSource) do(ctx context.Context, key string, query url.Values) (*http.Response, error) {
+	u := s.objectURL(key)
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+
+	creds, err := s.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load aws credentials: %w", err)
+	}
+	if err := s.signer.SignHTTP(ctx, creds, req, emptyPayloadHash, "s3", s.cfg.Region, time.Now()); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// objectURL returns the URL of key in the bucket: virtual-hosted on AWS,
+// path-style on a custom endpoint.
+func (s *S3MigrationSource) objectURL(key string) *url.URL {
+	path, rawPath := "/"+key, "/"+escapeKey(key)
+
+	if s.endpoint != nil {
+		base := strings.TrimSuffix(s.endpoint.Path, "/") + "/" + s.Bucket
+		return &url.URL{Scheme: s.endpoint.Scheme, Host: s.endpoint.Host, Path: base + path, RawPath: base + rawPath}
+	}
+	host := fmt.Sprintf("%s.s3.%s.amazonaws.com", s.Bucket, s.cfg.Region)
+	return &url.URL{Scheme: "https", Host: host, Path: path, RawPath: rawPath}
+}
+
+// escapeKey percent-encodes key as SigV4 expects for S3: every byte but
+// the unreserved characters and the slashes between segments.
+func escapeKey(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3Error is the body of a failed S3 request.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func responseError(resp *http.Response) error {
+	var e s3Error
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(body, &e) == nil && e.Code != "" {
+		return fmt.Errorf("%s: %s (HTTP %d)", e.Code, e.Message, resp.StatusCode)
+	}
+	return fmt.Errorf("HTTP %s", resp.Status)
+}
@@ -0,0 +1,151 @@
+//go:build aws
+
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// fakeS3 serves objects from a map, two keys per ListObjectsV2 page, and
+// counts the GetObject requests.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]string
+	keys    []string
+	gets    int
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "<Error><Code>AccessDenied</Code><Message>unsigned</Message></Error>")
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/bucket/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if key != "" {
+		f.mu.Lock()
+		f.gets++
+		f.mu.Unlock()
+		body, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<Error><Code>NoSuchKey</Code><Message>missing</Message></Error>")
+			return
+		}
+		fmt.Fprint(w, body)
+		return
+	}
+
+	start := 0
+	if token := r.URL.Query().Get("continuation-token"); token != "" {
+		fmt.Sscan(token, &start)
+	}
+	end := min(start+2, len(f.keys))
+	fmt.Fprint(w, "<ListBucketResult>")
+	for _, k := range f.keys[start:end] {
+		if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+			fmt.Fprintf(w, "<Contents><Key>%s</Key><ETag>&quot;%x&quot;</ETag></Contents>", k, md5.Sum([]byte(f.objects[k])))
+		}
+	}
+	if end < len(f.keys) {
+		fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>", end)
+	}
+	fmt.Fprint(w, "</ListBucketResult>")
+}
+
+func TestS3MigrationSourceDownload(t *testing.T) {
+	fake := &fakeS3{objects: map[string]string{
+		"migrations/Cargo.toml": "[package]",
+		"migrations/":           "",
+		"migrations/migrations/000001_init.up.sql":   "CREATE TABLE t ();",
+		"migrations/migrations/000001_init.down.sql": "DROP TABLE t;",
+		"migrations/src/lib.rs":                      "pub struct Migrator;",
+		"other/README":                               "not a migration",
+	}}
+	for k := range fake.objects {
+		fake.keys = append(fake.keys, k)
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:  server.Client(),
+	}
+	cache := &ETagCache{Dir: t.TempDir()}
+	src, err := NewS3MigrationSource("s3://bucket/migrations", server.URL, cfg, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest := t.TempDir()
+	if err := src.Download(context.Background(), dest); err != nil {
+		t.Fatal(err)
+	}
+	for rel, want := range map[string]string{
+		"Cargo.toml": "[package]",
+		filepath.Join("migrations", "000001_init.up.sql"):   "CREATE TABLE t ();",
+		filepath.Join("migrations", "000001_init.down.sql"): "DROP TABLE t;",
+		filepath.Join("src", "lib.rs"):                      "pub struct Migrator;",
+	} {
+		if got, err := os.ReadFile(filepath.Join(dest, rel)); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", rel, got, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dest, "README")); err == nil {
+		t.Error("an object outside the prefix was downloaded")
+	}
+	if fake.gets != 4 {
+		t.Errorf("first download made %d GetObject requests, want 4", fake.gets)
+	}
+
+	// The second download takes every object from the cache.
+	if err := src.Download(context.Background(), t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if fake.gets != 4 {
+		t.Errorf("cached download made %d more GetObject requests", fake.gets-4)
+	}
+}
+
+func TestS3MigrationSourceReportsS3Errors(t *testing.T) {
+	server := httptest.NewServer(&fakeS3{})
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("WRONG", "SECRET", ""),
+		HTTPClient:  server.Client(),
+	}
+	src, err := NewS3MigrationSource("s3://bucket/migrations", server.URL, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = src.Download(context.Background(), t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Download = %v, want an AccessDenied error", err)
+	}
+}
+
+func TestEscapeKey(t *testing.T) {
+	if got, want := escapeKey("migrations/000001_a b+c=d.sql"), "migrations/000001_a%20b%2Bc%3Dd.sql"; got != want {
+		t.Errorf("escapeKey = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+// Package storage fetches the migration crate from where it is kept, the
+// local filesystem or an S3 prefix, into a directory Cargo can build.
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MigrationSource is a copy of the migration directory that can be
+// downloaded.
+type MigrationSource interface {
+	// Download writes the migration directory's files into destDir, which
+	// exists, keeping their relative paths.
+	Download(ctx context.Context, destDir string) error
+}
+
+// LocalMigrationSource is a migration directory on the local filesystem.
+type LocalMigrationSource struct {
+	Dir string
+}
+
+// Download copies every file under s.Dir into destDir.
+func (s LocalMigrationSource) Download(ctx context.Context, destDir string) error {
+	return filepath.WalkDir(s.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.Dir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destDir, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		return copyFile(target, path)
+	})
+}
+
+// ParseS3URI splits an s3://bucket/prefix URI. The prefix, when not empty,
+// is returned with a trailing slash, so that it only matches whole
+// directory names.
+func ParseS3URI(uri string) (bucket, prefix string, err error) {
+	rest, ok := strings.CutPrefix(uri, "s3://")
+	if !ok {
+		return "", "", fmt.Errorf("invalid S3 location %q: want s3://bucket/prefix", uri)
+	}
+	bucket, prefix, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("invalid S3 location %q: no bucket", uri)
+	}
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return bucket, prefix, nil
+}
+
+// localPath returns where the object key, under prefix, goes in destDir,
+// failing for keys that would land outside it. Keys naming a directory
+// return "".
+func localPath(destDir, prefix, key string) (string, error) {
+	rel := strings.TrimPrefix(key, prefix)
+	if rel == "" || strings.HasSuffix(rel, "/") {
+		return "", nil
+	}
+	if !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return "", fmt.Errorf("object %s is outside the migration directory", key)
+	}
+	return filepath.Join(destDir, filepath.FromSlash(rel)), nil
+}
+
+// ETagCache keeps downloaded objects in Dir by their ETag, which changes
+// whenever an object's content does, so that unchanged migrations are not
+// downloaded again.
+type ETagCache struct {
+	Dir string
+}
+
+// DefaultCacheDir is the cache directory used unless another is given: a
+// directory of the user's cache directory, e.g. ~/.cache on Linux.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "crypto-bot-migrate", "s3"), nil
+}
+
+// plainETag matches the ETags S3 returns, hex digests with an optional
+// -parts suffix, which can be used as file names as they are.
+var plainETag = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+func (c *ETagCache) path(etag string) string {
+	etag = strings.Trim(etag, `"`)
+	if !plainETag.MatchString(etag) {
+		sum := sha256.Sum256([]byte(etag))
+		etag = hex.EncodeToString(sum[:])
+	}
+	return filepath.Join(c.Dir, etag)
+}
+
+// Lookup returns the cached file for etag, if there is one.
+func (c *ETagCache) Lookup(etag string) (string, bool) {
+	path := c.path(etag)
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	return path, true
+}
+
+// Store caches what fill writes as the file for etag and returns its path.
+// The file only appears once fill has succeeded, so an interrupted
+// download is not mistaken for a cached one.
+func (c *ETagCache) Store(etag string, fill func(w io.Writer) error) (string, error) {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(c.Dir, ".download-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = fill(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	path := c.path(etag)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// copyFile copies src to dst, creating dst's directory.
+func copyFile(dst, src string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	return errors.Join(err, out.Close())
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseS3URI(t *testing.T) {
+	for _, tt := range []struct {
+		uri, bucket, prefix string
+	}{
+		{"s3://mybucket/migrations", "mybucket", "migrations/"},
+		{"s3://mybucket/a/b/", "mybucket", "a/b/"},
+		{"s3://mybucket", "mybucket", ""},
+	} {
+		bucket, prefix, err := ParseS3URI(tt.uri)
+		if err != nil || bucket != tt.bucket || prefix != tt.prefix {
+			t.Errorf("ParseS3URI(%q) = %q, %q, %v; want %q, %q", tt.uri, bucket, prefix, err, tt.bucket, tt.prefix)
+		}
+	}
+	for _, bad := range []string{"mybucket/migrations", "s3:///migrations", "https://mybucket/x"} {
+		if _, _, err := ParseS3URI(bad); err == nil {
+			t.Errorf("ParseS3URI(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	dest := t.TempDir()
+	got, err := localPath(dest, "migrations/", "migrations/src/lib.rs")
+	if want := filepath.Join(dest, "src", "lib.rs"); err != nil || got != want {
+		t.Errorf("localPath = %q, %v; want %q", got, err, want)
+	}
+	if got, err := localPath(dest, "migrations/", "migrations/src/"); err != nil || got != "" {
+		t.Errorf("localPath of a directory key = %q, %v; want skipped", got, err)
+	}
+	if _, err := localPath(dest, "migrations/", "migrations/../../etc/passwd"); err == nil {
+		t.Error("localPath accepted a key escaping the destination")
+	}
+}
+
+func TestETagCache(t *testing.T) {
+	cache := &ETagCache{Dir: filepath.Join(t.TempDir(), "cache")}
+	if _, ok := cache.Lookup(`"abc123"`); ok {
+		t.Fatal("Lookup found an entry in an empty cache")
+	}
+
+	path, err := cache.Store(`"abc123"`, func(w io.Writer) error {
+		_, err := io.WriteString(w, "CREATE TABLE t ();")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := cache.Lookup("abc123")
+	if !ok || got != path {
+		t.Fatalf("Lookup = %q, %v; want %q", got, ok, path)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "CREATE TABLE t ();" {
+		t.Errorf("cached %q", data)
+	}
+
+	if _, err := cache.Store("failed", func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return io.ErrUnexpectedEOF
+	}); err == nil {
+		t.Fatal("Store succeeded although fill failed")
+	}
+	if _, ok := cache.Lookup("failed"); ok {
+		t.Error("a failed download was cached")
+	}
+}
+
+func TestLocalMigrationSourceDownload(t *testing.T) {
+	src := t.TempDir()
+	for path, content := range map[string]string{
+		"Cargo.toml":                      "[package]",
+		"migrations/000001_init.up.sql":   "CREATE TABLE t ();",
+		"migrations/000001_init.down.sql": "DROP TABLE t;",
+		filepath.Join("src", "lib.rs"):    "pub struct Migrator;",
+	} {
+		full := filepath.Join(src, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dest := t.TempDir()
+	if err := (LocalMigrationSource{Dir: src}).Download(context.Background(), dest); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "migrations", "000001_init.down.sql"))
+	if err != nil || !strings.Contains(string(data), "DROP TABLE") {
+		t.Errorf("down file = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"github.com/crypto-bot/tools/migrate/tags"
+)
+
+// runTag labels the migration named by --at with the tag given as the
+// argument, so that rollback --to-tag and status --since-tag can refer to
+// it.
+func runTag(cfg Config) int {
+	var namer migrations.MigrationNamer
+
+	file, err := findMigration(cfg, cfg.At)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	name := namer.Base(file.Sequence, file.Name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		printer.Error("Error: connect to database: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	if err := tags.NewPostgresTagger(conn).Tag(ctx, cfg.Args[0], name); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	printer.Success("Tagged %s as %s", name, cfg.Args[0])
+	return 0
+}
+
+// taggedVersion returns the version of the migration tag labels.
+func taggedVersion(cfg Config, tag string) (int64, error) {
+	var namer migrations.MigrationNamer
+
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return 0, fmt.Errorf("connect to database: %w", err)
+	}
+	defer conn.Close()
+
+	t, err := tags.NewPostgresTagger(conn).Lookup(ctx, tag)
+	if err != nil {
+		return 0, err
+	}
+	// The migration's file may have been archived since, so its version is
+	// read from the recorded name.
+	sequence, _, ok := namer.Parse(t.Migration + ".sql")
+	if !ok {
+		return 0, fmt.Errorf("tag %s marks %q, which is not a migration name", tag, t.Migration)
+	}
+	return int64(sequence), nil
+}
+
+// runStatusSinceTag lists the migrations after the one --since-tag labels,
+// with their status read from the database, so that the changes since a
+// release can be reviewed without Cargo.
+func runStatusSinceTag(cfg Config) int {
+	version, err := taggedVersion(cfg, cfg.SinceTag)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	_, state, err := migrationState(cfg)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	var since []db.Migration
+	for _, m := range state {
+		if m.Version > version {
+			since = append(since, m)
+		}
+	}
+	if len(since) == 0 {
+		printer.Success("No migrations after %s", cfg.SinceTag)
+		return 0
+	}
+	writeMigrationTable(os.Stdout, since, nil)
+	return 0
+}
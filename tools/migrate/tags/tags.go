@@ -0,0 +1,146 @@
+// Package tags names migrations with labels such as release versions, kept
+// in a _migrate_tags table beside the migration tracking table.
+package tags
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// createTable creates the tag table on first use.
+const createTable = `CREATE TABLE IF NOT EXISTS _migrate_tags (
+    tag TEXT PRIMARY KEY,
+    migration_name TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+)`
+
+// ErrNotFound is returned for a tag that has not been recorded.
+var ErrNotFound = errors.New("tag not found")
+
+// Tag labels a migration, named by its file name stem such as
+// 000120_create_wallets.
+type Tag struct {
+	Name      string
+	Migration string
+	CreatedAt time.Time
+}
+
+// Tagger records tags.
+type Tagger interface {
+	// Tag labels migration with tag. A tag names one migration for good,
+	// so recording an existing tag again fails.
+	Tag(ctx context.Context, tag, migration string) error
+}
+
+// TagReader reads recorded tags.
+type TagReader interface {
+	// Lookup returns the tag called name, or ErrNotFound.
+	Lookup(ctx context.Context, name string) (Tag, error)
+	// List returns every tag, oldest first.
+	List(ctx context.Context) ([]Tag, error)
+}
+
+// Validate reports whether name can be used as a tag: it must be non-empty
+// and contain no whitespace, so that it can be typed on a command line.
+func Validate(name string) error {
+	if name == "" || strings.ContainsAny(name, " \t\r\n") {
+		return fmt.Errorf("invalid tag %q (expected a name without spaces, e.g. v2.5.0)", name)
+	}
+	return nil
+}
+
+// PostgresTagger keeps tags in the _migrate_tags table, which it creates
+// when the first tag is recorded.
+type PostgresTagger struct {
+	db *sql.DB
+}
+
+// NewPostgresTagger returns a Tagger and TagReader backed by db.
+func NewPostgresTagger(db *sql.DB) *PostgresTagger {
+	return &PostgresTagger{db: db}
+}
+
+func (t *PostgresTagger) Tag(ctx context.Context, tag, migration string) error {
+	if err := Validate(tag); err != nil {
+		return err
+	}
+	if _, err := t.db.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("create _migrate_tags: %w", err)
+	}
+
+	result, err := t.db.ExecContext(ctx,
+		"INSERT INTO _migrate_tags (tag, migration_name) VALUES ($1, $2) ON CONFLICT (tag) DO NOTHING", tag, migration)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	existing, err := t.Lookup(ctx, tag)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("tag %s already marks %s", tag, existing.Migration)
+}
+
+func (t *PostgresTagger) Lookup(ctx context.Context, name string) (Tag, error) {
+	exists, err := t.tableExists(ctx)
+	if err != nil {
+		return Tag{}, err
+	}
+	notFound := fmt.Errorf("%w: %s", ErrNotFound, name)
+	if !exists {
+		return Tag{}, notFound
+	}
+
+	tag := Tag{Name: name}
+	err = t.db.QueryRowContext(ctx, "SELECT migration_name, created_at FROM _migrate_tags WHERE tag = $1", name).
+		Scan(&tag.Migration, &tag.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Tag{}, notFound
+	}
+	return tag, err
+}
+
+func (t *PostgresTagger) List(ctx context.Context) ([]Tag, error) {
+	exists, err := t.tableExists(ctx)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := t.db.QueryContext(ctx, "SELECT tag, migration_name, created_at FROM _migrate_tags ORDER BY created_at, tag")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Tag
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Name, &tag.Migration, &tag.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, tag)
+	}
+	return list, rows.Err()
+}
+
+func (t *PostgresTagger) tableExists(ctx context.Context) (bool, error) {
+	var exists bool
+	err := t.db.QueryRowContext(ctx, "SELECT to_regclass('_migrate_tags') IS NOT NULL").Scan(&exists)
+	return exists, err
+}
+
+// ByMigration groups tags by the migration they label.
+func ByMigration(list []Tag) map[string][]string {
+	byMigration := make(map[string][]string)
+	for _, tag := range list {
+		byMigration[tag.Migration] = append(byMigration[tag.Migration], tag.Name)
+	}
+	return byMigration
+}
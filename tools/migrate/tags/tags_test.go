@@ -0,0 +1,33 @@
+package tags
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, name := range []string{"v2.5.0", "release-2024-01", "before_wallets"} {
+		if err := Validate(name); err != nil {
+			t.Errorf("Validate(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "v2 5", "v2\t5", "v2\n"} {
+		if err := Validate(name); err == nil {
+			t.Errorf("Validate(%q) succeeded", name)
+		}
+	}
+}
+
+func TestByMigration(t *testing.T) {
+	got := ByMigration([]Tag{
+		{Name: "v2.4.0", Migration: "000100_add_orders"},
+		{Name: "v2.5.0", Migration: "000120_create_wallets"},
+		{Name: "stable", Migration: "000120_create_wallets"},
+	})
+	if want := []string{"v2.5.0", "stable"}; !slices.Equal(got["000120_create_wallets"], want) {
+		t.Errorf("tags of 000120_create_wallets = %q, want %q", got["000120_create_wallets"], want)
+	}
+	if len(got) != 2 {
+		t.Errorf("got tags for %d migrations, want 2", len(got))
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// findTarget returns the migration named by --target.
+func findTarget(cfg Config) (migrations.File, error) {
+	file, err := findMigration(cfg, cfg.Target)
+	if err != nil {
+		return migrations.File{}, fmt.Errorf("target %w", err)
+	}
+	return file, nil
+}
+
+// findMigration returns the migration called target, which may be given as
+// the file name stem (000042_add_orders_table) or as either file name. The
+// error lists the migrations that do exist.
+func findMigration(cfg Config, target string) (migrations.File, error) {
+	var namer migrations.MigrationNamer
+
+	files, err := migrations.Scan(cfg.SQLDir())
+	if err != nil {
+		return migrations.File{}, err
+	}
+
+	name := target
+	if !strings.HasSuffix(name, ".sql") {
+		name += ".sql"
+	}
+	if sequence, stem, ok := namer.Parse(name); ok {
+		for _, file := range files {
+			if file.Sequence == sequence && file.Name == stem {
+				return file, nil
+			}
+		}
+	}
+
+	available := make([]string, len(files))
+	for i, file := range files {
+		available[i] = "  " + namer.Base(file.Sequence, file.Name)
+	}
+	if len(available) == 0 {
+		return migrations.File{}, fmt.Errorf("migration %q not found: %s contains no migrations", target, cfg.SQLDir())
+	}
+	return migrations.File{}, fmt.Errorf("migration %q not found in %s; available migrations:\n%s",
+		target, cfg.SQLDir(), strings.Join(available, "\n"))
+}
+
+// checkTarget reports whether --target, if given, names an existing
+// migration, printing the available ones if it does not.
+func checkTarget(cfg Config) bool {
+	if cfg.Target == "" {
+		return true
+	}
+	if _, err := findTarget(cfg); err != nil {
+		printer.Error("Error: %v", err)
+		return false
+	}
+	return true
+}
+
+// targetSteps works out how many migrations reach --target: for up, the
+// pending migrations up to and including it; for down, the applied
+// migrations after it, so that it ends up the latest applied one. The
+// migrator only understands a number of steps, so --target is passed on as
+// --num.
+func targetSteps(cfg Config) (int, error) {
+	target, err := findTarget(cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	_, state, err := migrationState(cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	version := int64(target.Sequence)
+	steps := 0
+	for _, m := range state {
+		switch {
+		case cfg.Command == "up" && !m.Applied && m.Version <= version:
+			steps++
+		case cfg.Command == "down" && m.Version == version && !m.Applied:
+			return 0, fmt.Errorf("target migration %s is not applied", cfg.Target)
+		case cfg.Command == "down" && m.Applied && m.Version > version:
+			steps++
+		}
+	}
+	return steps, nil
+}
+
+// migrationState returns the migrations on disk, in order, alongside their
+// state in the database.
+func migrationState(cfg Config) ([]migrations.File, []db.Migration, error) {
+	files, err := migrations.Scan(cfg.SQLDir())
+	if err != nil {
+		return nil, nil, err
+	}
+	state := make([]db.Migration, len(files))
+	for i, file := range files {
+		state[i] = db.Migration{Version: int64(file.Sequence), Name: file.Name}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect to database: %w", err)
+	}
+	defer conn.Close()
+
+	state, err = db.NewPostgresRepository(conn).Status(ctx, state)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read migration state: %w", err)
+	}
+	return files, state, nil
+}
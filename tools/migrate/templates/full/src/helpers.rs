@@ -0,0 +1,53 @@
+//! Building blocks shared by migrations.
+
+use sea_orm_migration::prelude::*;
+use sea_orm_migration::sea_orm::ConnectionTrait;
+
+/// An auto-incrementing `BIGINT` primary key named `id`.
+pub fn id_column() -> ColumnDef {
+    ColumnDef::new(Alias::new("id"))
+        .big_integer()
+        .not_null()
+        .auto_increment()
+        .primary_key()
+        .to_owned()
+}
+
+/// Adds `created_at` and `updated_at` columns that default to the current
+/// time.
+pub fn timestamps(table: &mut TableCreateStatement) -> &mut TableCreateStatement {
+    table
+        .col(
+            ColumnDef::new(Alias::new("created_at"))
+                .timestamp_with_time_zone()
+                .not_null()
+                .default(Expr::current_timestamp()),
+        )
+        .col(
+            ColumnDef::new(Alias::new("updated_at"))
+                .timestamp_with_time_zone()
+                .not_null()
+                .default(Expr::current_timestamp()),
+        )
+}
+
+/// A foreign key from `from_table.from_column` to `to_table.id` that
+/// deletes the row when the referenced row is deleted.
+pub fn cascade_foreign_key(
+    from_table: &str,
+    from_column: &str,
+    to_table: &str,
+) -> ForeignKeyCreateStatement {
+    ForeignKey::create()
+        .name(format!("fk_{from_table}_{from_column}"))
+        .from(Alias::new(from_table), Alias::new(from_column))
+        .to(Alias::new(to_table), Alias::new("id"))
+        .on_delete(ForeignKeyAction::Cascade)
+        .to_owned()
+}
+
+/// Runs SQL that SeaQuery cannot express, such as triggers or extensions.
+pub async fn execute_sql(manager: &SchemaManager<'_>, sql: &str) -> Result<(), DbErr> {
+    manager.get_connection().execute_unprepared(sql).await?;
+    Ok(())
+}
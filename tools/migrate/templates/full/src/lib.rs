@@ -0,0 +1,12 @@
+pub use sea_orm_migration::prelude::*;
+
+pub mod helpers;
+
+pub struct Migrator;
+
+#[async_trait::async_trait]
+impl MigratorTrait for Migrator {
+    fn migrations() -> Vec<Box<dyn MigrationTrait>> {
+        vec![]
+    }
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/testrunner"
+)
+
+// testReadyTimeout bounds how long test waits for its container to accept
+// connections.
+const testReadyTimeout = 60 * time.Second
+
+// runTest applies every migration to a throwaway PostgreSQL container, rolls
+// them all back and applies them again, to check that the migrations run on
+// an empty database and that their down migrations undo them cleanly.
+func runTest(cfg Config) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	phase := func(name, command string) testrunner.Phase {
+		return testrunner.Phase{Name: name, Run: func(databaseURL string) int {
+			phaseCfg := cfg
+			phaseCfg.Command = command
+			phaseCfg.DatabaseURL = databaseURL
+			return runMigration(phaseCfg)
+		}}
+	}
+	phases := []testrunner.Phase{
+		phase("up", "up"),
+		// The migrator's reset rolls back every applied migration.
+		phase("down", "reset"),
+		phase("up again", "up"),
+	}
+
+	printer.Info("Starting %s", testrunner.DefaultImage)
+	results, err := testrunner.Run(ctx, testrunner.Options{ReadyTimeout: testReadyTimeout}, phases)
+
+	exitCode := 0
+	for _, result := range results {
+		duration := result.Duration.Round(time.Millisecond)
+		if result.ExitCode == 0 {
+			printer.Success("✓ %s (%s)", result.Phase.Name, duration)
+			continue
+		}
+		printer.Error("✗ %s: exited with %d after %s", result.Phase.Name, result.ExitCode, duration)
+		exitCode = 1
+	}
+	if len(results) > 0 {
+		for _, phase := range phases[len(results):] {
+			printer.Warn("- %s: not run", phase.Name)
+		}
+	}
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	return exitCode
+}
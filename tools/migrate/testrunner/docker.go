@@ -0,0 +1,121 @@
+// Package testrunner applies migrations to a throwaway PostgreSQL container
+// started with the docker CLI.
+package testrunner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultImage is the PostgreSQL image started when Options.Image is empty.
+const DefaultImage = "postgres:16-alpine"
+
+const (
+	postgresUser     = "postgres"
+	postgresPassword = "postgres"
+	postgresDB       = "migrate_test"
+
+	readyPoll = 500 * time.Millisecond
+)
+
+// Container is a running PostgreSQL container.
+type Container struct {
+	ID   string
+	Port string
+}
+
+// StartPostgres pulls image and starts it in the background, published on a
+// random port on the loopback interface. The caller must Remove the
+// container.
+func StartPostgres(ctx context.Context, image string) (*Container, error) {
+	if _, err := docker(ctx, "pull", "--quiet", image); err != nil {
+		return nil, err
+	}
+
+	id, err := docker(ctx, "run", "--detach",
+		"--publish", "127.0.0.1::5432",
+		"--env", "POSTGRES_USER="+postgresUser,
+		"--env", "POSTGRES_PASSWORD="+postgresPassword,
+		"--env", "POSTGRES_DB="+postgresDB,
+		image)
+	if err != nil {
+		return nil, err
+	}
+	c := &Container{ID: id}
+
+	mapping, err := docker(ctx, "port", id, "5432/tcp")
+	if err != nil {
+		c.Remove()
+		return nil, err
+	}
+	// docker port prints one host:port per line.
+	first, _, _ := strings.Cut(mapping, "\n")
+	_, port, err := net.SplitHostPort(first)
+	if err != nil {
+		c.Remove()
+		return nil, fmt.Errorf("unexpected docker port output %q", mapping)
+	}
+	c.Port = port
+	return c, nil
+}
+
+// WaitReady blocks until the server accepts connections or ctx is done. It
+// checks over TCP, because the image's entrypoint runs a temporary server
+// on the Unix socket only while it initialises the database.
+func (c *Container) WaitReady(ctx context.Context) error {
+	for {
+		_, err := docker(ctx, "exec", c.ID, "pg_isready", "--quiet",
+			"--host", "127.0.0.1", "--username", postgresUser, "--dbname", postgresDB)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("postgres did not become ready: %w", ctx.Err())
+		case <-time.After(readyPoll):
+		}
+	}
+}
+
+// DatabaseURL returns the connection string for the container's database.
+func (c *Container) DatabaseURL() string {
+	return fmt.Sprintf("postgres://%s:%s@127.0.0.1:%s/%s?sslmode=disable",
+		postgresUser, postgresPassword, c.Port, postgresDB)
+}
+
+// Remove stops the container and deletes it along with its volumes.
+func (c *Container) Remove() error {
+	// Use a fresh context so that cleanup still happens after the caller's
+	// context has been cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := docker(ctx, "rm", "--force", "--volumes", c.ID)
+	return err
+}
+
+// docker runs the docker CLI and returns its trimmed stdout. The error
+// includes whatever it printed to stderr.
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", errors.New("docker not found in PATH")
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("docker %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("docker %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,75 @@
+package testrunner
+
+import (
+	"context"
+	"time"
+)
+
+// Phase is one step of a test run. Run applies it to the database at
+// databaseURL and returns the exit code of the migration; any non-zero code
+// counts as a failure.
+type Phase struct {
+	Name string
+	Run  func(databaseURL string) int
+}
+
+// Result reports how a phase went.
+type Result struct {
+	Phase    Phase
+	ExitCode int
+	Duration time.Duration
+}
+
+// Options controls the container a test run uses.
+type Options struct {
+	// Image is the PostgreSQL image to start; DefaultImage if empty.
+	Image string
+	// ReadyTimeout bounds how long to wait for the server to accept
+	// connections. Zero means no limit beyond ctx.
+	ReadyTimeout time.Duration
+}
+
+// Run starts a PostgreSQL container, runs phases against it in order until
+// one fails, and removes the container however the run ends. It returns a
+// result for each phase that ran, and an error if the container could not
+// be started or removed. Phases are not started once ctx is done.
+func Run(ctx context.Context, opts Options, phases []Phase) (results []Result, err error) {
+	image := opts.Image
+	if image == "" {
+		image = DefaultImage
+	}
+
+	container, err := StartPostgres(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if removeErr := container.Remove(); err == nil {
+			err = removeErr
+		}
+	}()
+
+	readyCtx := ctx
+	if opts.ReadyTimeout > 0 {
+		var cancel context.CancelFunc
+		readyCtx, cancel = context.WithTimeout(ctx, opts.ReadyTimeout)
+		defer cancel()
+	}
+	if err := container.WaitReady(readyCtx); err != nil {
+		return nil, err
+	}
+
+	for _, phase := range phases {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		start := time.Now()
+		code := phase.Run(container.DatabaseURL())
+		results = append(results, Result{Phase: phase, ExitCode: code, Duration: time.Since(start)})
+		if code != 0 {
+			break
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,138 @@
+// Package tidy renames migration files to the canonical
+// <sequence>_<name>.up.sql form that create writes.
+package tidy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// Rename is a file to move from Old to New.
+type Rename struct {
+	Old, New string
+}
+
+// Change is a migration whose files are not named canonically: its
+// sequence number is not zero-padded to six digits or its name is not
+// normalized.
+type Change struct {
+	Sequence int
+	OldName  string
+	NewName  string
+	Renames  []Rename
+}
+
+// Plan returns the changes that give every migration in files its
+// canonical name, with the sequence number padded to six digits, or to the
+// width of the largest one if that is wider, and the name normalized as
+// create normalizes it. Each file keeps its .up.sql, .down.sql or .sql
+// suffix. It fails if two migrations share a sequence number or a new name
+// is taken by a file that is not being renamed.
+func Plan(files []migrations.File) ([]Change, error) {
+	var namer migrations.MigrationNamer
+
+	width := 6
+	for _, file := range files {
+		width = max(width, len(fmt.Sprint(file.Sequence)))
+	}
+
+	bySequence := make(map[int]string)
+	sources := make(map[string]bool)
+	for _, file := range files {
+		if other, ok := bySequence[file.Sequence]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share sequence number %d", other, file.Name, file.Sequence)
+		}
+		bySequence[file.Sequence] = file.Name
+		for _, path := range []string{file.UpPath, file.DownPath} {
+			if path != "" {
+				sources[path] = true
+			}
+		}
+	}
+
+	var changes []Change
+	targets := make(map[string]bool)
+	for _, file := range files {
+		name, err := namer.Normalize(file.Name)
+		if err != nil {
+			return nil, err
+		}
+		change := Change{Sequence: file.Sequence, OldName: file.Name, NewName: name}
+		for _, path := range []string{file.UpPath, file.DownPath} {
+			if path == "" {
+				continue
+			}
+			base := filepath.Base(path)
+			target := filepath.Join(filepath.Dir(path), fmt.Sprintf("%0*d_%s%s", width, file.Sequence, name, suffix(base)))
+			if target == path {
+				continue
+			}
+			if targets[target] {
+				return nil, fmt.Errorf("%s and another file would both be renamed to %s", base, filepath.Base(target))
+			}
+			targets[target] = true
+			if _, err := os.Stat(target); err == nil && !sources[target] {
+				return nil, fmt.Errorf("cannot rename %s: %s already exists", base, filepath.Base(target))
+			}
+			change.Renames = append(change.Renames, Rename{Old: path, New: target})
+		}
+		if len(change.Renames) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// suffix returns the direction and extension of a migration file name:
+// .up.sql, .down.sql or .sql.
+func suffix(filename string) string {
+	for _, s := range []string{".up.sql", ".down.sql"} {
+		if strings.HasSuffix(filename, s) {
+			return s
+		}
+	}
+	return ".sql"
+}
+
+// Mover renames files with git mv inside a git work tree, so that the
+// history follows them, and with os.Rename elsewhere or for untracked
+// files.
+type Mover struct {
+	git bool
+}
+
+// NewMover returns a Mover for the files in dir.
+func NewMover(ctx context.Context, dir string) Mover {
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--is-inside-work-tree").Output()
+	return Mover{git: err == nil && strings.TrimSpace(string(out)) == "true"}
+}
+
+// Git reports whether the Mover uses git mv.
+func (m Mover) Git() bool {
+	return m.git
+}
+
+// Move renames from to to.
+func (m Mover) Move(ctx context.Context, from, to string) error {
+	if m.git {
+		tracked := exec.CommandContext(ctx, "git", "-C", filepath.Dir(from), "ls-files", "--error-unmatch", filepath.Base(from))
+		if tracked.Run() == nil {
+			target, err := filepath.Abs(to)
+			if err != nil {
+				return err
+			}
+			cmd := exec.CommandContext(ctx, "git", "-C", filepath.Dir(from), "mv", filepath.Base(from), target)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("git mv %s: %v: %s", filepath.Base(from), err, strings.TrimSpace(string(out)))
+			}
+			return nil
+		}
+	}
+	return os.Rename(from, to)
+}
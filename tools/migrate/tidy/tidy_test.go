@@ -0,0 +1,124 @@
+package tidy
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "1_init.sql", "00002_users.up.sql", "00002_users.down.sql", "000003_orders.up.sql", "4_Add-Index.up.sql")
+	files, err := migrations.Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Plan(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := func(name string) string { return filepath.Join(dir, name) }
+	want := []Change{
+		{Sequence: 1, OldName: "init", NewName: "init", Renames: []Rename{{p("1_init.sql"), p("000001_init.sql")}}},
+		{Sequence: 2, OldName: "users", NewName: "users", Renames: []Rename{
+			{p("00002_users.up.sql"), p("000002_users.up.sql")},
+			{p("00002_users.down.sql"), p("000002_users.down.sql")},
+		}},
+		{Sequence: 4, OldName: "Add-Index", NewName: "add_index", Renames: []Rename{{p("4_Add-Index.up.sql"), p("000004_add_index.up.sql")}}},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Plan =\n%+v\nwant\n%+v", changes, want)
+	}
+}
+
+func TestPlanPadsToTheWidestSequence(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "1_init.up.sql", "1234567_big.up.sql")
+	files, _ := migrations.Scan(dir)
+	changes, err := Plan(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || filepath.Base(changes[0].Renames[0].New) != "0000001_init.up.sql" {
+		t.Errorf("Plan = %+v, want 1_init padded to seven digits", changes)
+	}
+}
+
+func TestPlanRejectsConflicts(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "1_a.up.sql", "01_b.up.sql")
+	files, _ := migrations.Scan(dir)
+	if _, err := Plan(files); err == nil {
+		t.Error("Plan accepted two migrations with sequence 1")
+	}
+}
+
+func TestMoverRenamesOutsideGit(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "1_init.sql")
+	ctx := context.Background()
+	m := NewMover(ctx, dir)
+	if m.Git() {
+		t.Skip("temporary directory is inside a git work tree")
+	}
+	if err := m.Move(ctx, filepath.Join(dir, "1_init.sql"), filepath.Join(dir, "000001_init.sql")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "000001_init.sql")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMoverUsesGitMv(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+	git("init", "-q")
+	writeFiles(t, dir, "1_init.sql", "2_untracked.sql")
+	git("add", "1_init.sql")
+	git("commit", "-q", "-m", "init")
+
+	ctx := context.Background()
+	m := NewMover(ctx, dir)
+	if !m.Git() {
+		t.Fatal("NewMover did not detect the git work tree")
+	}
+	for _, names := range [][2]string{{"1_init.sql", "000001_init.sql"}, {"2_untracked.sql", "000002_untracked.sql"}} {
+		if err := m.Move(ctx, filepath.Join(dir, names[0]), filepath.Join(dir, names[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := git("status", "--porcelain", "--untracked-files=no"); got != "R  1_init.sql -> 000001_init.sql\n" {
+		t.Errorf("git status = %q, want a staged rename", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "000002_untracked.sql")); err != nil {
+		t.Error(err)
+	}
+}
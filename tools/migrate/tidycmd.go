@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/internal/lock"
+	"github.com/crypto-bot/tools/migrate/internal/pg"
+	"github.com/crypto-bot/tools/migrate/migrations"
+	"github.com/crypto-bot/tools/migrate/tidy"
+)
+
+// runTidy renames the migration files to the canonical form, with git mv in
+// a git work tree, and updates the descriptions in the tracking table of the
+// migrations whose names change, all under the migration lock. Renaming a
+// migration the migrator has not applied yet could make it apply the wrong
+// file or skip one, so tidy refuses while any are pending. With --dry-run
+// it only prints the renames.
+func runTidy(cfg Config) int {
+	files, err := migrations.Scan(cfg.SQLDir())
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	changes, err := tidy.Plan(files)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if len(changes) == 0 {
+		printer.Success("Every migration file in %s is named canonically", cfg.SQLDir())
+		return 0
+	}
+
+	_, state, err := migrationState(cfg)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	if pending := countPending(state); pending > 0 {
+		printer.Error("Error: %d migration(s) are pending; apply them with up before tidying, as renaming them could change what up applies", pending)
+		return 1
+	}
+
+	for _, change := range changes {
+		for _, r := range change.Renames {
+			printer.Info("%s -> %s", filepath.Base(r.Old), filepath.Base(r.New))
+		}
+		if change.NewName != change.OldName {
+			printer.Info("  (%s: %q -> %q)", db.RecordsTable(), change.OldName, change.NewName)
+		}
+	}
+	if cfg.DryRun {
+		printer.Info("Dry run: %d migration(s) would be renamed", len(changes))
+		return 0
+	}
+
+	release, err := acquireMigrationLock(context.Background(), cfg.DatabaseURL, cfg.LockTimeout)
+	if errors.Is(err, lock.ErrTimeout) {
+		printer.Error("Error: another migration is running (lock not acquired within %s)", cfg.LockTimeout)
+		return exitLockContention
+	}
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	defer release()
+
+	start := time.Now()
+	exitCode := 0
+	if err := renameMigrations(cfg, changes); err != nil {
+		printer.Error("Error: %v", err)
+		exitCode = 1
+	} else {
+		printer.Success("✓ Renamed %d migration(s)", len(changes))
+	}
+	recordAudit(cfg, start, exitCode, time.Since(start))
+	return exitCode
+}
+
+// renameMigrations updates the tracking table and moves the files in one
+// go: the table changes are committed only once every file has moved, and
+// the files already moved are moved back if a later one fails.
+func renameMigrations(cfg Config, changes []tidy.Change) error {
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	conn, err := pg.Open(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// golang-migrate's schema_migrations records no names.
+	var hasDescriptions bool
+	if err := conn.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", db.QuotedRecordsTable()).Scan(&hasDescriptions); err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if hasDescriptions {
+		for _, change := range changes {
+			if change.NewName == change.OldName {
+				continue
+			}
+			if err := db.RecordRenamed(ctx, tx, int64(change.Sequence), change.NewName); err != nil {
+				return err
+			}
+		}
+	}
+
+	mover := tidy.NewMover(ctx, cfg.SQLDir())
+	var moved []tidy.Rename
+	for _, change := range changes {
+		for _, r := range change.Renames {
+			if err := mover.Move(ctx, r.Old, r.New); err != nil {
+				for i := len(moved) - 1; i >= 0; i-- {
+					if undoErr := mover.Move(ctx, moved[i].New, moved[i].Old); undoErr != nil {
+						printer.Warn("Warning: could not move %s back: %v", moved[i].New, undoErr)
+					}
+				}
+				return err
+			}
+			moved = append(moved, r)
+		}
+	}
+	if mover.Git() {
+		printer.Info("Renamed with git mv; commit the renames")
+	}
+	return tx.Commit()
+}
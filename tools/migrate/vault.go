@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/config"
+	"github.com/crypto-bot/tools/migrate/secrets"
+)
+
+// resolveVaultSecret sets DATABASE_URL from the Vault secret at
+// VAULT_SECRET_PATH when VAULT_ADDR, VAULT_TOKEN and VAULT_SECRET_PATH are
+// all set. As with DATABASE_URL_FILE, an explicit DATABASE_URL wins.
+func resolveVaultSecret(timeout time.Duration) error {
+	addr := getenv("VAULT_ADDR")
+	token := getenv("VAULT_TOKEN")
+	path := getenv("VAULT_SECRET_PATH")
+	if addr == "" || token == "" || path == "" || getenv("DATABASE_URL") != "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client := &secrets.VaultClient{Addr: addr, Token: token, Client: &http.Client{}}
+	url, err := client.DatabaseURL(ctx, path)
+	if err != nil {
+		return fmt.Errorf("read DATABASE_URL from vault: %w", err)
+	}
+	environment.Set("DATABASE_URL", url)
+	return nil
+}
+
+// resolveConfigSecret sets DATABASE_URL from the Vault secret the
+// configuration file's database_url names with the !secret tag, using
+// VAULT_ADDR and VAULT_TOKEN. Like the file's other settings it overrides
+// the environment.
+func resolveConfigSecret(file config.Config, timeout time.Duration) error {
+	if file.DatabaseURLSecret == "" {
+		return nil
+	}
+	path, field, err := secrets.ParseVaultRef(file.DatabaseURLSecret)
+	if err != nil {
+		return err
+	}
+	addr := getenv("VAULT_ADDR")
+	token := getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return fmt.Errorf("database_url is %s, which needs VAULT_ADDR and VAULT_TOKEN", file.DatabaseURLSecret)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client := &secrets.VaultClient{Addr: addr, Token: token, Client: &http.Client{}}
+	url, err := client.Field(ctx, path, field)
+	if err != nil {
+		return fmt.Errorf("read database_url from vault: %w", err)
+	}
+	environment.Set("DATABASE_URL", url)
+	return nil
+}
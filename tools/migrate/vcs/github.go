@@ -0,0 +1,218 @@
+// Package vcs reads the migrations a pull request adds from the code host,
+// for reviewing them without checking the branch out.
+package vcs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PullRequest identifies a GitHub pull request.
+type PullRequest struct {
+	// Host is github.com, or the host of a GitHub Enterprise server.
+	Host   string
+	Owner  string
+	Repo   string
+	Number int
+}
+
+// ParsePullRequestURL parses a pull request URL such as
+// https://github.com/org/repo/pull/123, ignoring anything after the number
+// as in .../pull/123/files.
+func ParsePullRequestURL(raw string) (PullRequest, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return PullRequest{}, fmt.Errorf("%q is not a pull request URL", raw)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 4 || parts[2] != "pull" {
+		return PullRequest{}, fmt.Errorf("%q is not a pull request URL, expected https://%s/<owner>/<repo>/pull/<number>", raw, u.Host)
+	}
+	n, err := strconv.Atoi(parts[3])
+	if err != nil || n <= 0 {
+		return PullRequest{}, fmt.Errorf("%q has no pull request number", raw)
+	}
+	return PullRequest{Host: u.Host, Owner: parts[0], Repo: parts[1], Number: n}, nil
+}
+
+// APIURL is the REST API root of the pull request's host.
+func (pr PullRequest) APIURL() string {
+	if pr.Host == "github.com" {
+		return "https://api.github.com"
+	}
+	return "https://" + pr.Host + "/api/v3"
+}
+
+// File is a migration file a pull request touches.
+type File struct {
+	// Path is relative to the repository root.
+	Path string
+	// Status is GitHub's: added, modified, removed or renamed.
+	Status string
+	// Content is the file at the pull request's head; it is only fetched
+	// for added files.
+	Content string
+}
+
+// PRDiff is what a pull request changes under a migration directory,
+// compared with its base branch.
+type PRDiff struct {
+	PR         PullRequest
+	Title      string
+	BaseBranch string
+	HeadSHA    string
+	// Files are sorted by path, so added migrations come in the order they
+	// are applied.
+	Files []File
+}
+
+// Added returns the files the pull request adds.
+func (d *PRDiff) Added() []File {
+	var added []File
+	for _, f := range d.Files {
+		if f.Status == "added" {
+			added = append(added, f)
+		}
+	}
+	return added
+}
+
+// GitHubClient reads pull requests through the GitHub REST API.
+type GitHubClient struct {
+	// Token, usually GITHUB_TOKEN, is needed for private repositories.
+	Token  string
+	Client *http.Client
+	// BaseURL overrides the pull request's API root, for tests.
+	BaseURL string
+}
+
+type pullResponse struct {
+	Title string `json:"title"`
+	Head  struct {
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+type compareResponse struct {
+	Files []struct {
+		Filename string `json:"filename"`
+		Status   string `json:"status"`
+	} `json:"files"`
+}
+
+// PRDiff returns the .sql files directly under dir, a path relative to the
+// repository root such as migration/migrations, that differ between
+// baseBranch and the head of pr, with the content of the added ones.
+func (c *GitHubClient) PRDiff(ctx context.Context, pr PullRequest, baseBranch, dir string) (*PRDiff, error) {
+	var pull pullResponse
+	if err := c.getJSON(ctx, pr, fmt.Sprintf("/pulls/%d", pr.Number), &pull); err != nil {
+		return nil, err
+	}
+
+	var compare compareResponse
+	spec := escapePath(baseBranch) + "..." + pull.Head.SHA
+	if err := c.getJSON(ctx, pr, "/compare/"+spec, &compare); err != nil {
+		return nil, err
+	}
+
+	diff := &PRDiff{PR: pr, Title: pull.Title, BaseBranch: baseBranch, HeadSHA: pull.Head.SHA}
+	pattern := path.Join(dir, "*.sql")
+	for _, f := range compare.Files {
+		if ok, _ := path.Match(pattern, f.Filename); !ok {
+			continue
+		}
+		file := File{Path: f.Filename, Status: f.Status}
+		if f.Status == "added" {
+			content, err := c.content(ctx, pr, f.Filename, pull.Head.SHA)
+			if err != nil {
+				return nil, err
+			}
+			file.Content = content
+		}
+		diff.Files = append(diff.Files, file)
+	}
+	sort.Slice(diff.Files, func(i, j int) bool { return diff.Files[i].Path < diff.Files[j].Path })
+	return diff, nil
+}
+
+func (c *GitHubClient) getJSON(ctx context.Context, pr PullRequest, endpoint string, v any) error {
+	body, err := c.get(ctx, pr, endpoint, "application/vnd.github+json")
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decode github response for %s: %w", endpoint, err)
+	}
+	return nil
+}
+
+// content fetches the file at filePath as of ref.
+func (c *GitHubClient) content(ctx context.Context, pr PullRequest, filePath, ref string) (string, error) {
+	endpoint := "/contents/" + escapePath(filePath) + "?ref=" + url.QueryEscape(ref)
+	body, err := c.get(ctx, pr, endpoint, "application/vnd.github.raw")
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// escapePath escapes each segment of p, leaving its slashes, which
+// branch names and file paths may both contain.
+func escapePath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// get requests endpoint under the pull request's repository.
+func (c *GitHubClient) get(ctx context.Context, pr PullRequest, endpoint, accept string) ([]byte, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = pr.APIURL()
+	}
+	u := strings.TrimRight(base, "/") + "/repos/" + url.PathEscape(pr.Owner) + "/" + url.PathEscape(pr.Repo) + endpoint
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Message != "" {
+			return nil, fmt.Errorf("github returned %s for %s: %s", resp.Status, endpoint, e.Message)
+		}
+		return nil, fmt.Errorf("github returned %s for %s", resp.Status, endpoint)
+	}
+	return body, nil
+}
@@ -0,0 +1,105 @@
+package vcs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParsePullRequestURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    PullRequest
+		wantErr bool
+	}{
+		{url: "https://github.com/org/repo/pull/123", want: PullRequest{Host: "github.com", Owner: "org", Repo: "repo", Number: 123}},
+		{url: "https://github.com/org/repo/pull/7/files", want: PullRequest{Host: "github.com", Owner: "org", Repo: "repo", Number: 7}},
+		{url: "https://git.example.com/org/repo/pull/9", want: PullRequest{Host: "git.example.com", Owner: "org", Repo: "repo", Number: 9}},
+		{url: "https://github.com/org/repo/issues/123", wantErr: true},
+		{url: "https://github.com/org/repo/pull/abc", wantErr: true},
+		{url: "org/repo#123", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePullRequestURL(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePullRequestURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePullRequestURL(%q) = %+v, want %+v", tt.url, got, tt.want)
+		}
+	}
+
+	if got := (PullRequest{Host: "git.example.com"}).APIURL(); got != "https://git.example.com/api/v3" {
+		t.Errorf("APIURL() = %q", got)
+	}
+}
+
+func TestPRDiff(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/repos/org/repo/pulls/123":
+			fmt.Fprint(w, `{"title":"Add wallets","head":{"sha":"abc123"}}`)
+		case "/repos/org/repo/compare/release/2.x...abc123":
+			fmt.Fprint(w, `{"files":[
+				{"filename":"migration/migrations/000121_add_index.up.sql","status":"added"},
+				{"filename":"migration/migrations/000120_create_wallets.up.sql","status":"added"},
+				{"filename":"migration/migrations/000100_init.up.sql","status":"modified"},
+				{"filename":"migration/src/lib.rs","status":"modified"},
+				{"filename":"migration/migrations/nested/x.sql","status":"added"}
+			]}`)
+		case "/repos/org/repo/contents/migration/migrations/000120_create_wallets.up.sql",
+			"/repos/org/repo/contents/migration/migrations/000121_add_index.up.sql":
+			if r.URL.Query().Get("ref") != "abc123" || r.Header.Get("Accept") != "application/vnd.github.raw" {
+				t.Errorf("content request %s with Accept %q", r.URL, r.Header.Get("Accept"))
+			}
+			fmt.Fprintf(w, "-- %s\n", r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := &GitHubClient{Token: "ghp_token", BaseURL: server.URL}
+	pr := PullRequest{Host: "github.com", Owner: "org", Repo: "repo", Number: 123}
+	diff, err := client.PRDiff(context.Background(), pr, "release/2.x", "migration/migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if auth != "Bearer ghp_token" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if diff.Title != "Add wallets" || diff.HeadSHA != "abc123" {
+		t.Errorf("diff = %+v", diff)
+	}
+	if len(diff.Files) != 3 {
+		t.Fatalf("got %d files, want 3: %+v", len(diff.Files), diff.Files)
+	}
+	added := diff.Added()
+	if len(added) != 2 || added[0].Path != "migration/migrations/000120_create_wallets.up.sql" ||
+		added[0].Content != "-- 000120_create_wallets.up.sql\n" {
+		t.Errorf("Added() = %+v", added)
+	}
+}
+
+func TestPRDiffError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Not Found"}`)
+	}))
+	defer server.Close()
+
+	client := &GitHubClient{BaseURL: server.URL}
+	_, err := client.PRDiff(context.Background(), PullRequest{Owner: "org", Repo: "repo", Number: 1}, "main", "migration/migrations")
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "Not Found") {
+		t.Errorf("err = %v, want the status and message", err)
+	}
+}
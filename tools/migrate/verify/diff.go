@@ -0,0 +1,184 @@
+// Package verify compares the schemas a migration cycle leaves behind, to
+// show what a down migration failed to undo.
+package verify
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is how many unchanged lines surround each change in a diff.
+const contextLines = 3
+
+// maxTableCells bounds the table used to align the lines that differ. Two
+// schemas that differ by more are shown as the changed region removed and
+// added in full.
+const maxTableCells = 4 << 20
+
+// edit is one line of a diff: ' ' kept, '-' only in the old text, '+' only
+// in the new one.
+type edit struct {
+	op   byte
+	line string
+}
+
+// LineDiff returns a unified diff of old and new, labelled with oldName and
+// newName, or "" if they are equal.
+func LineDiff(oldName, newName, old, new string) string {
+	if old == new {
+		return ""
+	}
+	edits := diffLines(splitLines(old), splitLines(new))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(edits); {
+		// Find the next change and the run of changes that are no more
+		// than twice the context apart, which share a hunk.
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		last := first
+		for i := first; i < len(edits); i++ {
+			if edits[i].op != ' ' {
+				last = i
+			} else if i-last > 2*contextLines {
+				break
+			}
+		}
+
+		from := max(first-contextLines, start)
+		to := min(last+contextLines+1, len(edits))
+		oldStart, newStart := lineNumbers(edits[:from])
+		oldCount, newCount := 0, 0
+		for _, e := range edits[from:to] {
+			if e.op != '+' {
+				oldCount++
+			}
+			if e.op != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, e := range edits[from:to] {
+			fmt.Fprintf(&b, "%c%s\n", e.op, e.line)
+		}
+		start = to
+	}
+	return b.String()
+}
+
+// diffLines aligns old and new on a longest common subsequence of their
+// lines, after setting aside the lines they start and end with in common.
+func diffLines(old, new []string) []edit {
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix && old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+
+	var edits []edit
+	for _, line := range old[:prefix] {
+		edits = append(edits, edit{' ', line})
+	}
+	edits = append(edits, alignMiddle(old[prefix:len(old)-suffix], new[prefix:len(new)-suffix])...)
+	for _, line := range old[len(old)-suffix:] {
+		edits = append(edits, edit{' ', line})
+	}
+	return edits
+}
+
+// alignMiddle diffs the lines that differ with a longest common subsequence
+// table, or removes and adds them all when the table would be too large.
+func alignMiddle(old, new []string) []edit {
+	var edits []edit
+	if (len(old)+1)*(len(new)+1) > maxTableCells {
+		for _, line := range old {
+			edits = append(edits, edit{'-', line})
+		}
+		for _, line := range new {
+			edits = append(edits, edit{'+', line})
+		}
+		return edits
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of old[i:]
+	// and new[j:].
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(old) && j < len(new) {
+		switch {
+		case old[i] == new[j]:
+			edits = append(edits, edit{' ', old[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', old[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', new[j]})
+			j++
+		}
+	}
+	for ; i < len(old); i++ {
+		edits = append(edits, edit{'-', old[i]})
+	}
+	for ; j < len(new); j++ {
+		edits = append(edits, edit{'+', new[j]})
+	}
+	return edits
+}
+
+// lineNumbers returns the line numbers in the old and new texts at which
+// the hunk following edits starts.
+func lineNumbers(edits []edit) (oldLine, newLine int) {
+	oldLine, newLine = 1, 1
+	for _, e := range edits {
+		if e.op != '+' {
+			oldLine++
+		}
+		if e.op != '-' {
+			newLine++
+		}
+	}
+	return oldLine, newLine
+}
+
+// hunkRange formats the start,count range of a hunk. An empty range starts
+// at the line before it, as diff writes it.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
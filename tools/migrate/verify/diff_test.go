@@ -0,0 +1,52 @@
+package verify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLineDiffEqual(t *testing.T) {
+	if got := LineDiff("a", "b", "x\ny\n", "x\ny\n"); got != "" {
+		t.Errorf("LineDiff of equal texts = %q, want empty", got)
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	old := "CREATE TABLE users (\n    id bigint,\n    name text\n);\n\nCREATE TABLE orders (\n    id bigint\n);\n"
+	new := "CREATE TABLE users (\n    id bigint,\n    name text,\n    email text\n);\n\nCREATE TABLE orders (\n    id bigint\n);\n"
+	want := `--- first
++++ second
+@@ -1,6 +1,7 @@
+ CREATE TABLE users (
+     id bigint,
+-    name text
++    name text,
++    email text
+ );
+ 
+ CREATE TABLE orders (
+`
+	if got := LineDiff("first", "second", old, new); got != want {
+		t.Errorf("LineDiff =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLineDiffSeparateHunks(t *testing.T) {
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, strings.Repeat("x", i+1))
+	}
+	old := strings.Join(lines, "\n") + "\n"
+	changed := append([]string(nil), lines...)
+	changed = append(changed[:1], changed[2:]...)
+	changed = append(changed, "tail")
+	new := strings.Join(changed, "\n") + "\n"
+
+	got := LineDiff("a", "b", old, new)
+	if n := strings.Count(got, "@@ -"); n != 2 {
+		t.Fatalf("LineDiff made %d hunks, want 2:\n%s", n, got)
+	}
+	if !strings.Contains(got, "@@ -1,5 +1,4 @@\n") || !strings.Contains(got, "@@ -18,3 +17,4 @@\n") {
+		t.Errorf("unexpected hunk ranges:\n%s", got)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/crypto-bot/tools/migrate/db"
+	"github.com/crypto-bot/tools/migrate/internal/lock"
+	"github.com/crypto-bot/tools/migrate/verify"
+)
+
+// runVerify checks on a disposable database, such as staging, that the
+// newest migration can be rolled back cleanly: it runs up, down one step
+// and up again, and fails with a diff if the schema after the second up
+// differs from the schema after the first. The whole cycle holds the
+// migration lock.
+func runVerify(cfg Config) int {
+	release, err := acquireMigrationLock(context.Background(), cfg.DatabaseURL, cfg.LockTimeout)
+	if errors.Is(err, lock.ErrTimeout) {
+		printer.Error("Error: another migration is running (lock not acquired within %s)", cfg.LockTimeout)
+		return exitLockContention
+	}
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	defer release()
+
+	step := func(command string, steps int) bool {
+		stepCfg := cfg
+		stepCfg.Command = command
+		stepCfg.Steps = steps
+		if code := runMigration(stepCfg); code != 0 {
+			printer.Error("✗ %s exited with %d", command, code)
+			return false
+		}
+		return true
+	}
+
+	if !step("up", 0) {
+		return 1
+	}
+	first, err := dumpSchema(cfg)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	printer.Success("✓ up")
+
+	if !step("down", 1) {
+		return 1
+	}
+	printer.Success("✓ down --steps 1")
+
+	if !step("up", 0) {
+		return 1
+	}
+	second, err := dumpSchema(cfg)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	printer.Success("✓ up again")
+
+	if diff := verify.LineDiff("schema after up", "schema after up again", first, second); diff != "" {
+		fmt.Print(diff)
+		printer.Error("✗ The schema changed across down and up; the down migration does not undo its up migration")
+		return 1
+	}
+	printer.Success("The schema is the same after rolling back and re-applying the newest migration")
+	return 0
+}
+
+// dumpSchema returns the schema of the database, leaving out the tracking
+// table, which is not the migrations' to undo.
+func dumpSchema(cfg Config) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	var schema bytes.Buffer
+	if err := newDumper(cfg.DatabaseURL).Schema(ctx, &schema, db.MigrationsTable(), db.RecordsTable()); err != nil {
+		return "", fmt.Errorf("dump schema: %w", err)
+	}
+	return schema.String(), nil
+}
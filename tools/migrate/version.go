@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log/slog"
+	"runtime"
+)
+
+// Version is the version of the tool, set at build time with
+// -ldflags "-X main.Version=v1.2.3".
+var Version = "dev"
+
+func runVersion() int {
+	cargo, err := findCargo()
+	if err != nil {
+		cargo = "not found"
+	}
+
+	slog.Info("migrate "+Version, "go", runtime.Version(), "cargo", cargo)
+	return 0
+}
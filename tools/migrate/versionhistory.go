@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/crypto-bot/tools/migrate/history"
+	"github.com/crypto-bot/tools/migrate/migrations"
+)
+
+// historyTimeout is how long each database has to answer version-history.
+const historyTimeout = 10 * time.Second
+
+// runVersionHistory prints when --migration was applied to DATABASE_URL and
+// to each database in MIGRATE_HISTORY_DB_URLS, which are asked at once. It
+// fails if any of them could not be asked.
+func runVersionHistory(cfg Config) int {
+	var namer migrations.MigrationNamer
+
+	file, err := findMigration(cfg, cfg.Migration)
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	name := namer.Base(file.Sequence, file.Name)
+
+	sources := []history.Source{{Environment: environmentLabel(cfg.DatabaseURL), DatabaseURL: cfg.DatabaseURL}}
+	for _, databaseURL := range strings.Split(getenv("MIGRATE_HISTORY_DB_URLS"), ",") {
+		if databaseURL = strings.TrimSpace(databaseURL); databaseURL != "" {
+			sources = append(sources, history.Source{Environment: environmentLabel(databaseURL), DatabaseURL: databaseURL})
+		}
+	}
+
+	records := history.Query(context.Background(), sources, int64(file.Sequence), historyTimeout)
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ENVIRONMENT\tMIGRATION\tAPPLIED AT\tDURATION")
+	for _, r := range records {
+		appliedAt, duration := "not applied", "-"
+		switch {
+		case r.Err != nil:
+			failed++
+			appliedAt = "unknown"
+		case r.AppliedAt != nil:
+			appliedAt = r.AppliedAt.UTC().Format(time.RFC3339)
+			if r.Duration > 0 {
+				duration = r.Duration.Round(time.Millisecond).String()
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Environment, name, appliedAt, duration)
+	}
+	w.Flush()
+
+	for _, r := range records {
+		if r.Err != nil {
+			printer.Error("Error: %s: %v", r.Environment, r.Err)
+		}
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// environmentLabel names a database by its host and database name, leaving
+// out the credentials.
+func environmentLabel(databaseURL string) string {
+	return databaseHost(databaseURL) + "/" + databaseName(databaseURL)
+}
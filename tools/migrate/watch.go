@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// runWatch applies pending migrations whenever .sql files in the migration
+// directory are created or modified. Changes within --watch-delay of each
+// other are coalesced into one run. It only ever runs up.
+func runWatch(cfg Config) int {
+	if looksLikeProduction(getenv("APP_ENV")) {
+		printer.Warn("WARNING: APP_ENV=%s looks like production; watch is meant for local development only", getenv("APP_ENV"))
+	}
+
+	dir := cfg.SQLDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		printer.Error("Error: %v", err)
+		return 1
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(dir); err != nil {
+		printer.Error("Error: watch %s: %v", dir, err)
+		return 1
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	printer.Info("Watching %s for changes (Ctrl-C to stop)", dir)
+
+	upCfg := cfg
+	upCfg.Command = "up"
+
+	changed := make(map[string]bool)
+	debounce := time.NewTimer(0)
+	<-debounce.C
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return 0
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			if !strings.HasSuffix(event.Name, ".sql") {
+				continue
+			}
+			changed[filepath.Base(event.Name)] = true
+			debounce.Reset(cfg.WatchDelay)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return 0
+			}
+			printer.Warn("Warning: watch: %v", err)
+
+		case <-debounce.C:
+			names := make([]string, 0, len(changed))
+			for name := range changed {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			clear(changed)
+
+			printer.Info("Changed: %s", strings.Join(names, ", "))
+			migrateDatabase(context.Background(), upCfg)
+			printer.Info("Watching %s for changes (Ctrl-C to stop)", dir)
+
+		case <-signals:
+			return 0
+		}
+	}
+}
+
+// looksLikeProduction reports whether appEnv names a production
+// environment, such as "prod" or "production".
+func looksLikeProduction(appEnv string) bool {
+	appEnv = strings.ToLower(appEnv)
+	return strings.HasPrefix(appEnv, "prod") || appEnv == "live"
+}